@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+var touchCommand = &Command{
+	Name:    "touch",
+	Args:    "[ticket-id]",
+	MaxArgs: 1,
+	Summary: "Record access to a worktree (for shell hooks)",
+	Description: `Records that the worktree containing the current directory, or the given ticket's
+worktree, was accessed. It is meant to be run from a shell hook, so outside a tracked
+worktree it does nothing.`,
+	Examples: []string{"touch"},
+	Setup:    func(*flag.FlagSet) RunFunc { return runTouch },
+}
+
+// runTouch records that the worktree containing the current directory, or the given
+// ticket's worktree, was accessed.
+func runTouch(ctx *Context, args []string) error {
+
+	// Get primary repo path
+	repoPath, err := ctx.primaryRepoPath()
+	if err != nil {
+		if len(args) == 0 {
+			return nil
+		}
+		return fmt.Errorf("failed to find primary repository: %w", err)
+	}
+
+	m, err := openManagerAt(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return m.Touch(args[0])
+	}
+
+	loc, err := util.Locate(ctx.Dir)
+	if err != nil || loc.TopLevel == "" {
+		return nil
+	}
+	wt, ok := m.WorktreeAt(loc.TopLevel)
+	if !ok {
+		return nil
+	}
+	return m.Touch(wt.Ticket)
+}
+
+// formatAge describes how long ago t was, e.g. "5m ago" or "3d ago".
+func formatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 14*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	default:
+		return fmt.Sprintf("%dw ago", int(d/(7*24*time.Hour)))
+	}
+}
+
+// parseAge parses a duration such as "30d", "2w" or "12h".
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var archiveCommand = &Command{
+	Name:    "archive",
+	Args:    "<ticket-id>",
+	MinArgs: 1,
+	MaxArgs: 1,
+	Summary: "Save changes and remove a worktree, keeping its branch",
+	Description: `Frees the disk space used by a worktree without losing its state: uncommitted changes,
+including untracked files, are saved in a ref, the worktree directory is removed, and
+the branch and metadata entry are kept. Ignored files, such as build output, aren't
+saved, so a worktree with any is only archived with --force.`,
+	Examples: []string{"archive PROJ-123"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		force := fs.Bool("force", false, "Archive even if ignored files would be deleted")
+		return func(ctx *Context, args []string) error {
+			return runArchive(ctx, "archive", args, *force)
+		}
+	},
+}
+
+var restoreCommand = &Command{
+	Name:        "restore",
+	Args:        "<ticket-id>",
+	MinArgs:     1,
+	MaxArgs:     1,
+	Summary:     "Recreate an archived worktree",
+	Description: `Recreates the worktree at its original path and reapplies its saved changes.`,
+	Examples:    []string{"restore PROJ-123"},
+	Setup: func(*flag.FlagSet) RunFunc {
+		return func(ctx *Context, args []string) error {
+			return runArchive(ctx, "restore", args, false)
+		}
+	},
+}
+
+// runArchive implements the archive and restore commands. force only applies to archive.
+func runArchive(ctx *Context, command string, args []string, force bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	if command == "archive" {
+		wt, err := m.Archive(args[0], gittree.ArchiveOptions{Force: force})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Stdout, "\nWorktree for %s archived. Branch %s was kept.\n", wt.Ticket, wt.Branch)
+		fmt.Fprintf(ctx.Stdout, "To restore it:\n  git tree restore %s\n", wt.Ticket)
+		return nil
+	}
+
+	wt, err := m.Restore(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Stdout, "\nWorktree for %s restored.\n", wt.Ticket)
+	fmt.Fprintf(ctx.Stdout, "To switch to this worktree:\n  cd %s\n", wt.Path)
+	return nil
+}
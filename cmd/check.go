@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+var checkCommand = &Command{
+	Name:    "check",
+	Args:    "[ticket-id | --all]",
+	MaxArgs: 1,
+	Summary: "Predict conflicts with mainline",
+	Description: `Fetches the mainline and, without touching any worktree, reports which worktrees would
+conflict if updated, and in which files.`,
+	Examples: []string{"check"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		all := fs.Bool("all", false, "Check every worktree (the default)")
+		noFetch := fs.Bool("no-fetch", false, "Compare with the mainline as last fetched")
+		return func(ctx *Context, args []string) error {
+			return runCheck(ctx, args, *all, *noFetch)
+		}
+	},
+}
+
+// runCheck predicts which worktrees would conflict with the current mainline when updated.
+func runCheck(ctx *Context, args []string, all, noFetch bool) error {
+	if all && len(args) > 0 {
+		return invalidArgs("")
+	}
+
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	if m.Mainline() == "" {
+		return config.NewError(config.ErrNoMainline, "", "mainline branch not set in metadata")
+	}
+
+	tickets := m.Tickets()
+	if len(args) == 1 {
+		entry, err := m.Get(args[0])
+		if err != nil {
+			return err
+		}
+		tickets = []string{entry.Ticket}
+	}
+
+	if !noFetch {
+		if err := m.Fetch(); err != nil {
+			return err
+		}
+	}
+
+	if len(tickets) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No worktrees found.")
+		return nil
+	}
+
+	target := m.MainlineRef()
+	fmt.Fprintf(ctx.Stdout, "Checking against %s...\n\n", target)
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKET\tBRANCH\tRESULT")
+	fmt.Fprintln(w, "------\t------\t------")
+
+	conflicting := 0
+	for _, ticketID := range tickets {
+		entry, _ := m.Get(ticketID)
+		conflicts, err := m.CheckConflicts(ticketID)
+
+		var result string
+		switch {
+		case err != nil:
+			line, _, _ := strings.Cut(err.Error(), "\n")
+			result = "error: " + line
+		case conflicts.Behind == 0:
+			result = "up to date"
+		case len(conflicts.Files) > 0:
+			conflicting++
+			result = fmt.Sprintf("conflicts (↓%d): %s", conflicts.Behind, strings.Join(conflicts.Files, ", "))
+		default:
+			result = fmt.Sprintf("clean (↓%d)", conflicts.Behind)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", ticketID, entry.Branch, result)
+	}
+	w.Flush()
+
+	if conflicting > 0 {
+		return config.NewError(git.ErrConflict, "", "%d worktree(s) would conflict with %s", conflicting, target)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var cleanCommand = &Command{
+	Name:    "clean",
+	Args:    "--inactive <age>",
+	Summary: "Remove worktrees inactive for longer than age",
+	Description: `Removes worktrees, and their branches, that haven't been active for longer than an age
+such as 30d, 2w or 12h. Worktrees with uncommitted changes are always kept.`,
+	Examples: []string{"clean --inactive 30d --dry-run"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		age := fs.String("inactive", "", "Remove worktrees inactive for longer than `age`")
+		dryRun := fs.Bool("dry-run", false, "Show what would be removed without removing it")
+		noTracker := noTrackerFlag(fs)
+		return func(ctx *Context, args []string) error {
+			return runClean(ctx, *age, *dryRun, *noTracker)
+		}
+	},
+}
+
+// runClean removes worktrees, and their branches, that haven't been active for longer than
+// the given age. Worktrees with uncommitted changes are always kept.
+func runClean(ctx *Context, age string, dryRun, noTracker bool) error {
+	if age == "" {
+		return invalidArgs("--inactive is required")
+	}
+	inactive, err := parseAge(age)
+	if err != nil {
+		return err
+	}
+
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := ctx.Now()
+	found := false
+	removed, err := m.Clean(gittree.CleanOptions{
+		Inactive: inactive,
+		Confirm: func(candidates []gittree.Status) (bool, error) {
+			found = true
+			fmt.Fprintf(ctx.Stdout, "Found %d worktree(s) inactive for more than %s:\n", len(candidates), age)
+			for _, c := range candidates {
+				fmt.Fprintf(ctx.Stdout, "  - %s (%s, last active %s)\n", c.Ticket, c.Branch, formatAge(c.LastActive, now))
+			}
+
+			if dryRun {
+				return false, nil
+			}
+			ok, err := ctx.Confirm("\nRemove these worktrees and their branches?", false)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, fmt.Errorf("clean %w", ErrCancelled)
+			}
+			fmt.Fprintln(ctx.Stdout)
+			return true, nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		fmt.Fprintln(ctx.Stdout, "No inactive worktrees found.")
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+
+	if !noTracker {
+		settings, err := config.LoadSettings(m.RepoPath())
+		if err != nil {
+			return err
+		}
+		for _, entry := range removed {
+			runLifecycle(ctx, settings, "delete", settings.OnDelete, entry.Ticket, entry.Branch)
+		}
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nRemoved %d inactive worktree(s).\n", len(removed))
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var cloneCommand = &Command{
+	Name:    "clone",
+	Args:    "<url> [directory]",
+	MinArgs: 1,
+	MaxArgs: 2,
+	Summary: "Clone a repository as a bare hub",
+	Description: `The bare repository is stored in <directory>/.bare, and the mainline and ticket
+worktrees are checked out next to it. The directory defaults to the repository name.`,
+	Examples: []string{"clone git@github.com:org/myrepo.git"},
+	Setup:    func(*flag.FlagSet) RunFunc { return runClone },
+}
+
+// runClone clones a repository as a bare hub: the bare repository is stored in
+// <directory>/.bare and the mainline and ticket worktrees are checked out next to it.
+func runClone(ctx *Context, args []string) error {
+	url := args[0]
+	var dir string
+	if len(args) >= 2 {
+		dir = args[1]
+	} else {
+		dir = strings.TrimSuffix(filepath.Base(strings.TrimRight(url, "/")), ".git")
+	}
+
+	hubPath := dir
+	if !filepath.IsAbs(hubPath) {
+		hubPath = filepath.Join(ctx.Dir, hubPath)
+	}
+
+	// Refuse to clone into a non-empty directory, as git does
+	if entries, err := os.ReadDir(hubPath); err == nil && len(entries) > 0 {
+		return fmt.Errorf("destination path %s already exists and is not empty", hubPath)
+	}
+
+	barePath := filepath.Join(hubPath, ".bare")
+	fmt.Fprintf(ctx.Stdout, "Cloning %s into %s...\n", url, barePath)
+	repo, err := git.CloneBare(ctx.Dir, url, barePath)
+	if err != nil {
+		return err
+	}
+
+	// Point <hub>/.git at the bare repository so git commands work from the hub directory
+	if err := os.WriteFile(filepath.Join(hubPath, ".git"), []byte("gitdir: ./.bare\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write .git file: %w", err)
+	}
+
+	fmt.Fprintln(ctx.Stdout, "Fetching latest from origin...")
+	if err := repo.Fetch(); err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	if err := repo.SetRemoteHead(); err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+	}
+
+	fmt.Fprintln(ctx.Stdout, "Detecting mainline branch...")
+	mainline, err := repo.DetectMainline()
+	if err != nil {
+		return fmt.Errorf("failed to detect mainline branch: %w", err)
+	}
+	fmt.Fprintf(ctx.Stdout, "Detected mainline: %s\n", mainline)
+
+	// The bare clone already has a local mainline branch, so check it out rather than create it
+	mainlinePath := util.GetWorktreePath(barePath, mainline)
+	fmt.Fprintf(ctx.Stdout, "Creating mainline worktree at %s...\n", mainlinePath)
+	if repo.BranchExists(mainline) {
+		err = repo.AttachWorktree(mainlinePath, mainline)
+	} else {
+		err = repo.AddWorktree(mainlinePath, mainline, "origin/"+mainline)
+	}
+	if err != nil {
+		return err
+	}
+	if err := repo.SetUpstream(mainline, "origin/"+mainline); err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+	}
+
+	// Save metadata
+	m, err := openManagerAt(ctx, barePath)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Init(gittree.InitOptions{Mainline: mainline, Command: "clone", Args: args}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nRepository cloned successfully!\n")
+	fmt.Fprintf(ctx.Stdout, "  Bare repository:  %s\n", barePath)
+	fmt.Fprintf(ctx.Stdout, "  Mainline:         %s\n", mainlinePath)
+	fmt.Fprintf(ctx.Stdout, "\nTicket worktrees will be created in %s\n", hubPath)
+	fmt.Fprintf(ctx.Stdout, "\nTo switch to the mainline worktree:\n")
+	fmt.Fprintf(ctx.Stdout, "  cd %s\n", mainlinePath)
+
+	return nil
+}
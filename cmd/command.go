@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// RunFunc runs a command with its positional arguments.
+type RunFunc func(ctx *Context, args []string) error
+
+// Command is a git-tree command.
+type Command struct {
+	// Name is what the command is run as, and Aliases are other names for it.
+	Name    string
+	Aliases []string
+
+	// Args describes the positional arguments, e.g. "<ticket-id> [branch-name]".
+	Args string
+
+	// MinArgs and MaxArgs bound the number of positional arguments. A negative MaxArgs
+	// means any number.
+	MinArgs int
+	MaxArgs int
+
+	// Summary is a one-line description for the command list, and Description the
+	// longer explanation shown by --help and in the man page.
+	Summary     string
+	Description string
+
+	// Examples are command lines showing typical use.
+	Examples []string
+
+	// JSON is set if the command can write its output as JSON with --json.
+	JSON bool
+
+	// Setup defines the command's flags on fs and returns the function that runs it.
+	// It is called afresh for every run, so flag values never carry over between runs.
+	Setup func(fs *flag.FlagSet) RunFunc
+}
+
+// commands lists every command in the order they're shown in help. It is filled in by
+// init because the help command refers to it.
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		initCommand,
+		cloneCommand,
+		createCommand,
+		listCommand,
+		pushCommand,
+		deleteCommand,
+		statusCommand,
+		noteCommand,
+		labelCommand,
+		updateCommand,
+		checkCommand,
+		switchCommand,
+		archiveCommand,
+		restoreCommand,
+		pruneCommand,
+		cleanCommand,
+		touchCommand,
+		doctorCommand,
+		indexCommand,
+		syncCommand,
+		migrateStorageCommand,
+		logCommand,
+		undoCommand,
+		helpCommand,
+	}
+}
+
+// ErrUsage is the kind of error returned when git-tree is run the wrong way, such as with an
+// unknown command or option or the wrong number of arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// invalidArgsError is returned by a command whose arguments don't make sense together.
+// It is reported with the command's synopsis.
+type invalidArgsError struct {
+	reason string
+}
+
+func (e *invalidArgsError) Error() string {
+	if e.reason == "" {
+		return "invalid arguments"
+	}
+	return e.reason
+}
+
+// invalidArgs returns an invalidArgsError, with an optional reason.
+func invalidArgs(reason string) error {
+	return &invalidArgsError{reason: reason}
+}
+
+// findCommand returns the command with the given name or alias, or nil if there is none.
+func findCommand(name string) *Command {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+		for _, alias := range c.Aliases {
+			if alias == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// synopsis returns the command line the command is run with.
+func (c *Command) synopsis() string {
+	s := "git tree " + c.Name
+	if c.hasFlags() {
+		s += " [options]"
+	}
+	if c.Args != "" {
+		s += " " + c.Args
+	}
+	return s
+}
+
+// flags returns a flag set with the command's own flags defined, and the function that
+// runs the command with their values.
+func (c *Command) flags() (*flag.FlagSet, RunFunc) {
+	fs := newFlagSet("git tree " + c.Name)
+	run := c.Setup(fs)
+	return fs, run
+}
+
+// hasFlags reports whether the command has flags of its own.
+func (c *Command) hasFlags() bool {
+	fs, _ := c.flags()
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// checkArgs checks the number of positional arguments.
+func (c *Command) checkArgs(args []string) error {
+	if len(args) < c.MinArgs || (c.MaxArgs >= 0 && len(args) > c.MaxArgs) {
+		return invalidArgs("")
+	}
+	return nil
+}
+
+// usageError describes a command's invalid use and where to find out more.
+func (c *Command) usageError(reason string) error {
+	if reason != "" {
+		reason += "\n"
+	}
+	return config.NewError(ErrUsage, "", "%susage: %s\nRun 'git tree %s --help' for more information.", reason, c.synopsis(), c.Name)
+}
+
+// writeHelp writes the help for a command, shown by git tree <command> --help.
+func (c *Command) writeHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s\n\n%s.\n", c.synopsis(), c.Summary)
+	if c.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Description)
+	}
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+
+	fs, _ := c.flags()
+	if options := flagHelp(fs); len(options) > 0 {
+		fmt.Fprintf(w, "\nOptions:\n")
+		writeColumns(w, options)
+	}
+
+	if len(c.Examples) > 0 {
+		fmt.Fprintf(w, "\nExamples:\n")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "  git tree %s\n", example)
+		}
+	}
+
+	fmt.Fprintf(w, "\nRun 'git tree help' for the options every command accepts.\n")
+}
+
+// writeUsage writes the overview of git-tree shown by git tree help, listing the plugins
+// found on PATH after the built-in commands.
+func writeUsage(w io.Writer, plugins []string) {
+	fmt.Fprintf(w, "git-tree - Git worktree management tool\n\n")
+	fmt.Fprintf(w, "Usage:\n  git tree [options] <command> [arguments]\n\n")
+
+	fmt.Fprintf(w, "Options:\n")
+	writeColumns(w, flagHelp(globalFlagSet()))
+
+	fmt.Fprintf(w, "\nCommands:\n")
+	var rows [][2]string
+	for _, c := range commands {
+		rows = append(rows, [2]string{strings.TrimSpace(c.Name + " " + c.Args), c.Summary})
+	}
+	writeColumns(w, rows)
+
+	if len(plugins) > 0 {
+		fmt.Fprintf(w, "\nPlugins:\n")
+		for _, name := range plugins {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+	}
+
+	fmt.Fprintf(w, "\nExamples:\n")
+	for _, c := range commands {
+		if len(c.Examples) > 0 && c.Name != "help" {
+			fmt.Fprintf(w, "  git tree %s\n", c.Examples[0])
+		}
+	}
+
+	fmt.Fprintf(w, "\nRun 'git tree help <command>' or 'git tree <command> --help' for more about a command.\n")
+}
+
+// writeColumns writes indented rows with their second column aligned.
+func writeColumns(w io.Writer, rows [][2]string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "  %s\t%s\n", row[0], row[1])
+	}
+	tw.Flush()
+}
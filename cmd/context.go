@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// ErrNoInput is returned when a command needs an answer but the user can't be asked,
+// because input isn't a terminal or --no-input was given.
+var ErrNoInput = errors.New("input required but not available")
+
+// ErrCancelled is returned when the user declines to go ahead, e.g. "deletion cancelled".
+var ErrCancelled = errors.New("cancelled")
+
+// Context is the environment a command runs in: its I/O streams, working directory,
+// environment variables and clock. Commands use it instead of the os package, so they
+// can be embedded, tested and run non-interactively.
+type Context struct {
+	// Stdin, Stdout and Stderr are the command's I/O streams.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Dir is the working directory the repository is discovered from.
+	Dir string
+
+	// Env holds the environment variables git-tree reads, as "KEY=value" entries.
+	// git itself inherits the process environment.
+	Env []string
+
+	// Now returns the current time.
+	Now func() time.Time
+
+	// Interactive indicates a user can answer prompts on Stdin.
+	Interactive bool
+
+	// Yes answers every confirmation with yes and every prompt with its default (--yes).
+	Yes bool
+
+	// JSON asks for output as JSON (--json).
+	JSON bool
+
+	// Color enables colored output. It is set when stdout is a terminal, unless NO_COLOR
+	// is set or --no-color is given.
+	Color bool
+
+	// trace, if set, receives a line for every git command run on the repositories
+	// commands work on (GIT_TREE_TRACE or --verbose).
+	trace io.Writer
+
+	// input buffers Stdin so input isn't lost between prompts.
+	input *bufio.Reader
+}
+
+// NewContext returns a context for the running process: the standard streams, the current
+// directory and environment, and the system clock. It is interactive if stdin is a terminal,
+// and colored if stdout is.
+func NewContext() (*Context, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	interactive := isTerminal(os.Stdin)
+	color := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+
+	return &Context{
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Dir:         dir,
+		Env:         os.Environ(),
+		Now:         time.Now,
+		Interactive: interactive,
+		Color:       color,
+	}, nil
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps s in an ANSI color if color is enabled. Codes are two digits, so every
+// colored string has the same overhead and columns stay aligned.
+func (c *Context) colorize(code int, s string) string {
+	if !c.Color {
+		return s
+	}
+	return fmt.Sprintf("\x1b[%02dm%s\x1b[0m", code, s)
+}
+
+// Getenv returns the value of an environment variable, or "" if it isn't set.
+func (c *Context) Getenv(key string) string {
+	// Later entries win, as they do for exec.Cmd.Env
+	value := ""
+	for _, kv := range c.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// repo returns a Repo for the repository or worktree at path that traces git commands
+// if the context does.
+func (c *Context) repo(path string) *git.Repo {
+	repo := git.NewRepo(path)
+	if c.trace != nil {
+		repo.Runner = &git.ExecRunner{Trace: c.trace}
+	}
+	return repo
+}
+
+// primaryRepoPath returns the primary repository containing the working directory.
+func (c *Context) primaryRepoPath() (string, error) {
+	return util.FindPrimaryRepoPath(c.Dir)
+}
+
+// readLine reads a line of input, without the line ending.
+func (c *Context) readLine() string {
+	if c.input == nil {
+		c.input = bufio.NewReader(c.Stdin)
+	}
+	line, _ := c.input.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// Prompt asks a question and returns the answer, or def if the answer is empty.
+// With --yes it returns def without asking. If the user can't be asked it fails
+// with ErrNoInput rather than waiting for input that will never come.
+func (c *Context) Prompt(question, def string) (string, error) {
+	if c.Yes {
+		fmt.Fprintf(c.Stdout, "%s: %s\n", question, def)
+		return def, nil
+	}
+	if !c.Interactive {
+		return "", fmt.Errorf("%w: %s (use --yes to accept defaults)", ErrNoInput, question)
+	}
+
+	if def != "" {
+		fmt.Fprintf(c.Stdout, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(c.Stdout, "%s: ", question)
+	}
+
+	if answer := c.readLine(); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// Confirm asks a yes/no question and returns the answer, or def if the answer is empty.
+// With --yes it returns true without asking. If the user can't be asked it fails with
+// ErrNoInput, so nothing is done without consent.
+func (c *Context) Confirm(question string, def bool) (bool, error) {
+	if c.Yes {
+		return true, nil
+	}
+	if !c.Interactive {
+		return false, fmt.Errorf("%w: %s (use --yes to confirm)", ErrNoInput, strings.TrimSpace(question))
+	}
+
+	options := "y/N"
+	if def {
+		options = "Y/n"
+	}
+	fmt.Fprintf(c.Stdout, "%s (%s): ", question, options)
+
+	switch strings.ToLower(c.readLine()) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	default:
+		return def, nil
+	}
+}
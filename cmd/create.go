@@ -2,102 +2,89 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
-	"os"
 
 	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/git"
 	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// Create creates a new worktree for the specified ticket.
-func Create(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: git tree create <ticket-id> [branch-name]")
-	}
-
-	ticketID := args[0]
-	var branchName string
-	if len(args) >= 2 {
-		branchName = args[1]
-	} else {
-		branchName = ticketID
-	}
+var createCommand = &Command{
+	Name:    "create",
+	Args:    "<ticket-id> [branch-name]",
+	MinArgs: 1,
+	MaxArgs: 2,
+	Summary: "Create a new worktree for a ticket",
+	Description: `Creates a branch from the latest mainline and checks it out in a new worktree. The
+branch name comes from the branch template unless one is given. If an issue tracker is
+configured, the ticket is looked up first and the create actions are run. With
+--workspace, a worktree is created in every repository of the workspace, in a directory
+shared by the ticket.`,
+	Examples: []string{"create PROJ-123", "create PROJ-123 feature/add-new-feature", "create PROJ-123 --workspace platform"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		noTracker := noTrackerFlag(fs)
+		workspace := workspaceFlag(fs)
+		return func(ctx *Context, args []string) error {
+			if *workspace != "" {
+				return runWorkspaceCreate(ctx, *workspace, args, *noTracker)
+			}
+			return runCreate(ctx, args, *noTracker)
+		}
+	},
+}
 
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
+// runCreate creates a new worktree for the specified ticket.
+func runCreate(ctx *Context, args []string, noTracker bool) error {
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
+		return err
 	}
 
 	// Check if we're in a worktree (should be in primary repo)
-	inWorktree, err := util.IsInWorktree()
+	loc, err := util.Locate(ctx.Dir)
 	if err != nil {
 		return err
 	}
-	if inWorktree {
+	// Every checkout of a bare hub is a worktree, so any of them will do
+	if loc.IsLinkedWorktree() && !util.IsBareRepo(m.RepoPath()) {
 		return fmt.Errorf("must be in primary repository to create worktree (not in an existing worktree)")
 	}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+	settings, err := config.LoadSettings(m.RepoPath())
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
 	}
 
-	// Check if worktree already exists
-	if meta.HasWorktree(ticketID) {
-		entry := meta.Worktrees[ticketID]
-		return fmt.Errorf("worktree for %s already exists at %s", ticketID, entry.Path)
+	// Validate the ticket and fetch its details from the tracker, if configured
+	opts := gittree.CreateOptions{}
+	if len(args) >= 2 {
+		opts.Branch = args[1]
 	}
-
-	// Initialize repo
-	repo := git.NewRepo(repoPath)
-
-	// Detect mainline if not set
-	if meta.Mainline == "" {
-		fmt.Println("Detecting mainline branch...")
-		mainline, err := repo.DetectMainline()
-		if err != nil {
-			return fmt.Errorf("failed to detect mainline branch: %w", err)
+	if !noTracker {
+		opts.Lookup = func(ticketID string) (*gittree.TicketInfo, error) {
+			return lookupTicket(ctx, settings, ticketID)
 		}
-		meta.Mainline = mainline
-		fmt.Printf("Detected mainline: %s\n", mainline)
 	}
 
-	// Fetch latest
-	fmt.Println("Fetching latest from origin...")
-	if err := repo.Fetch(); err != nil {
-		return fmt.Errorf("failed to fetch: %w", err)
-	}
-
-	// Calculate worktree path
-	worktreePath := util.GetWorktreePath(repoPath, ticketID)
-
-	// Check if path already exists
-	if _, err := os.Stat(worktreePath); err == nil {
-		return fmt.Errorf("path already exists: %s", worktreePath)
-	}
-
-	// Create worktree
-	startPoint := fmt.Sprintf("origin/%s", meta.Mainline)
-	fmt.Printf("Creating worktree at %s...\n", worktreePath)
-	if err := repo.AddWorktree(worktreePath, branchName, startPoint); err != nil {
+	entry, err := m.Create(args[0], opts)
+	if err != nil {
 		return err
 	}
 
-	// Save metadata
-	meta.AddWorktree(ticketID, worktreePath, branchName)
-	if err := config.Save(repoPath, meta); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+	if !noTracker {
+		runLifecycle(ctx, settings, "create", settings.OnCreate, entry.Ticket, entry.Branch)
 	}
 
-	fmt.Printf("\nWorktree created successfully!\n")
-	fmt.Printf("  Ticket:  %s\n", ticketID)
-	fmt.Printf("  Branch:  %s\n", branchName)
-	fmt.Printf("  Path:    %s\n", worktreePath)
-	fmt.Printf("\nTo switch to this worktree:\n")
-	fmt.Printf("  cd %s\n", worktreePath)
+	fmt.Fprintf(ctx.Stdout, "\nWorktree created successfully!\n")
+	fmt.Fprintf(ctx.Stdout, "  Ticket:  %s\n", entry.Ticket)
+	if entry.TicketInfo != nil {
+		fmt.Fprintf(ctx.Stdout, "  Title:   %s\n", entry.TicketInfo.Title)
+	}
+	fmt.Fprintf(ctx.Stdout, "  Branch:  %s\n", entry.Branch)
+	fmt.Fprintf(ctx.Stdout, "  Path:    %s\n", entry.Path)
+	fmt.Fprintf(ctx.Stdout, "\nTo switch to this worktree:\n")
+	fmt.Fprintf(ctx.Stdout, "  cd %s\n", entry.Path)
 
 	return nil
 }
@@ -1,75 +1,71 @@
 package cmd
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 
 	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/git"
-	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// Delete removes a worktree and cleans up its branch.
-func Delete(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: git tree delete <ticket-id>")
-	}
-
-	ticketID := args[0]
-
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
-	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
-	}
+var deleteCommand = &Command{
+	Name:    "delete",
+	Aliases: []string{"rm"},
+	Args:    "<ticket-id>",
+	MinArgs: 1,
+	MaxArgs: 1,
+	Summary: "Delete a worktree and its branch",
+	Description: `Removes the worktree and deletes its local branch. If the worktree has uncommitted
+changes you're asked to confirm first, and with --yes they are discarded. With
+--workspace, the ticket's worktrees are removed from every repository of the workspace.`,
+	Examples: []string{"delete PROJ-123", "delete PROJ-123 --workspace platform"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		noTracker := noTrackerFlag(fs)
+		workspace := workspaceFlag(fs)
+		return func(ctx *Context, args []string) error {
+			if *workspace != "" {
+				return runWorkspaceDelete(ctx, *workspace, args, *noTracker)
+			}
+			return runDelete(ctx, args, *noTracker)
+		}
+	},
+}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+// runDelete removes a worktree and cleans up its branch.
+func runDelete(ctx *Context, args []string, noTracker bool) error {
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
-	}
-
-	// Check if worktree exists
-	entry, ok := meta.GetWorktree(ticketID)
-	if !ok {
-		return fmt.Errorf("worktree for %s not found", ticketID)
+		return err
 	}
 
-	// Initialize repo
-	repo := git.NewRepo(repoPath)
-
-	// Check if worktree has uncommitted changes
-	wtRepo := git.NewRepo(entry.Path)
-	clean, err := wtRepo.IsClean()
-	if err == nil && !clean {
-		fmt.Printf("Warning: worktree has uncommitted changes\n")
-		fmt.Printf("Path: %s\n", entry.Path)
-		fmt.Printf("Continue with deletion? (y/n): ")
-		var response string
-		fmt.Scanln(&response)
-		if response != "y" && response != "Y" {
-			return fmt.Errorf("deletion cancelled")
+	entry, err := m.Delete(args[0], gittree.DeleteOptions{})
+	if errors.Is(err, gittree.ErrDirty) {
+		fmt.Fprintf(ctx.Stdout, "Warning: worktree has uncommitted changes\n")
+		if entry, err := m.Get(args[0]); err == nil {
+			fmt.Fprintf(ctx.Stdout, "Path: %s\n", entry.Path)
 		}
+		ok, confirmErr := ctx.Confirm("Continue with deletion?", false)
+		if confirmErr != nil {
+			return confirmErr
+		}
+		if !ok {
+			return fmt.Errorf("deletion %w", ErrCancelled)
+		}
+		entry, err = m.Delete(args[0], gittree.DeleteOptions{Force: true})
 	}
-
-	// Remove worktree
-	fmt.Printf("Removing worktree at %s...\n", entry.Path)
-	if err := repo.RemoveWorktree(entry.Path); err != nil {
-		return fmt.Errorf("failed to remove worktree: %w", err)
-	}
-
-	// Delete branch
-	fmt.Printf("Deleting branch %s...\n", entry.Branch)
-	if err := repo.DeleteBranch(entry.Branch); err != nil {
-		// Don't fail if branch deletion fails (might be merged/deleted already)
-		fmt.Printf("Warning: failed to delete branch: %v\n", err)
+	if err != nil {
+		return err
 	}
 
-	// Update metadata
-	meta.RemoveWorktree(ticketID)
-	if err := config.Save(repoPath, meta); err != nil {
-		return fmt.Errorf("failed to save metadata: %w", err)
+	if !noTracker {
+		settings, err := config.LoadSettings(m.RepoPath())
+		if err != nil {
+			return err
+		}
+		runLifecycle(ctx, settings, "delete", settings.OnDelete, entry.Ticket, entry.Branch)
 	}
 
-	fmt.Printf("\nWorktree for %s deleted successfully.\n", ticketID)
+	fmt.Fprintf(ctx.Stdout, "\nWorktree for %s deleted successfully.\n", entry.Ticket)
 	return nil
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// ErrProblems means doctor found problems that are left unrepaired.
+var ErrProblems = errors.New("problems found")
+
+var doctorCommand = &Command{
+	Name:    "doctor",
+	Summary: "Diagnose and repair inconsistencies",
+	Description: `Cross-checks the metadata against git and the filesystem, and reports missing or
+untracked worktrees, branch mismatches, missing branches and similar problems. Exits
+with status 11 if problems are found, or with --fix, if some are left to repair by hand.
+A branch mismatch is repaired by checking out the recorded branch again.`,
+	Examples: []string{"doctor --fix"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		fix := fs.Bool("fix", false, "Repair the problems that can be repaired safely")
+		return func(ctx *Context, args []string) error {
+			return runDoctor(ctx, *fix)
+		}
+	},
+}
+
+// runDoctor cross-checks metadata against git and the filesystem and reports inconsistencies.
+// With fix, problems that can be repaired safely are repaired.
+func runDoctor(ctx *Context, fix bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Problems are still reported if the repaired metadata can't be saved
+	problems, err := m.Doctor(fix)
+	if problems == nil && err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No problems found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	if fix {
+		fmt.Fprintln(w, "CATEGORY\tTICKET\tPROBLEM\tRESULT")
+		fmt.Fprintln(w, "--------\t------\t-------\t------")
+	} else {
+		fmt.Fprintln(w, "CATEGORY\tTICKET\tPROBLEM")
+		fmt.Fprintln(w, "--------\t------\t-------")
+	}
+
+	fixable, unresolved := 0, 0
+	for _, p := range problems {
+		ticket := p.Ticket
+		if ticket == "" {
+			ticket = "-"
+		}
+
+		if !fix {
+			if p.Fixable {
+				fixable++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Category, ticket, p.Message)
+			continue
+		}
+
+		if !p.Fixed {
+			unresolved++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Category, ticket, p.Message, p.Result())
+	}
+	w.Flush()
+
+	if err != nil {
+		return err
+	}
+	if !fix {
+		if fixable > 0 {
+			fmt.Fprintf(ctx.Stdout, "\n%d of %d problems can be repaired with: git tree doctor --fix\n", fixable, len(problems))
+		}
+		return fmt.Errorf("%w: %d problem(s)", ErrProblems, len(problems))
+	}
+	if unresolved > 0 {
+		return fmt.Errorf("%w: %d of %d problem(s) need fixing by hand", ErrProblems, unresolved, len(problems))
+	}
+	return nil
+}
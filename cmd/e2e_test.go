@@ -0,0 +1,620 @@
+package cmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/harness"
+)
+
+// transcript records git-tree invocations and their output for comparison with a golden file.
+type transcript struct {
+	h *harness.Harness
+	b strings.Builder
+}
+
+// run runs git-tree in dir with stdin and records the command and its result.
+func (tr *transcript) run(dir, stdin string, args ...string) harness.Result {
+	result := tr.h.RunIn(dir, stdin, args...)
+	tr.b.WriteString("$ git tree " + strings.Join(args, " ") + "\n")
+	tr.b.WriteString(result.String())
+	tr.b.WriteString("\n")
+	return result
+}
+
+func TestLifecycle(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "init", "--yes")
+	tr.run(h.Repo, "", "create", "PROJ-1")
+	h.Golden("lifecycle-metadata", h.Metadata())
+
+	wt := filepath.Join(h.Root, "worktrees", "repo", "PROJ-1")
+	tr.run(h.Repo, "", "list")
+	tr.run(wt, "", "switch", "PROJ-1")
+
+	// Work on the ticket while the mainline moves on
+	h.Commit(wt, "feature.txt", "feature\n", "Add feature")
+	h.WriteFile(filepath.Join(wt, "notes.txt"), "scratch\n")
+	h.AdvanceMainline("mainline.txt", "mainline\n", "Change mainline")
+
+	tr.run(wt, "", "status", "PROJ-1")
+	if err := os.Remove(filepath.Join(wt, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Repo, "", "update", "PROJ-1")
+	tr.run(h.Repo, "", "status")
+
+	// Deleting a dirty worktree asks first
+	h.WriteFile(filepath.Join(wt, "notes.txt"), "scratch\n")
+	tr.run(h.Repo, "n\n", "delete", "PROJ-1")
+	tr.run(h.Repo, "y\n", "delete", "PROJ-1")
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("lifecycle", tr.b.String())
+	h.Golden("lifecycle-metadata-after", h.Metadata())
+}
+
+func TestPrune(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "init", "--yes")
+	tr.run(h.Repo, "", "create", "PROJ-2")
+	tr.run(h.Repo, "", "create", "PROJ-3")
+
+	// Remove a worktree behind git-tree's back
+	if err := os.RemoveAll(filepath.Join(h.Root, "worktrees", "repo", "PROJ-2")); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Repo, "", "list")
+	tr.run(h.Repo, "", "prune")
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("prune", tr.b.String())
+	h.Golden("prune-metadata", h.Metadata())
+}
+
+func TestInitPrompts(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// Accept the defaults, but require ticket IDs to look like PROJ-123
+	tr.run(h.Repo, "\n\n\n\nPROJ-\\d+\n", "init")
+	tr.run(h.Repo, "", "create", "PROJ-7")
+	tr.run(h.Repo, "", "create", "OTHER-1")
+
+	h.Golden("init-prompts", tr.b.String())
+}
+
+func TestNonInteractive(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// Without a terminal, commands fail instead of waiting for an answer
+	tr.run(h.Repo, "", "init")
+	tr.run(h.Repo, "\n\n\n\n\n", "--no-input", "init")
+	tr.run(h.Repo, "", "--yes", "init")
+	tr.run(h.Repo, "", "-C", "..", "-C", "repo", "create", "PROJ-5")
+
+	wt := filepath.Join(h.Root, "worktrees", "repo", "PROJ-5")
+	h.WriteFile(filepath.Join(wt, "notes.txt"), "scratch\n")
+	tr.run(h.Repo, "", "delete", "PROJ-5")
+	tr.run(h.Repo, "", "delete", "PROJ-5", "--yes")
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("non-interactive", tr.b.String())
+}
+
+func TestHelp(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "help")
+	tr.run(h.Repo, "", "delete", "--help")
+	tr.run(h.Repo, "", "help", "list")
+	tr.run(h.Repo, "", "rm", "-h")
+	h.Golden("help", tr.b.String())
+
+	// Every command's man page is written, and git-tree(1) refers to each of them
+	dir := filepath.Join(h.Root, "man")
+	h.MustRun("help", "--man", dir)
+	pages, err := filepath.Glob(filepath.Join(dir, "*.1"))
+	if err != nil || len(pages) < 2 {
+		t.Fatalf("man pages = %v, %v", pages, err)
+	}
+	main, err := os.ReadFile(filepath.Join(dir, "git-tree.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".1")
+		if name != "git-tree" && !strings.Contains(string(main), `\fB`+name+`\fR(1)`) {
+			t.Errorf("git-tree.1 doesn't refer to %s", name)
+		}
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "git-tree-list.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Golden("man-list", string(page))
+}
+
+func TestAliases(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "--yes", "init")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "create", "PROJ-2", "--no-tracker")
+	tr.run(h.Repo, "", "label", "PROJ-2", "wip")
+
+	// Aliases are defined in git config and take further arguments
+	h.Git(h.Repo, "config", "tree.alias.wip", "list --label wip")
+	h.Git(h.Repo, "config", "tree.alias.bad", "frobnicate")
+	tr.run(h.Repo, "", "wip")
+	tr.run(h.Repo, "", "wip", "--sort", "created")
+	tr.run(h.Repo, "", "ls")
+	tr.run(h.Repo, "", "bad")
+	tr.run(h.Repo, "", "wop")
+
+	h.Golden("aliases", tr.b.String())
+}
+
+func TestPlugins(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// A plugin is any git-tree-<name> executable on PATH
+	bin := filepath.Join(h.Root, "bin")
+	plugin := filepath.Join(bin, "git-tree-preview")
+	h.WriteFile(plugin, `#!/bin/sh
+if [ "$1" = --help ]; then
+	echo "usage: git tree preview [ticket-id]"
+	exit 0
+fi
+echo "args: $*"
+echo "repo: $GIT_TREE_REPO"
+echo "metadata: $GIT_TREE_METADATA"
+echo "mainline: $GIT_TREE_MAINLINE"
+echo "ticket: $GIT_TREE_TICKET"
+echo "worktree: $GIT_TREE_WORKTREE"
+echo "branch: $GIT_TREE_BRANCH"
+echo "entry: $GIT_TREE_ENTRY"
+exit 3
+`)
+	if err := os.Chmod(plugin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tr.run(h.Repo, "", "--yes", "init")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+
+	// The ticket is selected by the first argument, or else by the worktree the plugin runs in
+	tr.run(h.Repo, "", "preview", "PROJ-1", "--stat")
+	tr.run(filepath.Join(h.Root, "worktrees", "repo", "PROJ-1"), "", "preview")
+	tr.run(h.Repo, "", "preview")
+	tr.run(h.Repo, "", "help", "preview")
+	tr.run(h.Repo, "", "preveiw")
+
+	if result := h.Run("help"); !strings.Contains(result.Stdout, "Plugins:\n  preview\n") {
+		t.Errorf("help doesn't list the plugin:\n%s", result.Stdout)
+	}
+
+	h.Golden("plugins", tr.b.String())
+}
+
+func TestWorkspace(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// A workspace lists its repositories in the global git config
+	backend := h.AddRepo("backend")
+	frontend := h.AddRepo("frontend")
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", backend)
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", frontend)
+
+	// The branch is named with the first repository's template in every repository
+	h.Git(frontend, "config", "tree.branchTemplate", "feature/{ticket}")
+	tr.run(h.Root, "", "create", "PROJ-1", "--workspace", "platform", "--no-tracker")
+	tr.run(h.Root, "", "create", "proj-1", "--workspace", "platform", "--no-tracker")
+	tr.run(h.Root, "", "list", "--workspace", "platform")
+
+	// Each worktree is also managed by its own repository
+	tr.run(backend, "", "list")
+	tr.run(h.Root, "", "switch", "--global", "PROJ-1")
+
+	ticketDir := filepath.Join(h.Root, "workspaces", "platform", "PROJ-1")
+	h.WriteFile(filepath.Join(ticketDir, "frontend", "wip.txt"), "wip\n")
+	tr.run(h.Root, "", "status", "--workspace", "platform")
+	tr.run(h.Root, "", "status", "PROJ-1", "--workspace", "platform")
+	tr.run(h.Root, "", "update", "PROJ-1", "--workspace", "platform")
+	tr.run(h.Root, "", "push", "PROJ-1", "--workspace", "platform", "--no-tracker")
+
+	tr.run(h.Root, "", "delete", "PROJ-1", "--workspace", "platform", "--no-tracker")
+	tr.run(h.Root, "", "--yes", "delete", "PROJ-1", "--workspace", "platform", "--no-tracker")
+	tr.run(h.Root, "", "list", "--workspace", "platform")
+	if _, err := os.Stat(ticketDir); !os.IsNotExist(err) {
+		t.Errorf("ticket directory not removed: %v", err)
+	}
+
+	tr.run(h.Root, "", "create", "PROJ-2", "--workspace", "mobile")
+
+	h.Golden("workspace", tr.b.String())
+}
+
+func TestRegistry(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// Every repository with worktrees is registered when its metadata is saved
+	other := h.AddRepo("other")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(other, "", "create", "PROJ-2", "--no-tracker")
+	tr.run(other, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(h.Root, "", "index")
+	tr.run(h.Root, "", "list", "--global")
+	tr.run(h.Root, "", "switch", "--global", "PROJ-2")
+	tr.run(h.Root, "", "switch", "--global", "PROJ-1")
+	tr.run(h.Root, "", "switch", "--global", "PROJ-404")
+
+	// A lost registry is rebuilt from the code roots
+	if err := os.Remove(filepath.Join(h.Root, "home", ".local", "share", "git-tree", "registry.json")); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Root, "", "list", "--global")
+	tr.run(h.Root, "", "index", "rebuild")
+	h.Git(h.Root, "config", "--global", "--add", "tree.codeRoot", h.Root)
+	tr.run(h.Root, "", "index", "rebuild")
+	tr.run(h.Root, "", "list", "--global", "--sort", "created")
+	tr.run(h.Root, "", "index", "reindex")
+
+	h.Golden("registry", tr.b.String())
+}
+
+func TestStaleRegistry(t *testing.T) {
+	h := harness.New(t)
+	backend := h.AddRepo("backend")
+	frontend := h.AddRepo("frontend")
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", backend)
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", frontend)
+	h.RunIn(h.Root, "", "create", "PROJ-1", "--workspace", "platform", "--no-tracker")
+
+	// The registry still lists a worktree deleted while it was out of date
+	path := filepath.Join(h.Root, "home", ".local", "share", "git-tree", "registry.json")
+	stale, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := h.RunIn(frontend, "", "--yes", "delete", "PROJ-1", "--no-tracker"); result.Code != 0 {
+		t.Fatalf("delete failed: %s", result)
+	}
+	h.WriteFile(path, string(stale))
+
+	result := h.RunIn(h.Root, "", "switch", "--global", "PROJ-1")
+	if result.Code != 0 || strings.Contains(result.Stdout, "Warning") {
+		t.Errorf("switch with a stale registry:\n%s", result)
+	}
+	if data, err := os.ReadFile(filepath.Join(frontend, ".git", "worktree-metadata.json")); err != nil || strings.Contains(string(data), "PROJ-1") {
+		t.Errorf("stale ticket added back to the metadata: %s, %v", data, err)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "undo")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "label", "PROJ-1", "blocked")
+	tr.run(h.Repo, "", "undo")
+	tr.run(h.Repo, "", "label", "PROJ-1")
+
+	// An update is undone by resetting the branch to its old tip, unless it has moved since
+	wt := filepath.Join(h.Root, "worktrees", "repo", "PROJ-1")
+	h.Commit(wt, "feature.txt", "feature\n", "Add feature")
+	tip := h.Git(wt, "rev-parse", "HEAD")
+	h.AdvanceMainline("mainline.txt", "mainline\n", "Change mainline")
+	tr.run(h.Repo, "", "update", "PROJ-1")
+	h.Commit(wt, "more.txt", "more\n", "Add more")
+	tr.run(h.Repo, "", "undo")
+	h.Git(wt, "reset", "--quiet", "--hard", "HEAD~1")
+	tr.run(h.Repo, "", "undo")
+	if got := h.Git(wt, "rev-parse", "HEAD"); got != tip {
+		t.Errorf("PROJ-1 is at %s after undoing the update, want %s", got, tip)
+	}
+
+	// A created worktree is only removed while its branch hasn't moved
+	tr.run(h.Repo, "", "undo")
+	tr.run(h.Repo, "", "create", "PROJ-2", "--no-tracker")
+	tr.run(h.Repo, "", "undo")
+	if _, err := os.Stat(filepath.Join(h.Root, "worktrees", "repo", "PROJ-2")); !os.IsNotExist(err) {
+		t.Errorf("PROJ-2 worktree not removed: %v", err)
+	}
+
+	// A deleted worktree is recreated at its old branch tip
+	tr.run(h.Repo, "", "--yes", "delete", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "undo")
+	if got := h.Git(wt, "rev-parse", "HEAD"); got != tip {
+		t.Errorf("PROJ-1 is at %s after undoing the delete, want %s", got, tip)
+	}
+	tr.run(h.Repo, "", "list")
+	tr.run(h.Repo, "", "log")
+	tr.run(h.Repo, "", "log", "2")
+	tr.run(h.Repo, "", "log", "none")
+
+	h.Golden("undo", tr.b.String())
+}
+
+func TestArchive(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	wt := filepath.Join(h.Root, "worktrees", "repo", "PROJ-1")
+	h.Commit(wt, "feature.txt", "feature\n", "Add feature")
+	h.WriteFile(filepath.Join(wt, "feature.txt"), "feature\nchanged\n")
+	h.WriteFile(filepath.Join(wt, "wip.txt"), "wip\n")
+
+	// Ignored files can't be saved, so they are only deleted when forced
+	h.WriteFile(filepath.Join(h.Repo, ".git", "info", "exclude"), "*.log\n")
+	h.WriteFile(filepath.Join(wt, "build.log"), "output\n")
+	tr.run(h.Repo, "", "archive", "PROJ-1")
+	if _, err := os.Stat(filepath.Join(wt, "build.log")); err != nil {
+		t.Errorf("refused archive removed ignored files: %v", err)
+	}
+
+	// Uncommitted changes are saved and the worktree removed, keeping the branch
+	tr.run(h.Repo, "", "archive", "PROJ-1", "--force")
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("PROJ-1 worktree not removed: %v", err)
+	}
+	h.Git(h.Repo, "rev-parse", "--verify", "--quiet", "PROJ-1")
+	tr.run(h.Repo, "", "list")
+	tr.run(h.Repo, "", "archive", "PROJ-1")
+	tr.run(h.Repo, "", "switch", "PROJ-1")
+	tr.run(h.Repo, "", "update", "PROJ-1")
+	tr.run(h.Repo, "", "archive", "PROJ-404")
+
+	// Restoring needs the original path to be free
+	h.WriteFile(filepath.Join(wt, "squatter.txt"), "squatter\n")
+	tr.run(h.Repo, "", "restore", "PROJ-1")
+	if err := os.RemoveAll(wt); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Repo, "", "restore", "PROJ-1")
+	for name, want := range map[string]string{"feature.txt": "feature\nchanged\n", "wip.txt": "wip\n"} {
+		if data, err := os.ReadFile(filepath.Join(wt, name)); err != nil || string(data) != want {
+			t.Errorf("%s after restore = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	tr.run(h.Repo, "", "restore", "PROJ-1")
+	tr.run(h.Repo, "", "status", "PROJ-1")
+
+	// Archiving is undone by restoring
+	tr.run(h.Repo, "", "archive", "PROJ-1")
+	tr.run(h.Repo, "", "undo")
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("archive", tr.b.String())
+}
+
+func TestCheck(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	wt := func(ticket string) string { return filepath.Join(h.Root, "worktrees", "repo", ticket) }
+	for _, ticket := range []string{"PROJ-1", "PROJ-2", "PROJ-3"} {
+		tr.run(h.Repo, "", "create", ticket, "--no-tracker")
+	}
+	h.Commit(wt("PROJ-1"), "feature.txt", "feature\n", "Add feature")
+	h.Commit(wt("PROJ-2"), "shared.txt", "ticket\n", "Change shared file")
+	h.Commit(wt("PROJ-3"), "other.txt", "other\n", "Add other")
+	h.WriteFile(filepath.Join(wt("PROJ-3"), "wip.txt"), "wip\n")
+	h.AdvanceMainline("shared.txt", "mainline\n", "Change shared file on mainline")
+
+	// Conflicts are predicted without touching the worktrees
+	tr.run(h.Repo, "", "check")
+	tr.run(h.Repo, "", "check", "PROJ-1", "--no-fetch")
+	tr.run(h.Repo, "", "check", "PROJ-404")
+
+	// Worktrees predicted to conflict, or with uncommitted changes, are left alone
+	tr.run(h.Repo, "", "update", "--all")
+	if got := strings.TrimSpace(h.Git(wt("PROJ-2"), "log", "-1", "--format=%s")); got != "Change shared file" {
+		t.Errorf("PROJ-2 was rebased: HEAD is %q", got)
+	}
+	tr.run(h.Repo, "", "update", "--all", "--include-conflicts")
+	if _, err := os.Stat(filepath.Join(h.Repo, ".git", "worktrees", "PROJ-2", "rebase-merge")); !os.IsNotExist(err) {
+		t.Errorf("PROJ-2 left mid-rebase: %v", err)
+	}
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("check", tr.b.String())
+}
+
+func TestDoctor(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	for _, ticket := range []string{"PROJ-1", "PROJ-2", "PROJ-3", "PROJ-4"} {
+		tr.run(h.Repo, "", "create", ticket, "--no-tracker")
+	}
+	tr.run(h.Repo, "", "doctor")
+
+	// Break the worktrees behind git-tree's back. The table of repairs lines up with the
+	// temporary directory's path, so the missing worktree is repaired on its own.
+	wt := func(ticket string) string { return filepath.Join(h.Root, "worktrees", "repo", ticket) }
+	if err := os.RemoveAll(wt("PROJ-3")); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Repo, "", "doctor")
+	if result := h.RunIn(h.Repo, "", "doctor", "--fix"); result.Code != 0 || !strings.Contains(result.Stdout, "fixed") {
+		t.Errorf("doctor --fix: %s", result)
+	}
+	h.Git(wt("PROJ-1"), "checkout", "-q", "-b", "experiment")
+	h.Git(wt("PROJ-2"), "checkout", "-q", "-b", "renamed")
+	h.Git(h.Repo, "branch", "-D", "PROJ-2")
+	h.Git(wt("PROJ-4"), "checkout", "-q", "-b", "spike")
+	h.WriteFile(filepath.Join(wt("PROJ-4"), "wip.txt"), "wip\n")
+
+	// Problems are reported with a non-zero exit code until they're all repaired
+	tr.run(h.Repo, "", "doctor")
+	tr.run(h.Repo, "", "doctor", "--fix")
+	if branch := strings.TrimSpace(h.Git(wt("PROJ-1"), "branch", "--show-current")); branch != "PROJ-1" {
+		t.Errorf("PROJ-1 worktree is on %s, want PROJ-1", branch)
+	}
+	h.Git(h.Repo, "rev-parse", "--verify", "--quiet", "experiment")
+	tr.run(h.Repo, "", "list")
+
+	// Once the rest are fixed by hand, doctor succeeds
+	h.Git(wt("PROJ-2"), "branch", "-q", "-m", "PROJ-2")
+	if err := os.Remove(filepath.Join(wt("PROJ-4"), "wip.txt")); err != nil {
+		t.Fatal(err)
+	}
+	tr.run(h.Repo, "", "doctor", "--fix")
+	tr.run(h.Repo, "", "doctor")
+
+	h.Golden("doctor", tr.b.String())
+}
+
+func TestSync(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// Tickets are shared with another clone through the remote
+	laptop := h.Clone("laptop")
+	tr.run(h.Repo, "", "sync")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "push", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "create", "PROJ-2", "--no-tracker")
+	tr.run(h.Repo, "", "label", "PROJ-1", "blocked")
+	tr.run(h.Repo, "", "sync")
+	tr.run(laptop, "", "sync")
+	tr.run(laptop, "", "list")
+
+	// Concurrent changes are merged
+	tr.run(h.Repo, "", "note", "PROJ-1", "from the desk")
+	tr.run(h.Repo, "", "--yes", "delete", "PROJ-2", "--no-tracker")
+	tr.run(laptop, "", "label", "PROJ-1", "review")
+	tr.run(laptop, "", "sync", "--no-push")
+	tr.run(laptop, "", "sync")
+	tr.run(h.Repo, "", "sync")
+	tr.run(h.Repo, "", "label", "PROJ-1")
+	tr.run(laptop, "", "sync")
+	tr.run(laptop, "", "note", "PROJ-1")
+
+	// A ticket deleted elsewhere keeps its worktree while it has unpushed commits
+	tr.run(h.Repo, "", "create", "PROJ-3", "--no-tracker")
+	tr.run(h.Repo, "", "sync")
+	tr.run(laptop, "", "sync")
+	h.Commit(filepath.Join(h.Root, "worktrees", "laptop", "PROJ-3"), "local.txt", "local\n", "Local work")
+	tr.run(h.Repo, "", "--yes", "delete", "PROJ-3", "--no-tracker")
+	tr.run(h.Repo, "", "sync")
+	tr.run(laptop, "", "sync")
+	tr.run(laptop, "", "list")
+	h.Git(laptop, "rev-parse", "--verify", "--quiet", "PROJ-3")
+
+	h.Golden("sync", tr.b.String())
+}
+
+func TestStorage(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	tr.run(h.Repo, "", "label", "PROJ-1", "blocked", "review")
+	tr.run(h.Repo, "", "note", "PROJ-1", "waiting on", "API review")
+
+	// The metadata moves between backends unchanged
+	tr.run(h.Repo, "", "migrate-storage", "gitconfig")
+	h.Golden("storage-gitconfig", h.Normalize(h.Git(h.Repo, "config", "--local", "--get-regexp", `^tree`)))
+	if _, err := os.Stat(filepath.Join(h.Repo, ".git", "worktree-metadata.json")); !os.IsNotExist(err) {
+		t.Errorf("old metadata not removed: %v", err)
+	}
+	tr.run(h.Repo, "", "create", "PROJ-2", "--no-tracker")
+	tr.run(h.Repo, "", "label", "PROJ-1", "--remove", "review")
+	tr.run(h.Repo, "", "list")
+
+	tr.run(h.Repo, "", "migrate-storage", "kv")
+	data, err := os.ReadFile(filepath.Join(h.Repo, ".git", "worktree-metadata.kv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Golden("storage-kv", h.Normalize(string(data)))
+	tr.run(h.Repo, "", "delete", "PROJ-2", "--yes", "--no-tracker")
+	want := h.Metadata()
+
+	tr.run(h.Repo, "", "migrate-storage", "json", "--keep")
+	if got := h.Metadata(); got != want {
+		t.Errorf("metadata changed after migrating back:\n%s\nwant:\n%s", got, want)
+	}
+	tr.run(h.Repo, "", "migrate-storage", "gitconfig", "--from", "kv")
+	tr.run(h.Repo, "", "migrate-storage", "json", "--from", "kv")
+	tr.run(h.Repo, "", "--yes", "migrate-storage", "json")
+	tr.run(h.Repo, "", "migrate-storage", "json")
+	// A migration can't be undone; migrating back is the way to reverse it
+	tr.run(h.Repo, "", "undo")
+	tr.run(h.Repo, "", "migrate-storage", "sqlite")
+	h.Git(h.Repo, "config", "tree.storage", "sqlite")
+	tr.run(h.Repo, "", "list")
+
+	h.Golden("storage", tr.b.String())
+}
+
+func TestTrace(t *testing.T) {
+	h := harness.New(t)
+	h.MustRun("create", "PROJ-1", "--no-tracker")
+
+	// Tracing applies to the run it was asked for, not to git-tree as a whole
+	path := filepath.Join(h.Root, "trace.log")
+	t.Setenv("GIT_TREE_TRACE", path)
+	h.MustRun("status", "PROJ-1")
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "status --porcelain") {
+		t.Errorf("trace file = %q, %v", data, err)
+	}
+	t.Setenv("GIT_TREE_TRACE", "")
+	if result := h.MustRun("--verbose", "list"); !strings.Contains(result.Stderr, "trace:") {
+		t.Errorf("--verbose didn't trace: %s", result)
+	}
+	if runner, ok := git.DefaultRunner.(*git.ExecRunner); !ok || runner.Trace != nil {
+		t.Errorf("git.DefaultRunner changed to %#v", git.DefaultRunner)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := harness.New(t)
+	tr := &transcript{h: h}
+
+	// Usage is printed when the command is missing, so only check the exit code
+	if result := h.Run(); result.Code != 1 || !strings.Contains(result.Stdout, "Usage:") {
+		t.Errorf("no command: %s", result)
+	}
+
+	tr.run(h.Repo, "", "bogus")
+	tr.run(h.Repo, "", "lsit")
+	tr.run(h.Repo, "", "create")
+	tr.run(h.Repo, "", "create", "PROJ-1", "branch", "extra")
+	tr.run(h.Repo, "", "list", "--bogus")
+	tr.run(h.Repo, "", "--json", "push", "PROJ-1")
+	tr.run(h.Repo, "", "update", "PROJ-1", "--all")
+	tr.run(h.Repo, "", "create", "../escape")
+	tr.run(h.Repo, "", "delete", "PROJ-404")
+	tr.run(h.Repo, "", "switch", "PROJ-404")
+	tr.run(h.Root, "", "list")
+
+	// Under --json, errors are written to stderr as JSON
+	tr.run(h.Repo, "", "--json", "status", "PROJ-404")
+	tr.run(h.Root, "", "--json", "list")
+	tr.run(h.Repo, "", "create", "PROJ-1", "--no-tracker")
+	h.WriteFile(filepath.Join(h.Root, "worktrees", "repo", "PROJ-1", "wip.txt"), "wip\n")
+	tr.run(h.Repo, "", "update", "PROJ-1")
+	tr.run(h.Repo, "", "delete", "PROJ-1")
+
+	h.Golden("errors", tr.b.String())
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/tracker"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// Exit codes, documented in the README. Scripts rely on them, so never renumber.
+const (
+	exitError         = 1
+	exitUsage         = 2
+	exitNotRepository = 3
+	exitNotFound      = 4
+	exitExists        = 5
+	exitDirty         = 6
+	exitConflict      = 7
+	exitGit           = 8
+	exitNoInput       = 9
+	exitConfig        = 10
+	exitProblems      = 11
+)
+
+// exitCodes maps kinds of error to exit codes and the kind reported in JSON errors.
+// The first match wins, so more specific kinds come first.
+var exitCodes = []struct {
+	err  error
+	code int
+	kind string
+}{
+	{ErrUsage, exitUsage, "usage"},
+	{config.ErrInvalidTicket, exitUsage, "invalid_ticket"},
+	{git.ErrNotRepository, exitNotRepository, "not_repository"},
+	{config.ErrNotFound, exitNotFound, "not_found"},
+	{tracker.ErrNotFound, exitNotFound, "ticket_not_found"},
+	{config.ErrExists, exitExists, "exists"},
+	{git.ErrDirty, exitDirty, "dirty"},
+	{gittree.ErrRebase, exitConflict, "rebase_failed"},
+	{git.ErrConflict, exitConflict, "conflict"},
+	{ErrNoInput, exitNoInput, "no_input"},
+	{ErrCancelled, exitNoInput, "cancelled"},
+	{config.ErrInvalidSettings, exitConfig, "invalid_config"},
+	{config.ErrCorrupt, exitConfig, "corrupt_metadata"},
+	{ErrProblems, exitProblems, "problems"},
+	{config.ErrArchived, exitError, "archived"},
+	{config.ErrNoMainline, exitError, "no_mainline"},
+}
+
+// classify returns the exit code and kind for an error.
+func classify(err error) (int, string) {
+	for _, c := range exitCodes {
+		if errors.Is(err, c.err) {
+			return c.code, c.kind
+		}
+	}
+	var exitErr *git.ExitError
+	if errors.As(err, &exitErr) {
+		return exitGit, "git"
+	}
+	return exitError, "error"
+}
+
+// errorJSON is the JSON form of an error, written to stderr under --json.
+type errorJSON struct {
+	Kind     string        `json:"kind"`
+	Message  string        `json:"message"`
+	ExitCode int           `json:"exit_code"`
+	Ticket   string        `json:"ticket,omitempty"`
+	Git      *gitErrorJSON `json:"git,omitempty"`
+}
+
+// gitErrorJSON describes a failed git command.
+type gitErrorJSON struct {
+	Args     []string `json:"args"`
+	ExitCode int      `json:"exit_code"`
+	Stderr   string   `json:"stderr,omitempty"`
+}
+
+// fail reports an error and returns the exit code for it. Under --json the error is
+// written to stderr as a JSON object, so scripts can tell failures apart without parsing
+// messages.
+func fail(ctx *Context, err error) int {
+	code, kind := classify(err)
+	if !ctx.JSON {
+		fmt.Fprintf(ctx.Stderr, "Error: %v\n", err)
+		return code
+	}
+
+	out := errorJSON{Kind: kind, Message: err.Error(), ExitCode: code}
+	var e *gittree.Error
+	var configErr *config.Error
+	if errors.As(err, &e) {
+		out.Ticket = e.Ticket
+	} else if errors.As(err, &configErr) {
+		out.Ticket = configErr.Ticket
+	}
+	var exitErr *git.ExitError
+	if errors.As(err, &exitErr) {
+		out.Git = &gitErrorJSON{
+			Args:     exitErr.Args,
+			ExitCode: exitErr.ExitCode,
+			Stderr:   strings.TrimSpace(string(exitErr.Stderr)),
+		}
+	}
+	enc := json.NewEncoder(ctx.Stderr)
+	enc.SetIndent("", "  ")
+	enc.Encode(map[string]errorJSON{"error": out})
+	return code
+}
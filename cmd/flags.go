@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// newFlagSet returns an empty flag set that reports errors to its caller rather than
+// printing them.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// shorthand defines a single-letter flag that is another name for an existing flag.
+func shorthand(fs *flag.FlagSet, short, long string) {
+	fs.Var(fs.Lookup(long).Value, short, "")
+}
+
+// parseArgs parses flags that may appear anywhere among the positional arguments, and
+// returns the positional arguments. Everything after "--" is positional.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, flagError(err)
+		}
+		rest := fs.Args()
+		// The flag package stops at the first non-flag argument or after "--"
+		if parsed := args[:len(args)-len(rest)]; len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// flagMention matches a flag named in an error from the flag package, which always
+// writes it with a single dash.
+var flagMention = regexp.MustCompile(`(^|\s)-([\w-]+)`)
+
+// flagError rewrites an error from the flag package to name flags as they're documented.
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	msg := strings.Replace(err.Error(), "flag provided but not defined", "unknown option", 1)
+	msg = flagMention.ReplaceAllStringFunc(msg, func(m string) string {
+		name := strings.TrimLeft(m, " \t-")
+		return m[:len(m)-len(name)-1] + flagName(name)
+	})
+	return errors.New(msg)
+}
+
+// globalOptions holds the global options that aren't kept in the context.
+type globalOptions struct {
+	verbose bool
+}
+
+// defineGlobalFlags defines the options every command accepts on fs. The directory option
+// is only accepted before the command, and --json only by commands that support it.
+// Options already given keep their values.
+func defineGlobalFlags(fs *flag.FlagSet, ctx *Context, opts *globalOptions, dir, json bool) {
+	if dir {
+		fs.Func("C", "Run as if git-tree was started in `path`", func(path string) error {
+			// Like git, each -C is applied relative to the previous one
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(ctx.Dir, path)
+			}
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				return fmt.Errorf("cannot change to %s: not a directory", path)
+			}
+			ctx.Dir = path
+			return nil
+		})
+	}
+	if json {
+		fs.BoolVar(&ctx.JSON, "json", ctx.JSON, "Write output as JSON")
+	}
+	fs.BoolVar(&ctx.Yes, "yes", ctx.Yes, "Answer yes to confirmations and accept defaults")
+	shorthand(fs, "y", "yes")
+	fs.BoolFunc("no-input", "Never prompt; fail if an answer is needed (the default when stdin isn't a terminal)", func(string) error {
+		ctx.Interactive = false
+		return nil
+	})
+	fs.BoolFunc("no-color", "Don't color output (or set NO_COLOR)", func(string) error {
+		ctx.Color = false
+		return nil
+	})
+	fs.BoolVar(&opts.verbose, "verbose", opts.verbose, "Log every git command and its duration to stderr (or set GIT_TREE_TRACE)")
+	shorthand(fs, "v", "verbose")
+}
+
+// globalFlagSet returns a flag set with only the global options defined, for help.
+func globalFlagSet() *flag.FlagSet {
+	fs := newFlagSet("git tree")
+	defineGlobalFlags(fs, &Context{}, &globalOptions{}, true, true)
+	return fs
+}
+
+// flagHelp returns a row for each flag defined on fs, naming the flag and its shorthand
+// and describing it.
+func flagHelp(fs *flag.FlagSet) [][2]string {
+	var rows [][2]string
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Usage == "" {
+			return
+		}
+
+		names := flagName(f.Name)
+		fs.VisitAll(func(short *flag.Flag) {
+			if short.Usage == "" && short.Value == f.Value {
+				names = flagName(short.Name) + ", " + names
+			}
+		})
+		arg, usage := flag.UnquoteUsage(f)
+		if arg != "" {
+			names += " <" + arg + ">"
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		rows = append(rows, [2]string{names, usage})
+	})
+	return rows
+}
+
+// flagName returns how a flag is written on the command line: single-letter flags with
+// one dash, and others with two.
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
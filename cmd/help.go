@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var helpCommand = &Command{
+	Name:    "help",
+	Args:    "[command]",
+	MaxArgs: 1,
+	Summary: "Show help for git-tree or a command",
+	Description: `With --man, writes a man page for git-tree and for each command to a directory instead,
+e.g. ~/.local/share/man/man1, so that man git-tree and git help tree work.`,
+	Examples: []string{"help create", "help --man ~/.local/share/man/man1"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		manDir := fs.String("man", "", "Write man pages to `directory`")
+		return func(ctx *Context, args []string) error {
+			return runHelp(ctx, args, *manDir)
+		}
+	},
+}
+
+// runHelp shows the overview or a command's help, or writes the man pages.
+func runHelp(ctx *Context, args []string, manDir string) error {
+	if manDir != "" {
+		if len(args) > 0 {
+			return invalidArgs("--man takes no command")
+		}
+		if !filepath.IsAbs(manDir) {
+			manDir = filepath.Join(ctx.Dir, manDir)
+		}
+		return writeManPages(ctx, manDir)
+	}
+
+	if len(args) == 0 {
+		writeUsage(ctx.Stdout, ctx.plugins())
+		return nil
+	}
+	command := findCommand(args[0])
+	if command == nil {
+		// A plugin documents itself
+		if path := ctx.findPlugin(args[0]); path != "" {
+			code, err := runPlugin(ctx, path, []string{"--help"}, false)
+			if err == nil && code != 0 {
+				err = fmt.Errorf("%s --help exited with status %d", filepath.Base(path), code)
+			}
+			return err
+		}
+		return fmt.Errorf("no help for %s: it is not a git-tree command", args[0])
+	}
+	command.writeHelp(ctx.Stdout)
+	return nil
+}
+
+// writeManPages writes git-tree(1) and a git-tree-<command>(1) page for every command to dir.
+func writeManPages(ctx *Context, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create man page directory: %w", err)
+	}
+
+	pages := map[string]func(io.Writer){"git-tree": writeMainManPage}
+	for _, c := range commands {
+		pages["git-tree-"+c.Name] = c.writeManPage
+	}
+	for name, write := range pages {
+		var b bytes.Buffer
+		write(&b)
+		path := filepath.Join(dir, name+".1")
+		if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+			return fmt.Errorf("failed to write man page: %w", err)
+		}
+	}
+
+	fmt.Fprintf(ctx.Stdout, "Wrote %d man pages to %s\n", len(pages), dir)
+	return nil
+}
+
+// writeMainManPage writes the git-tree(1) man page.
+func writeMainManPage(w io.Writer) {
+	fmt.Fprintf(w, ".TH GIT-TREE 1 \"\" \"git-tree\" \"git-tree Manual\"\n")
+	fmt.Fprintf(w, ".SH NAME\ngit-tree \\- Git worktree management tool\n")
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B git tree\n[\\fIoptions\\fR] \\fIcommand\\fR [\\fIarguments\\fR]\n")
+	fmt.Fprintf(w, ".SH DESCRIPTION\n%s\n", roff("git-tree manages one git worktree per ticket: it creates them from the latest mainline, keeps them up to date, and cleans them up, recording every change so it can be undone."))
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	writeManOptions(w, globalFlagSet())
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s. See \\fBgit-tree-%s\\fR(1).\n", roff(strings.TrimSpace(c.Name+" "+c.Args)), roff(c.Summary), c.Name)
+	}
+	fmt.Fprintf(w, ".SH SEE ALSO\n\\fBgit\\fR(1), \\fBgit-worktree\\fR(1)\n")
+}
+
+// writeManPage writes the git-tree-<command>(1) man page.
+func (c *Command) writeManPage(w io.Writer) {
+	name := "git-tree-" + c.Name
+	fmt.Fprintf(w, ".TH %s 1 \"\" \"git-tree\" \"git-tree Manual\"\n", strings.ToUpper(name))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", name, roff(c.Summary))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B git tree %s\n", c.Name)
+	if rest := strings.TrimPrefix(c.synopsis(), "git tree "+c.Name); rest != "" {
+		fmt.Fprintf(w, "%s\n", roff(strings.TrimSpace(rest)))
+	}
+
+	fmt.Fprintf(w, ".SH DESCRIPTION\n%s.\n", roff(c.Summary))
+	if c.Description != "" {
+		fmt.Fprintf(w, ".PP\n%s\n", roff(c.Description))
+	}
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(w, ".PP\nAlso available as %s.\n", roff(strings.Join(c.Aliases, ", ")))
+	}
+
+	if fs, _ := c.flags(); len(flagHelp(fs)) > 0 {
+		fmt.Fprintf(w, ".SH OPTIONS\n")
+		writeManOptions(w, fs)
+	}
+
+	if len(c.Examples) > 0 {
+		fmt.Fprintf(w, ".SH EXAMPLES\n.nf\n")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "%s\n", roff("git tree "+example))
+		}
+		fmt.Fprintf(w, ".fi\n")
+	}
+	fmt.Fprintf(w, ".SH SEE ALSO\n\\fBgit-tree\\fR(1)\n")
+}
+
+// writeManOptions writes a tagged paragraph for each flag defined on fs.
+func writeManOptions(w io.Writer, fs *flag.FlagSet) {
+	for _, row := range flagHelp(fs) {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roff(row[0]), roff(row[1]))
+	}
+}
+
+// roff escapes text for a man page: backslashes and dashes are escaped, and lines can't
+// start with a control character.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// codeRootDepth is how many directory levels below a code root index rebuild searches for
+// repositories.
+const codeRootDepth = 4
+
+var indexCommand = &Command{
+	Name:    "index",
+	Args:    "[rebuild]",
+	MaxArgs: 1,
+	Summary: "Show or rebuild the registry of repositories",
+	Description: `Every repository with worktrees is recorded in a registry under XDG_DATA_HOME whenever
+its metadata is saved, so list --global and switch --global work from any directory.
+Without arguments, shows the registered repositories. rebuild scans the directories in
+tree.codeRoot, and the repositories already registered, and replaces the registry with
+what it finds.`,
+	Examples: []string{"index", "index rebuild"},
+	JSON:     true,
+	Setup:    func(*flag.FlagSet) RunFunc { return runIndex },
+}
+
+// runIndex shows the registry, or rebuilds it.
+func runIndex(ctx *Context, args []string) error {
+	path, err := registryPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		if args[0] != "rebuild" {
+			return invalidArgs(fmt.Sprintf("unknown index action %q", args[0]))
+		}
+		return rebuildIndex(ctx, path)
+	}
+
+	reg, err := config.LoadRegistry(path)
+	if err != nil {
+		return err
+	}
+	if ctx.JSON {
+		return writeJSON(ctx, reg)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "Registry: %s\n\n", path)
+	if len(reg.Repos) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No repositories registered.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKTREES\tUPDATED\tREPOSITORY")
+	fmt.Fprintln(w, "---------\t-------\t----------")
+	for _, repoPath := range reg.RepoPaths() {
+		repo := reg.Repos[repoPath]
+		fmt.Fprintf(w, "%d\t%s\t%s\n", len(repo.Worktrees), formatAge(repo.Updated, ctx.Now()), repoPath)
+	}
+	w.Flush()
+	return nil
+}
+
+// rebuildIndex replaces the registry at path with the repositories with worktrees found in
+// the code roots and among those already registered.
+func rebuildIndex(ctx *Context, path string) error {
+	roots, err := config.LoadCodeRoots(ctx.Dir)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No code roots configured, only checking registered repositories.")
+		fmt.Fprintln(ctx.Stdout, "Add one with: git config --global --add tree.codeRoot <path>")
+	}
+
+	// A registry that can't be read is replaced anyway
+	old, err := config.LoadRegistry(path)
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+		old = &config.Registry{Repos: make(map[string]config.RegistryRepo)}
+	}
+
+	candidates := make(map[string]bool)
+	for _, root := range roots {
+		fmt.Fprintf(ctx.Stdout, "Scanning %s...\n", root)
+		for _, repoPath := range util.FindRepositories(root, codeRootDepth) {
+			candidates[repoPath] = true
+		}
+	}
+	for _, repoPath := range old.RepoPaths() {
+		if found, err := util.FindPrimaryRepoPath(repoPath); err == nil {
+			candidates[found] = true
+		}
+	}
+
+	repoPaths := make([]string, 0, len(candidates))
+	for repoPath := range candidates {
+		repoPaths = append(repoPaths, repoPath)
+	}
+	repos, worktrees, err := gittree.RebuildRegistry(repoPaths, gittree.Options{Log: ctx.Stdout, Getenv: ctx.Getenv, Now: ctx.Now, Trace: ctx.trace})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.Stdout, "\nRegistered %d repositories with %d worktree(s).\n", repos, worktrees)
+	return nil
+}
+
+// registryPath returns the path to the user's registry.
+func registryPath(ctx *Context) (string, error) {
+	path := config.RegistryPath(ctx.Getenv)
+	if path == "" {
+		return "", fmt.Errorf("no registry: neither XDG_DATA_HOME nor HOME is set")
+	}
+	return path, nil
+}
+
+// loadRegistry reads the user's registry.
+func loadRegistry(ctx *Context) (*config.Registry, error) {
+	path, err := registryPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.LoadRegistry(path)
+}
+
+// globalStatus is a worktree in the list of every registered repository's worktrees.
+type globalStatus struct {
+	Repo string `json:"repo"`
+	gittree.Status
+}
+
+// runGlobalList displays the worktrees of every registered repository with every one of
+// the labels.
+func runGlobalList(ctx *Context, labels []string, sortBy string) error {
+	reg, err := loadRegistry(ctx)
+	if err != nil {
+		return err
+	}
+
+	results := []globalStatus{}
+	for _, repoPath := range reg.RepoPaths() {
+		m, err := gittree.Open(repoPath, gittree.Options{Getenv: ctx.Getenv, Now: ctx.Now, Trace: ctx.trace})
+		if err != nil {
+			fmt.Fprintf(ctx.Stderr, "Warning: skipping %s: %v (run: git tree index rebuild)\n", repoPath, err)
+			continue
+		}
+		statuses, err := m.List(gittree.ListOptions{Labels: labels, Sort: sortBy})
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			results = append(results, globalStatus{Repo: repoPath, Status: s})
+		}
+	}
+
+	switch sortBy {
+	case gittree.SortActive:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].LastActive.After(results[j].LastActive) })
+	case gittree.SortCreated:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Created.After(results[j].Created) })
+	default:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Ticket < results[j].Ticket })
+	}
+
+	if ctx.JSON {
+		return writeJSON(ctx, results)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No worktrees found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKET\tREPO\tBRANCH\tSTATUS\tLAST ACTIVE\tLABELS\tPATH")
+	fmt.Fprintln(w, "------\t----\t------\t------\t-----------\t------\t----")
+
+	now := ctx.Now()
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", result.Ticket, util.GetRepoName(result.Repo), result.Branch, ctx.colorize(stateColors[result.State], listState(result.Status)), formatAge(result.LastActive, now), strings.Join(result.Labels, ","), result.Path)
+	}
+
+	w.Flush()
+	return nil
+}
+
+// findGlobal returns the registered worktrees of a ticket. It is an error if there are
+// none.
+func findGlobal(ctx *Context, ticketID string) ([]config.RegistryMatch, error) {
+	reg, err := loadRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := reg.Find(ticketID)
+	if len(matches) == 0 {
+		return nil, config.NewError(config.ErrNotFound, ticketID, "worktree for %s not found in any registered repository", ticketID)
+	}
+	return matches, nil
+}
+
+// sharedTicketDir returns the ticket directory of worktrees created together in a
+// workspace, or "" if the worktrees don't share one.
+func sharedTicketDir(matches []config.RegistryMatch) string {
+	dir := filepath.Dir(matches[0].Entry.Path)
+	for _, match := range matches {
+		if match.Entry.Workspace == "" || match.Entry.Workspace != matches[0].Entry.Workspace || filepath.Dir(match.Entry.Path) != dir {
+			return ""
+		}
+	}
+	return dir
+}
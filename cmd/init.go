@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var initCommand = &Command{
+	Name:    "init",
+	Summary: "Configure git-tree for a repository",
+	Description: `Walks through the remote, mainline branch, worktree root, branch template and ticket
+pattern, showing the detected value as the default, and saves them in the repository's
+git config. Existing worktrees git-tree doesn't know about can be adopted.`,
+	Examples: []string{"init", "init --yes --adopt"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		adopt := fs.Bool("adopt", false, "Adopt every existing worktree without asking")
+		return func(ctx *Context, args []string) error {
+			return runInit(ctx, *adopt)
+		}
+	},
+}
+
+// runInit bootstraps git-tree for a repository: it confirms the mainline, remote,
+// worktree root, branch template and ticket pattern, writes them to the repository
+// configuration, and optionally adopts existing worktrees.
+func runInit(ctx *Context, adopt bool) error {
+
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+	repoPath := m.RepoPath()
+
+	settings, err := config.LoadSettings(repoPath)
+	if err != nil {
+		return err
+	}
+
+	repo := ctx.repo(repoPath)
+
+	// Remote
+	remote, err := ctx.Prompt("Remote", settings.Remote)
+	if err != nil {
+		return err
+	}
+	if !repo.HasRemote(remote) {
+		return fmt.Errorf("remote %s does not exist", remote)
+	}
+	repo.Remote = remote
+
+	// Without the remote HEAD, mainline detection can only guess at common branch names
+	if _, err := repo.RemoteHead(); err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: %s/HEAD is not set, so mainline detection falls back to guessing.\n", remote)
+		setHead, err := ctx.Confirm(fmt.Sprintf("Run 'git remote set-head %s -a' now?", remote), true)
+		if err != nil {
+			return err
+		}
+		if setHead {
+			if err := repo.SetRemoteHead(); err != nil {
+				fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// Mainline
+	mainline := m.Mainline()
+	if mainline == "" {
+		if detected, err := repo.DetectMainline(); err == nil {
+			mainline = detected
+		}
+	}
+	mainline, err = ctx.Prompt("Mainline branch", mainline)
+	if err != nil {
+		return err
+	}
+	if mainline == "" {
+		return fmt.Errorf("a mainline branch is required")
+	}
+	if _, err := repo.ResolveCommit(remote + "/" + mainline); err != nil {
+		return fmt.Errorf("%s/%s does not exist", remote, mainline)
+	}
+
+	// Worktree root
+	root, err := ctx.Prompt("Worktree root", settings.WorktreeBasePath(repoPath))
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(ctx.Dir, root)
+	}
+
+	// Branch template and ticket patterns
+	template, err := ctx.Prompt("Branch template ({ticket} and {slug} are replaced)", settings.BranchTemplate)
+	if err != nil {
+		return err
+	}
+	patterns := settings.TicketPatterns
+	if len(patterns) > 1 {
+		// Several project patterns can only be edited with git config
+		fmt.Fprintf(ctx.Stdout, "Ticket patterns: %s\n", strings.Join(patterns, ", "))
+	} else {
+		def := ""
+		if len(patterns) == 1 {
+			def = patterns[0]
+		}
+		pattern, err := ctx.Prompt("Ticket pattern (regular expression, - for any)", def)
+		if err != nil {
+			return err
+		}
+		patterns = nil
+		if pattern != "-" && pattern != "" {
+			patterns = []string{pattern}
+		}
+	}
+
+	newSettings := *settings
+	newSettings.Remote = remote
+	newSettings.WorktreeRoot = root
+	newSettings.BranchTemplate = template
+	newSettings.TicketPatterns = patterns
+	if err := newSettings.Validate(); err != nil {
+		return err
+	}
+	if err := config.SaveSettings(repoPath, &newSettings); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create worktree root: %w", err)
+	}
+
+	// The manager has to see the new settings, such as the ticket pattern adopted
+	// worktrees are checked against
+	if err := m.Reload(); err != nil {
+		return err
+	}
+
+	// Adopt existing worktrees that git-tree doesn't know about yet
+	adopted, err := m.Init(gittree.InitOptions{
+		Mainline: mainline,
+		Adopt: func(path, branch string) (string, bool) {
+			// Without --adopt, worktrees are only adopted when the user agrees
+			if !adopt {
+				if ctx.Yes {
+					return "", false
+				}
+				ok, err := ctx.Confirm(fmt.Sprintf("Adopt worktree %s (%s)?", path, branch), false)
+				if err != nil || !ok {
+					return "", false
+				}
+			}
+			// Suggest the ticket ID found in the branch name, falling back to the directory name
+			def := newSettings.ExtractTicket(branch)
+			if def == "" {
+				def = filepath.Base(path)
+			}
+			ticketID, err := ctx.Prompt("  Ticket ID", def)
+			return ticketID, err == nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nRepository initialized!\n")
+	fmt.Fprintf(ctx.Stdout, "  Remote:          %s\n", newSettings.Remote)
+	fmt.Fprintf(ctx.Stdout, "  Mainline:        %s\n", m.Mainline())
+	fmt.Fprintf(ctx.Stdout, "  Worktree root:   %s\n", newSettings.WorktreeRoot)
+	fmt.Fprintf(ctx.Stdout, "  Branch template: %s\n", newSettings.BranchTemplate)
+	if len(newSettings.TicketPatterns) > 0 {
+		fmt.Fprintf(ctx.Stdout, "  Ticket pattern:  %s\n", strings.Join(newSettings.TicketPatterns, ", "))
+	}
+	if len(adopted) > 0 {
+		fmt.Fprintf(ctx.Stdout, "  Adopted:         %d worktree(s)\n", len(adopted))
+	}
+
+	return nil
+}
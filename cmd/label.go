@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var labelCommand = &Command{
+	Name:    "label",
+	Args:    "<ticket-id> [label...]",
+	MinArgs: 1,
+	MaxArgs: -1,
+	Summary: "Add labels to a worktree, or show its labels",
+	Description: `Labels are single words used to group worktrees, e.g. with list --label. Without
+labels, shows the worktree's labels.`,
+	Examples: []string{"label PROJ-123 blocked review", "label PROJ-123 --remove blocked"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		remove := fs.Bool("remove", false, "Remove the labels instead of adding them")
+		shorthand(fs, "r", "remove")
+		return func(ctx *Context, args []string) error {
+			return runLabel(ctx, args, *remove)
+		}
+	},
+}
+
+// runLabel adds labels to a worktree, removes them, or, with no labels, shows them.
+func runLabel(ctx *Context, args []string, removing bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	labels := args[1:]
+	if len(labels) == 0 {
+		wt, err := m.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if len(wt.Labels) == 0 {
+			fmt.Fprintf(ctx.Stdout, "No labels for %s.\n", wt.Ticket)
+		} else {
+			fmt.Fprintln(ctx.Stdout, strings.Join(wt.Labels, ", "))
+		}
+		return nil
+	}
+
+	var wt gittree.Worktree
+	if removing {
+		wt, err = m.Label(args[0], nil, labels)
+	} else {
+		wt, err = m.Label(args[0], labels, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(wt.Labels) == 0 {
+		fmt.Fprintf(ctx.Stdout, "%s has no labels.\n", wt.Ticket)
+	} else {
+		fmt.Fprintf(ctx.Stdout, "%s labels: %s\n", wt.Ticket, strings.Join(wt.Labels, ", "))
+	}
+	return nil
+}
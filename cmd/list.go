@@ -1,89 +1,110 @@
 package cmd
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
-	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/git"
-	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// List displays all worktrees for the repository.
-func List(args []string) error {
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
-	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
-	}
+var listCommand = &Command{
+	Name:    "list",
+	Aliases: []string{"ls"},
+	Summary: "List all worktrees",
+	Description: `Shows each worktree's branch, state, commits ahead of and behind the mainline, last
+activity, labels and path. Worktrees whose directory is gone are shown as STALE. With
+--workspace, the workspace's tickets are listed with a row per repository, and with
+--global, the worktrees of every registered repository are listed from any directory.`,
+	Examples: []string{"list", "list --label blocked", "list --sort active", "list --workspace platform", "list --global"},
+	JSON:     true,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		var labels []string
+		fs.Func("label", "Only show worktrees with `label` (can be repeated)", func(label string) error {
+			labels = append(labels, label)
+			return nil
+		})
+		sortBy := fs.String("sort", "ticket", "Sort by `order`: ticket, active or created")
+		workspace := fs.String("workspace", "", "List the tickets of `workspace`, a row per repository")
+		global := fs.Bool("global", false, "List the worktrees of every registered repository")
+		return func(ctx *Context, args []string) error {
+			if *global {
+				return runGlobalList(ctx, labels, *sortBy)
+			}
+			if *workspace != "" {
+				return runWorkspaceList(ctx, *workspace, labels, *sortBy)
+			}
+			return runList(ctx, labels, *sortBy)
+		}
+	},
+}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+// runList displays the worktrees that have every one of the labels.
+func runList(ctx *Context, labels []string, sortBy string) error {
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
 	}
 
-	// Get git worktrees
-	repo := git.NewRepo(repoPath)
-	worktrees, err := repo.ListWorktrees()
+	results, err := m.List(gittree.ListOptions{Labels: labels, Sort: sortBy})
 	if err != nil {
-		return fmt.Errorf("failed to list worktrees: %w", err)
+		return err
 	}
 
-	// Build map of existing worktree paths
-	existingPaths := make(map[string]git.WorktreeInfo)
-	for _, wt := range worktrees {
-		absPath, err := filepath.Abs(wt.Path)
-		if err == nil {
-			existingPaths[absPath] = wt
-		}
+	if ctx.JSON {
+		return writeJSON(ctx, results)
 	}
 
-	if len(meta.Worktrees) == 0 {
-		fmt.Println("No worktrees found.")
+	if len(results) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No worktrees found.")
 		return nil
 	}
 
 	// Display worktrees
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "TICKET\tBRANCH\tSTATUS\tPATH")
-	fmt.Fprintln(w, "------\t------\t------\t----")
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKET\tBRANCH\tSTATUS\tLAST ACTIVE\tLABELS\tPATH")
+	fmt.Fprintln(w, "------\t------\t------\t-----------\t------\t----")
 
-	for ticketID, entry := range meta.Worktrees {
-		status := "?"
-		absPath, err := filepath.Abs(entry.Path)
-		if err == nil {
-			if wtInfo, exists := existingPaths[absPath]; exists {
-				// Check if worktree is clean
-				wtRepo := git.NewRepo(wtInfo.Path)
-				clean, err := wtRepo.IsClean()
-				if err == nil {
-					if clean {
-						status = "clean"
-					} else {
-						status = "dirty"
-					}
-
-					// Check ahead/behind of mainline
-					if meta.Mainline != "" {
-						ahead, behind, err := wtRepo.GetCommitCount(wtInfo.Branch, fmt.Sprintf("origin/%s", meta.Mainline))
-						if err == nil {
-							if ahead > 0 || behind > 0 {
-								status = fmt.Sprintf("%s (↑%d ↓%d)", status, ahead, behind)
-							}
-						}
-					}
-				}
-			} else {
-				status = "STALE"
-			}
-		}
+	now := ctx.Now()
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ticketID, entry.Branch, status, entry.Path)
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Ticket, result.Branch, ctx.colorize(stateColors[result.State], listState(result)), formatAge(result.LastActive, now), strings.Join(result.Labels, ","), result.Path)
 	}
 
 	w.Flush()
 	return nil
 }
+
+// listState describes a worktree's state in the list, with how far it is ahead of and
+// behind the mainline.
+func listState(result gittree.Status) string {
+	switch result.State {
+	case gittree.StateUnknown:
+		return "?"
+	case gittree.StateStale:
+		return "STALE"
+	}
+	if result.Ahead > 0 || result.Behind > 0 {
+		return fmt.Sprintf("%s (↑%d ↓%d)", result.State, result.Ahead, result.Behind)
+	}
+	return result.State
+}
+
+// stateColors are the ANSI colors of worktree states in the list.
+var stateColors = map[string]int{
+	gittree.StateClean:    32, // green
+	gittree.StateDirty:    33, // yellow
+	gittree.StateStale:    31, // red
+	gittree.StateUnknown:  31,
+	gittree.StateArchived: 90, // gray
+	gittree.StateMissing:  31,
+}
+
+// writeJSON writes v to the command output as indented JSON.
+func writeJSON(ctx *Context, v any) error {
+	enc := json.NewEncoder(ctx.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var logCommand = &Command{
+	Name:        "log",
+	Args:        "[count]",
+	MaxArgs:     1,
+	Summary:     "Show the operation journal",
+	Description: `Shows the operations recorded in the journal, most recent first, optionally only the last count.`,
+	Examples:    []string{"log 10"},
+	Setup:       func(*flag.FlagSet) RunFunc { return runLog },
+}
+
+// runLog displays the operation journal, most recent first.
+func runLog(ctx *Context, args []string) error {
+	limit := 0
+	if len(args) >= 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return invalidArgs("count must be a positive number")
+		}
+		limit = n
+	}
+
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	ops, err := m.Journal()
+	if err != nil {
+		return err
+	}
+
+	if len(ops) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No operations recorded.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tCOMMAND\tTICKETS\tNOTE")
+	fmt.Fprintln(w, "--\t----\t-------\t-------\t----")
+
+	shown := 0
+	for i := len(ops) - 1; i >= 0; i-- {
+		if limit > 0 && shown >= limit {
+			break
+		}
+		op := ops[i]
+
+		ticketStr := strings.Join(op.Tickets, ",")
+		if ticketStr == "" {
+			ticketStr = "-"
+		}
+
+		note := ""
+		if op.Reverts != 0 {
+			note = fmt.Sprintf("reverts #%d", op.Reverts)
+		} else if op.Undone {
+			note = "undone"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", op.ID, op.Time.Format("2006-01-02 15:04:05"), op.Command, ticketStr, note)
+		shown++
+	}
+
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Main runs git-tree with command-line arguments (without the program name) in ctx
+// and returns the exit code.
+func Main(ctx *Context, args []string) int {
+	// Global options apply to this run only
+	c := *ctx
+	ctx = &c
+
+	var opts globalOptions
+	fs := newFlagSet("git tree")
+	defineGlobalFlags(fs, ctx, &opts, true, true)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			writeUsage(ctx.Stdout, ctx.plugins())
+			return 0
+		}
+		return fail(ctx, config.NewError(ErrUsage, "", "%v\nRun 'git tree help' for the list of options.", flagError(err)))
+	}
+	args = fs.Args()
+	if len(args) < 1 {
+		writeUsage(ctx.Stdout, ctx.plugins())
+		return 1
+	}
+
+	// Like git, plugins on PATH come after built-in commands but before aliases
+	if path := ctx.findPlugin(args[0]); path != "" {
+		code, err := runPlugin(ctx, path, args[1:], opts.verbose)
+		if err != nil {
+			return fail(ctx, err)
+		}
+		return code
+	}
+
+	command, args, err := lookupCommand(ctx, args[0], args[1:])
+	if err != nil {
+		return fail(ctx, err)
+	}
+
+	// Global options can also follow the command
+	fs, run := command.flags()
+	defineGlobalFlags(fs, ctx, &opts, false, command.JSON)
+	args, err = parseArgs(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		command.writeHelp(ctx.Stdout)
+		return 0
+	}
+	if err != nil {
+		return fail(ctx, command.usageError(err.Error()))
+	}
+	if ctx.JSON && !command.JSON {
+		return fail(ctx, config.NewError(ErrUsage, "", "git tree %s does not support --json", command.Name))
+	}
+	if err := command.checkArgs(args); err != nil {
+		return fail(ctx, command.usageError(""))
+	}
+
+	trace, err := git.OpenTrace(ctx.Getenv("GIT_TREE_TRACE"), ctx.Stderr)
+	if err != nil {
+		return fail(ctx, err)
+	}
+	if trace != nil {
+		defer trace.Close()
+		ctx.trace = trace
+	}
+	if opts.verbose {
+		ctx.trace = ctx.Stderr
+	}
+
+	if err := run(ctx, args); err != nil {
+		var invalid *invalidArgsError
+		if errors.As(err, &invalid) {
+			err = command.usageError(invalid.reason)
+		}
+		return fail(ctx, err)
+	}
+	return 0
+}
+
+// lookupCommand finds the command to run, expanding aliases defined in git config, and
+// returns it with its arguments.
+func lookupCommand(ctx *Context, name string, args []string) (*Command, []string, error) {
+	if command := findCommand(name); command != nil {
+		return command, args, nil
+	}
+
+	// Like git, aliases can't replace built-in commands. Outside a repository only the
+	// global aliases apply, so an error reading them isn't fatal.
+	aliases, _ := config.LoadAliases(ctx.Dir)
+	if expansion, ok := aliases[name]; ok {
+		fields := strings.Fields(expansion)
+		if len(fields) == 0 {
+			return nil, nil, config.NewError(ErrUsage, "", "alias %s is empty", name)
+		}
+		command := findCommand(fields[0])
+		if command == nil {
+			return nil, nil, config.NewError(ErrUsage, "", "alias %s expands to unknown command %s", name, fields[0])
+		}
+		return command, append(fields[1:], args...), nil
+	}
+
+	plugins := ctx.plugins()
+	names := make([]string, 0, len(commands)+len(plugins)+len(aliases))
+	for _, c := range commands {
+		names = append(names, c.Name)
+		names = append(names, c.Aliases...)
+	}
+	names = append(names, plugins...)
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+
+	msg := fmt.Sprintf("'%s' is not a git-tree command. See 'git tree help'.", name)
+	switch suggestions := suggest(name, names); len(suggestions) {
+	case 0:
+	case 1:
+		msg += "\n\nThe most similar command is\n  " + suggestions[0]
+	default:
+		msg += "\n\nThe most similar commands are\n  " + strings.Join(suggestions, "\n  ")
+	}
+	return nil, nil, &config.Error{Kind: ErrUsage, Message: msg}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var migrateStorageCommand = &Command{
+	Name:    "migrate-storage",
+	Args:    "<backend>",
+	MinArgs: 1,
+	MaxArgs: 1,
+	Summary: "Move the metadata to another storage backend",
+	Description: `Copies the worktree metadata from the configured storage backend to another one,
+checks the copy, selects the new backend in tree.storage and removes the old copy.
+The backends are json (worktree-metadata.json, the default), gitconfig (tree-ticket
+sections of the repository's git config) and kv (worktree-metadata.kv, one line per
+ticket). Metadata already in the new backend is only replaced when confirmed.`,
+	Examples: []string{"migrate-storage gitconfig", "migrate-storage json --from kv --keep"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		from := fs.String("from", "", "Move the metadata from `backend` instead of the configured one")
+		keep := fs.Bool("keep", false, "Keep the metadata in the old backend")
+		return func(ctx *Context, args []string) error {
+			return runMigrateStorage(ctx, args[0], *from, *keep)
+		}
+	},
+}
+
+// runMigrateStorage moves the metadata from one storage backend to another and selects
+// the new one.
+func runMigrateStorage(ctx *Context, to, from string, keep bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{to, from} {
+		if name != "" && !slices.Contains(gittree.StorageBackends, name) {
+			return invalidArgs(fmt.Sprintf("unknown storage backend %q (choose from %s)", name, strings.Join(gittree.StorageBackends, ", ")))
+		}
+	}
+	if from == "" {
+		if from, err = m.Storage(); err != nil {
+			return err
+		}
+	}
+	if to == from {
+		return invalidArgs(fmt.Sprintf("metadata is already stored in %s", from))
+	}
+
+	_, err = m.MigrateStorage(to, gittree.MigrateOptions{
+		From: from,
+		Keep: keep,
+		Replace: func(path string, worktrees int) error {
+			ok, err := ctx.Confirm(fmt.Sprintf("%s already holds %d worktree(s). Replace them?", path, worktrees), false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("migration %w", ErrCancelled)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nMetadata moved from %s to %s.\n", from, to)
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var noteCommand = &Command{
+	Name:    "note",
+	Args:    "<ticket-id> [text...]",
+	MinArgs: 1,
+	MaxArgs: -1,
+	Summary: "Add a note to a worktree, or show its notes",
+	Description: `Adds a timestamped note, or with --description sets the one-line description shown
+by status. Without text, shows the description and notes. Put -- before text that
+starts with a dash.`,
+	Examples: []string{"note PROJ-123 waiting on API review", "note PROJ-123 --description OAuth login for the admin app"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		description := fs.Bool("description", false, "Set the description to the text instead of adding a note")
+		shorthand(fs, "d", "description")
+		deleteNote := fs.Int("delete", 0, "Delete note number `n`")
+		return func(ctx *Context, args []string) error {
+			return runNote(ctx, args, *description, *deleteNote)
+		}
+	},
+}
+
+// runNote adds a timestamped note to a worktree, sets its description, deletes a note,
+// or, with no text, shows the description and notes.
+func runNote(ctx *Context, args []string, description bool, deleteNote int) error {
+	if deleteNote != 0 && (description || len(args) > 1) {
+		return invalidArgs("--delete takes no text")
+	}
+
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	rest := args[1:]
+	text := strings.Join(rest, " ")
+	switch {
+	case len(rest) == 0 && !description && deleteNote == 0:
+		wt, err := m.Get(args[0])
+		if err != nil {
+			return err
+		}
+		showNotes(ctx, wt)
+	case description:
+		wt, err := m.Describe(args[0], text)
+		if err != nil {
+			return err
+		}
+		if wt.Description == "" {
+			fmt.Fprintf(ctx.Stdout, "Cleared description of %s.\n", wt.Ticket)
+		} else {
+			fmt.Fprintf(ctx.Stdout, "Set description of %s.\n", wt.Ticket)
+		}
+	case deleteNote != 0:
+		wt, err := m.DeleteNote(args[0], deleteNote)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Stdout, "Deleted note %d from %s.\n", deleteNote, wt.Ticket)
+	default:
+		wt, err := m.AddNote(args[0], text)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Stdout, "Added note %d to %s.\n", len(wt.Notes), wt.Ticket)
+	}
+	return nil
+}
+
+// showNotes prints a worktree's description and numbered notes.
+func showNotes(ctx *Context, wt gittree.Worktree) {
+	if wt.Description == "" && len(wt.Notes) == 0 {
+		fmt.Fprintf(ctx.Stdout, "No notes for %s.\n", wt.Ticket)
+		return
+	}
+	if wt.Description != "" {
+		fmt.Fprintf(ctx.Stdout, "Description: %s\n", wt.Description)
+	}
+	if len(wt.Notes) > 0 {
+		if wt.Description != "" {
+			fmt.Fprintln(ctx.Stdout)
+		}
+		fmt.Fprintln(ctx.Stdout, "Notes:")
+		for i, note := range wt.Notes {
+			fmt.Fprintf(ctx.Stdout, "  %d. [%s] %s\n", i+1, note.Time.Format("2006-01-02 15:04"), note.Text)
+		}
+	}
+}
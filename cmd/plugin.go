@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// pluginPrefix is the prefix of plugin executables: git tree <name> runs git-tree-<name>
+// from PATH when <name> isn't a built-in command.
+const pluginPrefix = "git-tree-"
+
+// findPlugin returns the path of the plugin for a command, or "" if there is none on PATH.
+func (c *Context) findPlugin(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) || findCommand(name) != nil {
+		return ""
+	}
+	for _, dir := range filepath.SplitList(c.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, pluginPrefix+name)
+		if isExecutable(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// plugins returns the names of the plugins on PATH, sorted. Plugins named after a
+// built-in command are left out, since they can't be run.
+func (c *Context) plugins() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(c.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if dir == "" || err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), pluginPrefix)
+			if !ok || name == "" || seen[name] || findCommand(name) != nil || !isExecutable(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isExecutable reports whether path is a regular file, or a link to one, that can be executed.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
+
+// runPlugin runs a plugin with args and returns its exit code. The plugin inherits the
+// standard streams and environment, plus the pluginVars describing the repository and
+// the selected worktree. With verbose, git-tree commands it runs trace git commands too.
+func runPlugin(ctx *Context, path string, args []string, verbose bool) (int, error) {
+	c := exec.Command(path, args...)
+	c.Dir = ctx.Dir
+	c.Env = append(append([]string{}, ctx.Env...), pluginEnv(ctx, args)...)
+	if verbose {
+		c.Env = append(c.Env, "GIT_TREE_TRACE=1")
+	}
+	c.Stdin = ctx.Stdin
+	c.Stdout = ctx.Stdout
+	c.Stderr = ctx.Stderr
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("failed to run %s: %w", filepath.Base(path), err)
+	}
+	return 0, nil
+}
+
+// pluginVars are the environment variables that tell a plugin about the repository:
+//
+//	GIT_TREE_REPO      the primary repository
+//	GIT_TREE_METADATA  the metadata file
+//	GIT_TREE_MAINLINE  the mainline branch
+//	GIT_TREE_TICKET    the selected ticket
+//	GIT_TREE_WORKTREE  the selected ticket's worktree
+//	GIT_TREE_BRANCH    the selected ticket's branch
+//	GIT_TREE_ENTRY     the selected ticket's metadata entry, as JSON
+var pluginVars = []string{
+	"GIT_TREE_REPO",
+	"GIT_TREE_METADATA",
+	"GIT_TREE_MAINLINE",
+	"GIT_TREE_TICKET",
+	"GIT_TREE_WORKTREE",
+	"GIT_TREE_BRANCH",
+	"GIT_TREE_ENTRY",
+}
+
+// pluginEnv returns the pluginVars for a plugin run with args. Variables that don't apply
+// are set to "", so values inherited from a calling plugin never leak through.
+func pluginEnv(ctx *Context, args []string) []string {
+	values := pluginValues(ctx, args)
+	env := make([]string, 0, len(pluginVars))
+	for _, key := range pluginVars {
+		env = append(env, key+"="+values[key])
+	}
+	return env
+}
+
+// pluginValues returns the values of the pluginVars that apply. The selected ticket is the
+// first argument if it names a worktree, or else the worktree containing the working directory.
+func pluginValues(ctx *Context, args []string) map[string]string {
+	values := make(map[string]string)
+	repoPath, err := ctx.primaryRepoPath()
+	if err != nil {
+		return values
+	}
+	values["GIT_TREE_REPO"] = repoPath
+	if path, err := config.MetadataPath(repoPath); err == nil {
+		values["GIT_TREE_METADATA"] = path
+	}
+
+	m, err := gittree.Open(repoPath, gittree.Options{Getenv: ctx.Getenv, Now: ctx.Now, Trace: ctx.trace})
+	if err != nil {
+		return values
+	}
+	values["GIT_TREE_MAINLINE"] = m.Mainline()
+
+	var wt gittree.Worktree
+	found := false
+	if len(args) > 0 {
+		wt, err = m.Get(args[0])
+		found = err == nil
+	}
+	if !found {
+		if loc, err := util.Locate(ctx.Dir); err == nil && loc.TopLevel != "" {
+			wt, found = m.WorktreeAt(loc.TopLevel)
+		}
+	}
+	if !found {
+		return values
+	}
+
+	values["GIT_TREE_TICKET"] = wt.Ticket
+	values["GIT_TREE_WORKTREE"] = wt.Path
+	values["GIT_TREE_BRANCH"] = wt.Branch
+	if data, err := json.Marshal(wt); err == nil {
+		values["GIT_TREE_ENTRY"] = string(data)
+	}
+	return values
+}
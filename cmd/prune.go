@@ -1,80 +1,46 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
-	"path/filepath"
-
-	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/git"
-	"github.com/sduncan/git-tree/internal/util"
 )
 
-// Prune removes stale metadata entries and orphaned worktrees.
-func Prune(args []string) error {
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
-	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
-	}
+var pruneCommand = &Command{
+	Name:    "prune",
+	Summary: "Clean up stale metadata and worktrees",
+	Description: `Removes metadata for worktrees whose directory is gone, and prunes git's records of
+missing worktrees.`,
+	Examples: []string{"prune"},
+	Setup:    func(*flag.FlagSet) RunFunc { return runPrune },
+}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+// runPrune removes stale metadata entries and orphaned worktrees.
+func runPrune(ctx *Context, args []string) error {
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
 	}
 
-	// Get git worktrees
-	repo := git.NewRepo(repoPath)
-	worktrees, err := repo.ListWorktrees()
+	stale, err := m.Prune()
 	if err != nil {
-		return fmt.Errorf("failed to list worktrees: %w", err)
+		return err
 	}
-
-	// Build map of existing worktree paths
-	existingPaths := make(map[string]bool)
-	for _, wt := range worktrees {
-		absPath, err := filepath.Abs(wt.Path)
-		if err == nil {
-			existingPaths[absPath] = true
-		}
-	}
-
-	// Find stale metadata entries
-	var staleTickets []string
-	for ticketID, entry := range meta.Worktrees {
-		absPath, err := filepath.Abs(entry.Path)
-		if err != nil {
-			staleTickets = append(staleTickets, ticketID)
-			continue
-		}
-
-		if !existingPaths[absPath] {
-			staleTickets = append(staleTickets, ticketID)
-		}
-	}
-
-	if len(staleTickets) == 0 {
-		fmt.Println("No stale metadata entries found.")
+	if len(stale) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No stale metadata entries found.")
 	} else {
-		fmt.Printf("Found %d stale metadata entries:\n", len(staleTickets))
-		for _, ticketID := range staleTickets {
-			entry := meta.Worktrees[ticketID]
-			fmt.Printf("  - %s (path: %s)\n", ticketID, entry.Path)
-			meta.RemoveWorktree(ticketID)
-		}
-
-		if err := config.Save(repoPath, meta); err != nil {
-			return fmt.Errorf("failed to save metadata: %w", err)
+		fmt.Fprintf(ctx.Stdout, "Found %d stale metadata entries:\n", len(stale))
+		for _, wt := range stale {
+			fmt.Fprintf(ctx.Stdout, "  - %s (path: %s)\n", wt.Ticket, wt.Path)
 		}
-		fmt.Println("\nStale metadata entries removed.")
+		fmt.Fprintln(ctx.Stdout, "\nStale metadata entries removed.")
 	}
 
 	// Prune git worktrees
-	fmt.Println("\nPruning git worktrees...")
-	if err := repo.PruneWorktrees(); err != nil {
-		return fmt.Errorf("failed to prune worktrees: %w", err)
+	fmt.Fprintln(ctx.Stdout, "\nPruning git worktrees...")
+	if err := m.PruneWorktrees(); err != nil {
+		return err
 	}
 
-	fmt.Println("Git worktree pruning complete.")
+	fmt.Fprintln(ctx.Stdout, "Git worktree pruning complete.")
 	return nil
 }
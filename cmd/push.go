@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+var pushCommand = &Command{
+	Name:    "push",
+	Args:    "<ticket-id>",
+	MinArgs: 1,
+	MaxArgs: 1,
+	Summary: "Push a worktree's branch and set its upstream",
+	Description: `Pushes the branch to the remote and sets it as the branch's upstream, then runs the
+push actions if an issue tracker is configured. With --workspace, the ticket's branch is
+pushed in every repository of the workspace.`,
+	Examples: []string{"push PROJ-123", "push PROJ-123 --workspace platform"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		noTracker := noTrackerFlag(fs)
+		workspace := workspaceFlag(fs)
+		return func(ctx *Context, args []string) error {
+			if *workspace != "" {
+				return runWorkspacePush(ctx, *workspace, args, *noTracker)
+			}
+			return runPush(ctx, args, *noTracker)
+		}
+	},
+}
+
+// runPush pushes a worktree's branch to the remote and sets it as the branch's upstream.
+func runPush(ctx *Context, args []string, noTracker bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry, err := m.Push(args[0])
+	if err != nil {
+		return err
+	}
+
+	if !noTracker {
+		settings, err := config.LoadSettings(m.RepoPath())
+		if err != nil {
+			return err
+		}
+		runLifecycle(ctx, settings, "push", settings.OnPush, entry.Ticket, entry.Branch)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nBranch %s pushed to %s.\n", entry.Branch, m.Remote())
+	return nil
+}
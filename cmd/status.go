@@ -1,129 +1,323 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
-	"github.com/sduncan/git-tree/internal/config"
 	"github.com/sduncan/git-tree/internal/git"
-	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// Status displays detailed status for worktrees.
-func Status(args []string) error {
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
-	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
-	}
+var statusCommand = &Command{
+	Name:    "status",
+	Args:    "[ticket-id]",
+	MaxArgs: 1,
+	Summary: "Show status of worktrees",
+	Description: `Without a ticket, summarizes every worktree. With one, shows the worktree's changed
+files by category, its commits and diffstat against the mainline, its upstream, stashes,
+and the files predicted to conflict when it is updated. With --workspace, the ticket's
+worktrees in every repository of the workspace are shown together.`,
+	Examples: []string{"status", "status PROJ-123", "status PROJ-123 --workspace platform"},
+	JSON:     true,
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		workspace := fs.String("workspace", "", "Show the tickets of `workspace` across its repositories")
+		return func(ctx *Context, args []string) error {
+			if *workspace != "" {
+				return runWorkspaceStatus(ctx, *workspace, args)
+			}
+			return runStatus(ctx, args)
+		}
+	},
+}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+// runStatus displays detailed status for worktrees.
+func runStatus(ctx *Context, args []string) error {
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
 	}
 
 	// If specific ticket provided, show detailed status
 	if len(args) >= 1 {
-		ticketID := args[0]
-		entry, ok := meta.GetWorktree(ticketID)
-		if !ok {
-			return fmt.Errorf("worktree for %s not found", ticketID)
+		result, err := m.Status(args[0])
+		if err != nil {
+			return err
 		}
 
-		return showDetailedStatus(entry, meta.Mainline)
+		if ctx.JSON {
+			return writeJSON(ctx, result)
+		}
+		return showDetailedStatus(ctx, result, m.MainlineRef())
+	}
+
+	results := []gittree.Status{}
+	for _, ticketID := range m.Tickets() {
+		result, err := m.Status(ticketID)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	if ctx.JSON {
+		return writeJSON(ctx, results)
 	}
 
 	// Otherwise show summary for all worktrees
-	if len(meta.Worktrees) == 0 {
-		fmt.Println("No worktrees found.")
+	if len(results) == 0 {
+		fmt.Fprintln(ctx.Stdout, "No worktrees found.")
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "TICKET\tBRANCH\tSTATUS\tCHANGES\tAHEAD/BEHIND")
 	fmt.Fprintln(w, "------\t------\t------\t-------\t------------")
 
-	for ticketID, entry := range meta.Worktrees {
-		wtRepo := git.NewRepo(entry.Path)
-
-		// Get status
-		status, err := wtRepo.GetStatus()
-		statusStr := "?"
-		changesStr := "?"
-		if err == nil {
-			if status == "" {
-				statusStr = "clean"
-				changesStr = "0"
-			} else {
-				statusStr = "dirty"
-				// Count number of changed files
-				lines := 0
-				for _, line := range status {
-					if line == '\n' {
-						lines++
-					}
-				}
-				changesStr = fmt.Sprintf("%d", lines)
-			}
+	for _, result := range results {
+		statusStr, changesStr := "?", "?"
+		if result.State == gittree.StateArchived {
+			statusStr, changesStr = gittree.StateArchived, "-"
+		} else if result.State != gittree.StateUnknown {
+			statusStr = result.State
+			changesStr = fmt.Sprintf("%d", result.Changes)
 		}
 
-		// Get ahead/behind
 		aheadBehindStr := "?"
-		if meta.Mainline != "" {
-			branch, err := wtRepo.GetCurrentBranch()
-			if err == nil {
-				ahead, behind, err := wtRepo.GetCommitCount(branch, fmt.Sprintf("origin/%s", meta.Mainline))
-				if err == nil {
-					aheadBehindStr = fmt.Sprintf("↑%d ↓%d", ahead, behind)
-				}
-			}
+		if result.Compared {
+			aheadBehindStr = fmt.Sprintf("↑%d ↓%d", result.Ahead, result.Behind)
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ticketID, entry.Branch, statusStr, changesStr, aheadBehindStr)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Ticket, result.Branch, statusStr, changesStr, aheadBehindStr)
 	}
 
 	w.Flush()
 	return nil
 }
 
-func showDetailedStatus(entry config.WorktreeEntry, mainline string) error {
-	fmt.Printf("Worktree: %s\n", entry.Ticket)
-	fmt.Printf("Path:     %s\n", entry.Path)
-	fmt.Printf("Branch:   %s\n", entry.Branch)
-	fmt.Printf("Created:  %s\n\n", entry.Created.Format("2006-01-02 15:04:05"))
-
-	wtRepo := git.NewRepo(entry.Path)
+// showDetailedStatus shows a worktree's details and status. Without a mainlineRef, it isn't
+// compared with the mainline.
+func showDetailedStatus(ctx *Context, result gittree.Status, mainlineRef string) error {
+	entry := result.Worktree
+	fmt.Fprintf(ctx.Stdout, "Worktree: %s\n", entry.Ticket)
+	fmt.Fprintf(ctx.Stdout, "Path:     %s\n", entry.Path)
+	fmt.Fprintf(ctx.Stdout, "Branch:   %s\n", entry.Branch)
+	fmt.Fprintf(ctx.Stdout, "Created:  %s\n", entry.Created.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(ctx.Stdout, "Active:   %s\n", formatAge(result.LastActive, ctx.Now()))
+	if info := entry.TicketInfo; info != nil {
+		fmt.Fprintf(ctx.Stdout, "Title:    %s\n", info.Title)
+		if info.Status != "" {
+			fmt.Fprintf(ctx.Stdout, "Ticket:   %s\n", info.Status)
+		}
+		if info.Assignee != "" {
+			fmt.Fprintf(ctx.Stdout, "Assignee: %s\n", info.Assignee)
+		}
+		if info.URL != "" {
+			fmt.Fprintf(ctx.Stdout, "URL:      %s\n", info.URL)
+		}
+	}
+	if entry.Description != "" {
+		fmt.Fprintf(ctx.Stdout, "About:    %s\n", entry.Description)
+	}
+	if len(entry.Labels) > 0 {
+		fmt.Fprintf(ctx.Stdout, "Labels:   %s\n", strings.Join(entry.Labels, ", "))
+	}
+	if len(entry.Notes) > 0 {
+		fmt.Fprintln(ctx.Stdout, "\nNotes:")
+		for i, note := range entry.Notes {
+			fmt.Fprintf(ctx.Stdout, "  %d. [%s] %s\n", i+1, note.Time.Format("2006-01-02 15:04"), note.Text)
+		}
+	}
+	fmt.Fprintln(ctx.Stdout)
 
-	// Get current branch
-	branch, err := wtRepo.GetCurrentBranch()
-	if err != nil {
-		return fmt.Errorf("failed to get current branch: %w", err)
+	if entry.IsArchived() {
+		fmt.Fprintf(ctx.Stdout, "Status: archived on %s\n", entry.Archived.Format("2006-01-02 15:04:05"))
+		if entry.Stash != "" {
+			fmt.Fprintf(ctx.Stdout, "Uncommitted changes are saved in %s\n", gittree.ArchiveRef(entry.Ticket))
+		}
+		return nil
 	}
+	if entry.Stash != "" {
+		fmt.Fprintf(ctx.Stdout, "Changes saved by the last archive did not apply and are still in %s\n\n", gittree.ArchiveRef(entry.Ticket))
+	}
+
+	wtRepo := ctx.repo(entry.Path)
 
 	// Get status
-	status, err := wtRepo.GetStatus()
+	status, err := wtRepo.Status()
 	if err != nil {
 		return fmt.Errorf("failed to get status: %w", err)
 	}
 
-	if status == "" {
-		fmt.Println("Status: clean (no changes)")
+	branch := status.Branch.Head
+	if branch == "" {
+		branch = "HEAD"
+	}
+
+	if status.Clean() {
+		fmt.Fprintln(ctx.Stdout, "Status: clean (no changes)")
 	} else {
-		fmt.Println("Status: dirty")
-		fmt.Println("\nChanges:")
-		fmt.Print(status)
+		showChanges(ctx, status)
+	}
+
+	// Get upstream tracking state
+	upstream := status.Branch
+	switch {
+	case upstream.Upstream == "":
+		fmt.Fprintln(ctx.Stdout, "\nUpstream: not set")
+	case !upstream.HasCounts:
+		fmt.Fprintf(ctx.Stdout, "\nUpstream: %s (gone)\n", upstream.Upstream)
+	case upstream.Ahead == 0 && upstream.Behind == 0:
+		fmt.Fprintf(ctx.Stdout, "\nUpstream: %s (up to date)\n", upstream.Upstream)
+	default:
+		fmt.Fprintf(ctx.Stdout, "\nUpstream: %s (↑%d ↓%d)\n", upstream.Upstream, upstream.Ahead, upstream.Behind)
 	}
 
 	// Get ahead/behind
-	if mainline != "" {
-		ahead, behind, err := wtRepo.GetCommitCount(branch, fmt.Sprintf("origin/%s", mainline))
+	if mainlineRef != "" {
+		ahead, behind, err := wtRepo.GetCommitCount(branch, mainlineRef)
 		if err == nil {
-			fmt.Printf("\nCommits ahead of origin/%s: %d\n", mainline, ahead)
-			fmt.Printf("Commits behind origin/%s: %d\n", mainline, behind)
+			fmt.Fprintf(ctx.Stdout, "\nCommits ahead of %s: %d\n", mainlineRef, ahead)
+			fmt.Fprintf(ctx.Stdout, "Commits behind %s: %d\n", mainlineRef, behind)
+		}
+
+		showBranchChanges(ctx, wtRepo, branch, mainlineRef)
+	}
+
+	// The stash is shared by all worktrees, so only show entries made on this branch
+	if stashes, err := wtRepo.StashList(); err == nil {
+		var ours []git.StashEntry
+		for _, stash := range stashes {
+			if stash.Branch == branch {
+				ours = append(ours, stash)
+			}
+		}
+		if len(ours) > 0 {
+			fmt.Fprintln(ctx.Stdout, "\nStash entries:")
+			for _, stash := range ours {
+				fmt.Fprintf(ctx.Stdout, "  %s: %s\n", stash.Ref, stash.Message)
+			}
 		}
 	}
 
 	return nil
 }
+
+// showChanges prints a dirty working tree's files grouped by category.
+func showChanges(ctx *Context, status *git.Status) {
+	var counts []string
+	for _, c := range []struct {
+		name  string
+		count int
+	}{
+		{"conflicted", len(status.Conflicted())},
+		{"staged", len(status.Staged())},
+		{"unstaged", len(status.Unstaged())},
+		{"untracked", len(status.Untracked())},
+	} {
+		if c.count > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", c.count, c.name))
+		}
+	}
+	fmt.Fprintf(ctx.Stdout, "Status: dirty (%s)\n", strings.Join(counts, ", "))
+
+	groups := []struct {
+		title string
+		files []git.FileStatus
+		code  func(git.FileStatus) string
+	}{
+		{"Conflicts", status.Conflicted(), func(f git.FileStatus) string { return conflictNames[string([]byte{f.Index, f.Worktree})] }},
+		{"Staged", status.Staged(), func(f git.FileStatus) string { return changeNames[f.Index] }},
+		{"Unstaged", status.Unstaged(), func(f git.FileStatus) string { return changeNames[f.Worktree] }},
+		{"Untracked", status.Untracked(), nil},
+	}
+	for _, g := range groups {
+		if len(g.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(ctx.Stdout, "\n%s:\n", g.title)
+		for _, f := range g.files {
+			path := displayPath(f.Path)
+			if f.OrigPath != "" && g.title == "Staged" {
+				path = displayPath(f.OrigPath) + " -> " + path
+			}
+			if f.Submodule.IsSubmodule {
+				path += " (submodule)"
+			}
+			if g.code == nil {
+				fmt.Fprintf(ctx.Stdout, "  %s\n", path)
+			} else {
+				fmt.Fprintf(ctx.Stdout, "  %-14s %s\n", g.code(f)+":", path)
+			}
+		}
+	}
+}
+
+// changeNames describes the status codes of staged and unstaged changes.
+var changeNames = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+// conflictNames describes the status codes of unmerged files.
+var conflictNames = map[string]string{
+	"DD": "both deleted",
+	"AU": "added by us",
+	"UD": "deleted by them",
+	"UA": "added by them",
+	"DU": "deleted by us",
+	"AA": "both added",
+	"UU": "both modified",
+}
+
+// displayPath quotes a path if it contains characters that would garble the output.
+func displayPath(path string) string {
+	for _, r := range path {
+		if r < ' ' || r == 0x7f {
+			return strconv.Quote(path)
+		}
+	}
+	return path
+}
+
+// maxStatusCommits is the number of commits listed by the detailed status.
+const maxStatusCommits = 20
+
+// showBranchChanges prints the commits and diffstat of a branch against its merge-base
+// with the mainline, and the conflicts a merge with the mainline would have.
+func showBranchChanges(ctx *Context, wtRepo *git.Repo, branch, mainlineRef string) {
+	if commits, err := wtRepo.CommitsBetween(mainlineRef, branch); err == nil && len(commits) > 0 {
+		fmt.Fprintf(ctx.Stdout, "\nCommits not in %s:\n", mainlineRef)
+		for i, commit := range commits {
+			if i == maxStatusCommits {
+				fmt.Fprintf(ctx.Stdout, "  ... and %d more\n", len(commits)-maxStatusCommits)
+				break
+			}
+			fmt.Fprintf(ctx.Stdout, "  %s %s\n", commit.Hash, commit.Subject)
+		}
+	}
+
+	if stat, err := wtRepo.DiffStat(mainlineRef, branch); err == nil && stat != "" {
+		fmt.Fprintf(ctx.Stdout, "\nChanges since merge-base with %s:\n", mainlineRef)
+		fmt.Fprint(ctx.Stdout, stat)
+	}
+
+	conflicts, err := wtRepo.MergeConflicts(branch, mainlineRef)
+	switch {
+	case err != nil:
+		fmt.Fprintf(ctx.Stdout, "\nCould not predict conflicts with %s: %v\n", mainlineRef, err)
+	case len(conflicts) == 0:
+		fmt.Fprintf(ctx.Stdout, "\nNo conflicts predicted with %s.\n", mainlineRef)
+	default:
+		fmt.Fprintf(ctx.Stdout, "\nPredicted conflicts with %s:\n", mainlineRef)
+		for _, path := range conflicts {
+			fmt.Fprintf(ctx.Stdout, "  %s\n", path)
+		}
+	}
+}
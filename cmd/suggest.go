@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"slices"
+	"sort"
+)
+
+// suggest returns the candidates closest to a mistyped name, if any are within a couple
+// of edits of it or start with it.
+func suggest(name string, candidates []string) []string {
+	best := 3
+	var suggestions []string
+	for _, candidate := range candidates {
+		d := editDistance(name, candidate)
+		if d >= len(candidate) {
+			continue
+		}
+		// A prefix such as "sta" for "status" counts as a single edit
+		if len(name) >= 2 && len(candidate) > len(name) && candidate[:len(name)] == name {
+			d = min(d, 1)
+		}
+		switch {
+		case d < best:
+			best, suggestions = d, []string{candidate}
+		case d == best && !slices.Contains(suggestions, candidate):
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b: the number of
+// single-character insertions, deletions, substitutions and adjacent transpositions that
+// turn one into the other.
+func editDistance(a, b string) int {
+	// d[i][j] is the distance between a[:i] and b[:j]
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
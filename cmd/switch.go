@@ -1,39 +1,97 @@
 package cmd
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// Switch outputs the command to switch to a worktree.
-func Switch(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: git tree switch <ticket-id>")
-	}
+var switchCommand = &Command{
+	Name:    "switch",
+	Args:    "<ticket-id>",
+	MinArgs: 1,
+	MaxArgs: 1,
+	Summary: "Show command to switch to worktree",
+	Description: `Prints the cd command for the worktree, since a program can't change its shell's
+directory, and records the access. With --global, the ticket is looked up in every
+registered repository, so it works from any directory; the worktrees of a workspace
+ticket are switched to together, in their shared directory.`,
+	Examples: []string{"switch PROJ-123", "switch --global PROJ-123"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		global := fs.Bool("global", false, "Look the ticket up in every registered repository")
+		return func(ctx *Context, args []string) error {
+			if *global {
+				return runGlobalSwitch(ctx, args)
+			}
+			repoPath, err := ctx.primaryRepoPath()
+			if err != nil {
+				return fmt.Errorf("failed to find primary repository: %w", err)
+			}
+			return runSwitch(ctx, repoPath, args[0])
+		}
+	},
+}
 
-	ticketID := args[0]
+// runSwitch outputs the command to switch to a worktree of the repository at repoPath.
+func runSwitch(ctx *Context, repoPath, arg string) error {
+	m, err := openManagerAt(ctx, repoPath)
+	if err != nil {
+		return err
+	}
 
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
+	// Check if worktree exists
+	wt, err := m.Get(arg)
 	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
+		return err
+	}
+	if wt.IsArchived() {
+		return config.NewError(config.ErrArchived, wt.Ticket, "worktree for %s is archived, run: git tree restore %s", wt.Ticket, wt.Ticket)
+	}
+
+	if err := m.Touch(wt.Ticket); err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
 	}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+	fmt.Fprintf(ctx.Stdout, "To switch to worktree %s:\n", wt.Ticket)
+	fmt.Fprintf(ctx.Stdout, "  cd %s\n", wt.Path)
+	return nil
+}
+
+// runGlobalSwitch outputs the command to switch to a worktree registered in any repository.
+func runGlobalSwitch(ctx *Context, args []string) error {
+	matches, err := findGlobal(ctx, args[0])
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
+	}
+	if len(matches) == 1 {
+		return runSwitch(ctx, matches[0].RepoPath, args[0])
 	}
 
-	// Check if worktree exists
-	entry, ok := meta.GetWorktree(ticketID)
-	if !ok {
-		return fmt.Errorf("worktree for %s not found", ticketID)
+	dir := sharedTicketDir(matches)
+	if dir == "" {
+		var repos []string
+		for _, match := range matches {
+			repos = append(repos, match.RepoPath)
+		}
+		return config.NewError(ErrUsage, args[0], "%s has worktrees in several repositories, run git tree switch in one of them:\n  %s", args[0], strings.Join(repos, "\n  "))
+	}
+
+	for _, match := range matches {
+		m, err := openManagerAt(ctx, match.RepoPath)
+		if err == nil {
+			err = m.Touch(match.Entry.Ticket)
+		}
+		// The registry can list worktrees deleted since it was last updated
+		if err != nil && !errors.Is(err, gittree.ErrNotFound) {
+			fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+		}
 	}
 
-	fmt.Printf("To switch to worktree %s:\n", ticketID)
-	fmt.Printf("  cd %s\n", entry.Path)
+	fmt.Fprintf(ctx.Stdout, "To switch to %s in workspace %s:\n", matches[0].Entry.Ticket, matches[0].Entry.Workspace)
+	fmt.Fprintf(ctx.Stdout, "  cd %s\n", dir)
 	return nil
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+var syncCommand = &Command{
+	Name:    "sync",
+	Summary: "Share ticket worktrees with other machines through the remote",
+	Description: `Merges the tickets' shared metadata (branches, descriptions, labels, notes and ticket
+details, but not paths) with the copy in refs/git-tree/metadata on the remote, then
+pushes the result back. Tickets created on another machine get worktrees here, on their
+pushed branch if there is one, and tickets deleted elsewhere have their worktrees
+removed unless they have uncommitted changes or commits that aren't pushed or on the
+mainline. When a ticket was changed on both machines, labels and notes from both are
+kept and local changes win for the rest. Worktrees don't record a parent ticket or
+branch, so there is no parent to share.`,
+	Examples: []string{"sync", "sync --no-push"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		noPush := fs.Bool("no-push", false, "Merge the remote's tickets without pushing the result")
+		return func(ctx *Context, args []string) error {
+			return runSync(ctx, *noPush)
+		}
+	},
+}
+
+// runSync merges the shared ticket metadata with the remote's and applies it to the
+// worktrees.
+func runSync(ctx *Context, noPush bool) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.Sync(gittree.SyncOptions{NoPush: noPush})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.Stdout)
+	for _, entry := range result.Created {
+		fmt.Fprintf(ctx.Stdout, "  + %s (%s) at %s\n", entry.Ticket, entry.Branch, entry.Path)
+	}
+	for _, entry := range result.Updated {
+		fmt.Fprintf(ctx.Stdout, "  ~ %s\n", entry.Ticket)
+	}
+	for _, entry := range result.Removed {
+		fmt.Fprintf(ctx.Stdout, "  - %s (%s)\n", entry.Ticket, entry.Branch)
+	}
+	for _, ticketID := range result.Kept {
+		fmt.Fprintf(ctx.Stdout, "  ! %s kept\n", ticketID)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "Synced: %d created, %d updated, %d removed, %d kept.\n", len(result.Created), len(result.Updated), len(result.Removed), len(result.Kept))
+	if result.Pushed {
+		fmt.Fprintf(ctx.Stdout, "Shared metadata pushed to %s.\n", m.Remote())
+	}
+	return nil
+}
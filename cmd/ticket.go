@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// openManager opens the worktree manager of the repository containing the working
+// directory, with its progress messages written to the command output.
+func openManager(ctx *Context) (*gittree.Manager, error) {
+	return openManagerAt(ctx, ctx.Dir)
+}
+
+// openManagerAt opens the worktree manager of the repository containing dir, with its
+// progress messages written to the command output.
+func openManagerAt(ctx *Context, dir string) (*gittree.Manager, error) {
+	return gittree.Open(dir, gittree.Options{Log: ctx.Stdout, Getenv: ctx.Getenv, Now: ctx.Now, Trace: ctx.trace})
+}
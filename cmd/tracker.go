@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/tracker"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// trackerTokenEnv lists the environment variables checked for a tracker API token,
+// after GIT_TREE_TRACKER_TOKEN and the credentials file.
+var trackerTokenEnv = map[string]string{
+	tracker.TypeJira:   "JIRA_API_TOKEN",
+	tracker.TypeGitHub: "GITHUB_TOKEN",
+	tracker.TypeGitLab: "GITLAB_TOKEN",
+	tracker.TypeLinear: "LINEAR_API_KEY",
+}
+
+// noTrackerFlag defines the --no-tracker flag of commands that talk to the issue tracker.
+func noTrackerFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-tracker", false, "Skip issue tracker lookups and actions")
+}
+
+// newTracker returns the configured issue tracker, or nil if none is configured.
+func newTracker(ctx *Context, settings *config.Settings) (tracker.Tracker, error) {
+	if settings.Tracker == "" {
+		return nil, nil
+	}
+
+	creds, err := config.LoadCredentials(config.CredentialsPath(ctx.Getenv), settings.Tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	token := ctx.Getenv("GIT_TREE_TRACKER_TOKEN")
+	if token == "" {
+		token = creds.Token
+	}
+	if token == "" {
+		token = ctx.Getenv(trackerTokenEnv[settings.Tracker])
+	}
+
+	user := settings.TrackerUser
+	if user == "" {
+		user = creds.User
+	}
+
+	return tracker.New(tracker.Config{
+		Type:    settings.Tracker,
+		BaseURL: settings.TrackerURL,
+		Project: settings.TrackerProject,
+		User:    user,
+		Token:   token,
+	})
+}
+
+// lookupTicket fetches ticket details from the configured tracker.
+// A ticket the tracker doesn't know is an error, but an unreachable or misconfigured
+// tracker only produces a warning so commands keep working offline.
+func lookupTicket(ctx *Context, settings *config.Settings, ticketID string) (*gittree.TicketInfo, error) {
+	tr, err := newTracker(ctx, settings)
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: issue tracker is misconfigured: %v\n", err)
+		return nil, nil
+	}
+	if tr == nil {
+		return nil, nil
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), tracker.DefaultTimeout)
+	defer cancel()
+
+	fmt.Fprintf(ctx.Stdout, "Looking up %s in %s...\n", ticketID, tr.Name())
+	ticket, err := tr.GetTicket(reqCtx, ticketID)
+	if errors.Is(err, tracker.ErrNotFound) {
+		return nil, config.NewError(tracker.ErrNotFound, ticketID, "ticket %s does not exist in %s", ticketID, tr.Name())
+	}
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: could not look up %s, continuing without ticket details: %v\n", ticketID, err)
+		return nil, nil
+	}
+
+	return &gittree.TicketInfo{
+		Title:    ticket.Title,
+		Status:   ticket.Status,
+		Assignee: ticket.Assignee,
+		URL:      ticket.URL,
+		Fetched:  ctx.Now(),
+	}, nil
+}
+
+// defaultComment is the comment posted by a "comment" action without text.
+const defaultComment = "git-tree: {event} on branch {branch}"
+
+// runLifecycle performs the tracker actions configured for a worktree lifecycle event.
+// Failures only produce warnings, since the git side of the command has already succeeded.
+func runLifecycle(ctx *Context, settings *config.Settings, event string, actions []string, ticketID, branch string) {
+	if len(actions) == 0 {
+		return
+	}
+
+	tr, err := newTracker(ctx, settings)
+	if err != nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: issue tracker is misconfigured: %v\n", err)
+		return
+	}
+	if tr == nil {
+		fmt.Fprintf(ctx.Stdout, "Warning: tracker actions for %s are configured but no issue tracker is set\n", event)
+		return
+	}
+	updater, ok := tr.(tracker.Updater)
+	if !ok {
+		fmt.Fprintf(ctx.Stdout, "Warning: %s does not support updating tickets\n", tr.Name())
+		return
+	}
+
+	expand := strings.NewReplacer("{ticket}", ticketID, "{branch}", branch, "{event}", event)
+	for _, action := range actions {
+		kind, arg, err := config.ParseAction(action)
+		if err != nil {
+			fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+			continue
+		}
+
+		reqCtx, cancel := context.WithTimeout(context.Background(), tracker.DefaultTimeout)
+		switch kind {
+		case config.ActionTransition:
+			fmt.Fprintf(ctx.Stdout, "Moving %s to %s...\n", ticketID, arg)
+			err = updater.Transition(reqCtx, ticketID, arg)
+		case config.ActionAssign:
+			fmt.Fprintf(ctx.Stdout, "Assigning %s to you...\n", ticketID)
+			err = updater.AssignToMe(reqCtx, ticketID)
+		case config.ActionComment:
+			if arg == "" {
+				arg = defaultComment
+			}
+			fmt.Fprintf(ctx.Stdout, "Commenting on %s...\n", ticketID)
+			err = updater.Comment(reqCtx, ticketID, expand.Replace(arg))
+		}
+		cancel()
+
+		if err != nil {
+			fmt.Fprintf(ctx.Stdout, "Warning: %s on %s failed: %v\n", kind, ticketID, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+)
+
+var undoCommand = &Command{
+	Name:    "undo",
+	Summary: "Reverse the most recent operation",
+	Description: `Reverses the most recent operation in the log: created worktrees are removed, removed
+ones are recreated at their old branch tip, archives are restored, updates are reset to
+their pre-rebase commit, and metadata changes are reverted. Nothing is changed unless
+every change of the operation can be undone. Operations that changed no worktrees, such
+as push, can't be undone.`,
+	Examples: []string{"undo"},
+	Setup:    func(*flag.FlagSet) RunFunc { return runUndo },
+}
+
+// runUndo reverses the most recent reversible operation in the journal.
+func runUndo(ctx *Context, args []string) error {
+	m, err := openManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	op, err := m.Undo()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nOperation #%d undone.\n", op.ID)
+	return nil
+}
@@ -1,72 +1,78 @@
 package cmd
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"strings"
 
-	"github.com/sduncan/git-tree/internal/config"
-	"github.com/sduncan/git-tree/internal/git"
-	"github.com/sduncan/git-tree/internal/util"
+	"github.com/sduncan/git-tree/pkg/gittree"
 )
 
-// Update updates a worktree by rebasing it onto the latest mainline.
-func Update(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: git tree update <ticket-id>")
-	}
-
-	ticketID := args[0]
+var updateCommand = &Command{
+	Name:    "update",
+	Args:    "<ticket-id> | --all",
+	MaxArgs: 1,
+	Summary: "Update worktrees from mainline",
+	Description: `Fetches the mainline and rebases the worktree's branch onto it. The worktree must have
+no uncommitted changes. With --all, every worktree is updated except those predicted to
+conflict. With --workspace, the ticket's worktree in every repository of the workspace
+is updated.`,
+	Examples: []string{"update PROJ-123", "update --all", "update PROJ-123 --workspace platform"},
+	Setup: func(fs *flag.FlagSet) RunFunc {
+		all := fs.Bool("all", false, "Update every worktree")
+		includeConflicts := fs.Bool("include-conflicts", false, "With --all, also update worktrees predicted to conflict")
+		workspace := workspaceFlag(fs)
+		return func(ctx *Context, args []string) error {
+			if *workspace != "" {
+				if *all || len(args) == 0 {
+					return invalidArgs("--workspace needs a ticket and can't be used with --all")
+				}
+				return runWorkspaceUpdate(ctx, *workspace, args)
+			}
+			return runUpdate(ctx, args, *all, *includeConflicts)
+		}
+	},
+}
 
-	// Get primary repo path
-	repoPath, err := util.GetPrimaryRepoPath()
-	if err != nil {
-		return fmt.Errorf("failed to find primary repository: %w", err)
+// runUpdate updates a worktree by rebasing it onto the latest mainline. With all, every
+// worktree is updated, except those predicted to conflict unless includeConflicts is set.
+func runUpdate(ctx *Context, args []string, all, includeConflicts bool) error {
+	if all == (len(args) == 1) {
+		return invalidArgs("")
 	}
 
-	// Load metadata
-	meta, err := config.Load(repoPath)
+	m, err := openManager(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load metadata: %w", err)
+		return err
 	}
 
-	// Check if worktree exists
-	entry, ok := meta.GetWorktree(ticketID)
-	if !ok {
-		return fmt.Errorf("worktree for %s not found", ticketID)
+	if all {
+		result, err := m.UpdateAll(gittree.UpdateAllOptions{IncludeConflicts: includeConflicts})
+		// Rebases that failed are reported after the summary
+		if err != nil && !errors.Is(err, gittree.ErrRebase) {
+			return err
+		}
+		fmt.Fprintf(ctx.Stdout, "\nUpdated %d worktree(s) from %s.\n", len(result.Updated), m.MainlineRef())
+		if len(result.Skipped) > 0 {
+			fmt.Fprintf(ctx.Stdout, "Skipped: %s\n", strings.Join(result.Skipped, ", "))
+		}
+		return err
 	}
 
-	if meta.Mainline == "" {
-		return fmt.Errorf("mainline branch not set in metadata")
+	entry, err := m.Update(args[0])
+	if errors.Is(err, gittree.ErrRebase) {
+		entry, _ := m.Get(args[0])
+		fmt.Fprintf(ctx.Stdout, "\nRebase failed. You may have conflicts to resolve.\n")
+		fmt.Fprintf(ctx.Stdout, "To continue after resolving conflicts:\n")
+		fmt.Fprintf(ctx.Stdout, "  cd %s\n", entry.Path)
+		fmt.Fprintf(ctx.Stdout, "  git rebase --continue\n")
 	}
-
-	// Check if worktree is clean
-	wtRepo := git.NewRepo(entry.Path)
-	clean, err := wtRepo.IsClean()
 	if err != nil {
-		return fmt.Errorf("failed to check worktree status: %w", err)
-	}
-	if !clean {
-		return fmt.Errorf("worktree has uncommitted changes, please commit or stash them first")
-	}
-
-	// Fetch latest
-	fmt.Println("Fetching latest from origin...")
-	repo := git.NewRepo(repoPath)
-	if err := repo.Fetch(); err != nil {
-		return fmt.Errorf("failed to fetch: %w", err)
-	}
-
-	// Rebase onto mainline
-	target := fmt.Sprintf("origin/%s", meta.Mainline)
-	fmt.Printf("Rebasing onto %s...\n", target)
-	if err := wtRepo.Rebase(target); err != nil {
-		fmt.Printf("\nRebase failed. You may have conflicts to resolve.\n")
-		fmt.Printf("To continue after resolving conflicts:\n")
-		fmt.Printf("  cd %s\n", entry.Path)
-		fmt.Printf("  git rebase --continue\n")
 		return err
 	}
 
-	fmt.Printf("\nWorktree updated successfully!\n")
-	fmt.Printf("Branch %s is now up to date with %s.\n", entry.Branch, target)
+	fmt.Fprintf(ctx.Stdout, "\nWorktree updated successfully!\n")
+	fmt.Fprintf(ctx.Stdout, "Branch %s is now up to date with %s.\n", entry.Branch, m.MainlineRef())
 	return nil
 }
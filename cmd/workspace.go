@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// workspaceFlag defines the --workspace flag of commands that can work on a ticket across
+// the repositories of a workspace.
+func workspaceFlag(fs *flag.FlagSet) *string {
+	return fs.String("workspace", "", "Work on the ticket in every repository of `workspace`")
+}
+
+// openWorkspace opens a workspace configured in git config, with its progress messages
+// written to the command output.
+func openWorkspace(ctx *Context, name string) (*gittree.Workspace, error) {
+	return gittree.OpenWorkspace(ctx.Dir, name, gittree.Options{Log: ctx.Stdout, Getenv: ctx.Getenv, Now: ctx.Now, Trace: ctx.trace})
+}
+
+// runWorkspaceCreate creates worktrees for a ticket in every repository of a workspace.
+// The tracker is configured by the first repository.
+func runWorkspaceCreate(ctx *Context, name string, args []string, noTracker bool) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+	settings, err := config.LoadSettings(ws.Members()[0].Manager.RepoPath())
+	if err != nil {
+		return err
+	}
+
+	opts := gittree.CreateOptions{}
+	if len(args) >= 2 {
+		opts.Branch = args[1]
+	}
+	if !noTracker {
+		opts.Lookup = func(ticketID string) (*gittree.TicketInfo, error) {
+			return lookupTicket(ctx, settings, ticketID)
+		}
+	}
+
+	created, err := ws.Create(args[0], opts)
+	if err != nil {
+		return err
+	}
+	entry := created[0]
+
+	if !noTracker {
+		runLifecycle(ctx, settings, "create", settings.OnCreate, entry.Ticket, entry.Branch)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nWorkspace worktrees created successfully!\n")
+	fmt.Fprintf(ctx.Stdout, "  Ticket:  %s\n", entry.Ticket)
+	if entry.TicketInfo != nil {
+		fmt.Fprintf(ctx.Stdout, "  Title:   %s\n", entry.TicketInfo.Title)
+	}
+	fmt.Fprintf(ctx.Stdout, "  Branch:  %s\n", entry.Branch)
+	fmt.Fprintf(ctx.Stdout, "  Path:    %s\n", ws.TicketPath(entry.Ticket))
+	fmt.Fprintf(ctx.Stdout, "  Repos:   %s\n", strings.Join(memberNames(ws), ", "))
+	fmt.Fprintf(ctx.Stdout, "\nTo switch to this workspace:\n")
+	fmt.Fprintf(ctx.Stdout, "  cd %s\n", ws.TicketPath(entry.Ticket))
+
+	return nil
+}
+
+// memberNames returns the names of a workspace's repositories.
+func memberNames(ws *gittree.Workspace) []string {
+	var names []string
+	for _, member := range ws.Members() {
+		names = append(names, member.Name)
+	}
+	return names
+}
+
+// runWorkspaceList displays the worktrees of a workspace's tickets, a row per repository.
+func runWorkspaceList(ctx *Context, name string, labels []string, sortBy string) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	results, err := ws.List(gittree.ListOptions{Labels: labels, Sort: sortBy})
+	if err != nil {
+		return err
+	}
+
+	if ctx.JSON {
+		return writeJSON(ctx, results)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(ctx.Stdout, "No worktrees found in workspace %s.\n", ws.Name())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKET\tREPO\tBRANCH\tSTATUS\tLAST ACTIVE\tPATH")
+	fmt.Fprintln(w, "------\t----\t------\t------\t-----------\t----")
+
+	now := ctx.Now()
+	for _, result := range results {
+		ticketID := result.Ticket
+		for _, repo := range result.Repos {
+			if repo.State == gittree.StateMissing {
+				fmt.Fprintf(w, "%s\t%s\t-\t%s\t-\t-\n", ticketID, repo.Repo, ctx.colorize(stateColors[repo.State], repo.State))
+			} else {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ticketID, repo.Repo, repo.Branch, ctx.colorize(stateColors[repo.State], listState(repo.Status)), formatAge(repo.LastActive, now), repo.Path)
+			}
+			// The ticket is only shown on its first row
+			ticketID = ""
+		}
+	}
+
+	w.Flush()
+	return nil
+}
+
+// runWorkspaceStatus summarizes a workspace's tickets or, given a ticket, shows the status
+// of its worktree in each repository.
+func runWorkspaceStatus(ctx *Context, name string, args []string) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		results, err := ws.List(gittree.ListOptions{})
+		if err != nil {
+			return err
+		}
+		if ctx.JSON {
+			return writeJSON(ctx, results)
+		}
+		if len(results) == 0 {
+			fmt.Fprintf(ctx.Stdout, "No worktrees found in workspace %s.\n", ws.Name())
+			return nil
+		}
+
+		w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TICKET\tSTATUS\tREPOS\tCHANGES\tPATH")
+		fmt.Fprintln(w, "------\t------\t-----\t-------\t----")
+		for _, result := range results {
+			present := 0
+			for _, repo := range result.Repos {
+				if repo.State != gittree.StateMissing {
+					present++
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\t%s\n", result.Ticket, result.State, present, len(result.Repos), result.Changes, result.Path)
+		}
+		w.Flush()
+		return nil
+	}
+
+	result, err := ws.Status(args[0])
+	if err != nil {
+		return err
+	}
+	if ctx.JSON {
+		return writeJSON(ctx, result)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "Ticket:    %s\n", result.Ticket)
+	fmt.Fprintf(ctx.Stdout, "Workspace: %s\n", ws.Name())
+	fmt.Fprintf(ctx.Stdout, "Path:      %s\n", result.Path)
+	fmt.Fprintf(ctx.Stdout, "Status:    %s\n", result.State)
+	fmt.Fprintf(ctx.Stdout, "Active:    %s\n\n", formatAge(result.LastActive, ctx.Now()))
+
+	w := tabwriter.NewWriter(ctx.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tBRANCH\tSTATUS\tCHANGES\tAHEAD/BEHIND")
+	fmt.Fprintln(w, "----\t------\t------\t-------\t------------")
+	for _, repo := range result.Repos {
+		branch, changes, aheadBehind := "-", "-", "-"
+		if repo.State != gittree.StateMissing {
+			branch = repo.Branch
+		}
+		switch repo.State {
+		case gittree.StateClean, gittree.StateDirty:
+			changes = fmt.Sprintf("%d", repo.Changes)
+			aheadBehind = "?"
+			if repo.Compared {
+				aheadBehind = fmt.Sprintf("↑%d ↓%d", repo.Ahead, repo.Behind)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", repo.Repo, branch, repo.State, changes, aheadBehind)
+	}
+	w.Flush()
+	return nil
+}
+
+// runWorkspaceUpdate rebases a ticket's worktrees onto the mainline of their repositories.
+func runWorkspaceUpdate(ctx *Context, name string, args []string) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	updated, err := ws.Update(args[0])
+	fmt.Fprintf(ctx.Stdout, "\nUpdated %d of %d worktree(s) for %s.\n", len(updated), len(ws.Members()), args[0])
+	if errors.Is(err, gittree.ErrRebase) {
+		fmt.Fprintf(ctx.Stdout, "Rebases that failed are left for their conflicts to be resolved with git rebase --continue.\n")
+	}
+	return err
+}
+
+// runWorkspacePush pushes a ticket's branch in every repository of a workspace.
+func runWorkspacePush(ctx *Context, name string, args []string, noTracker bool) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	pushed, err := ws.Push(args[0])
+	if err != nil {
+		return err
+	}
+	entry := pushed[0]
+
+	if !noTracker {
+		settings, err := config.LoadSettings(ws.Members()[0].Manager.RepoPath())
+		if err != nil {
+			return err
+		}
+		runLifecycle(ctx, settings, "push", settings.OnPush, entry.Ticket, entry.Branch)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nBranch %s pushed in %d repositories.\n", entry.Branch, len(pushed))
+	return nil
+}
+
+// runWorkspaceDelete removes a ticket's worktrees and branches in every repository of a
+// workspace, and the ticket directory.
+func runWorkspaceDelete(ctx *Context, name string, args []string, noTracker bool) error {
+	ws, err := openWorkspace(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := ws.Delete(args[0], gittree.DeleteOptions{})
+	if errors.Is(err, gittree.ErrDirty) {
+		fmt.Fprintf(ctx.Stdout, "Warning: %v\n", err)
+		ok, confirmErr := ctx.Confirm("Continue with deletion?", false)
+		if confirmErr != nil {
+			return confirmErr
+		}
+		if !ok {
+			return fmt.Errorf("deletion %w", ErrCancelled)
+		}
+		deleted, err = ws.Delete(args[0], gittree.DeleteOptions{Force: true})
+	}
+	if err != nil {
+		return err
+	}
+	entry := deleted[0]
+
+	if !noTracker {
+		settings, err := config.LoadSettings(ws.Members()[0].Manager.RepoPath())
+		if err != nil {
+			return err
+		}
+		runLifecycle(ctx, settings, "delete", settings.OnDelete, entry.Ticket, entry.Branch)
+	}
+
+	fmt.Fprintf(ctx.Stdout, "\nWorktrees for %s deleted from workspace %s.\n", entry.Ticket, ws.Name())
+	return nil
+}
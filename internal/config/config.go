@@ -2,10 +2,8 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -22,6 +20,80 @@ type WorktreeEntry struct {
 
 	// Ticket is the ticket/task identifier (e.g., "PROJ-123").
 	Ticket string `json:"ticket"`
+
+	// LastAccessed is when the worktree was last switched to or entered, if ever.
+	LastAccessed time.Time `json:"last_accessed,omitzero"`
+
+	// TicketInfo caches details fetched from the issue tracker, if one is configured.
+	TicketInfo *TicketInfo `json:"ticket_info,omitempty"`
+
+	// Description is a free-form summary of the work in the worktree.
+	Description string `json:"description,omitempty"`
+
+	// Labels tag the worktree (e.g., "blocked", "review"), sorted and without duplicates.
+	Labels []string `json:"labels,omitempty"`
+
+	// Notes are timestamped free-form notes, oldest first.
+	Notes []Note `json:"notes,omitempty"`
+
+	// Workspace is the name of the workspace the worktree was created in with the
+	// worktrees of its other repositories, if any.
+	Workspace string `json:"workspace,omitempty"`
+
+	// Archived is when the worktree directory was removed by an archive, or zero if the
+	// worktree is checked out.
+	Archived time.Time `json:"archived,omitzero"`
+
+	// Stash is the commit holding the uncommitted changes saved when the worktree was
+	// archived, if there were any. It is kept alive by the ref returned by ArchiveRef.
+	Stash string `json:"stash,omitempty"`
+}
+
+// IsArchived reports whether the worktree is archived.
+func (e WorktreeEntry) IsArchived() bool {
+	return !e.Archived.IsZero()
+}
+
+// ArchiveRef returns the ref that keeps a ticket's archived changes alive.
+func ArchiveRef(ticket string) string {
+	return "refs/tree/archive/" + ticket
+}
+
+// Note is a timestamped note attached to a worktree.
+type Note struct {
+	// Time is when the note was added.
+	Time time.Time `json:"time"`
+
+	// Text is the note itself.
+	Text string `json:"text"`
+}
+
+// HasLabel reports whether the entry has the given label.
+func (e WorktreeEntry) HasLabel(label string) bool {
+	for _, l := range e.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketInfo caches ticket details fetched from an issue tracker.
+type TicketInfo struct {
+	// Title is the ticket's summary line.
+	Title string `json:"title,omitempty"`
+
+	// Status is the ticket's workflow state when it was fetched.
+	Status string `json:"status,omitempty"`
+
+	// Assignee is the ticket's assignee when it was fetched.
+	Assignee string `json:"assignee,omitempty"`
+
+	// URL links to the ticket in the tracker.
+	URL string `json:"url,omitempty"`
+
+	// Fetched is the timestamp when the details were fetched.
+	Fetched time.Time `json:"fetched"`
 }
 
 // Metadata represents the complete worktree metadata for a repository.
@@ -33,60 +105,43 @@ type Metadata struct {
 	Mainline string `json:"mainline"`
 }
 
-// metadataPath returns the path to the metadata file for a repository.
-func metadataPath(repoPath string) string {
-	return filepath.Join(repoPath, ".git", "worktree-metadata.json")
+// MetadataPath returns the path to the file the metadata for a repository is stored in
+// by the configured storage backend. It lives in the common git directory so it is
+// shared by all worktrees, including those of bare repositories.
+func MetadataPath(repoPath string) (string, error) {
+	storage, err := OpenStorage(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return storage.Path(), nil
 }
 
-// Load reads the metadata file from the repository.
-// If the file doesn't exist, returns an empty Metadata.
+// Load reads the metadata from the repository's configured storage backend.
+// If none has been stored, returns an empty Metadata.
 func Load(repoPath string) (*Metadata, error) {
-	path := metadataPath(repoPath)
-
-	data, err := os.ReadFile(path)
+	storage, err := OpenStorage(repoPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &Metadata{
-				Worktrees: make(map[string]WorktreeEntry),
-			}, nil
-		}
-		return nil, fmt.Errorf("failed to read metadata: %w", err)
+		return nil, err
 	}
-
-	var meta Metadata
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata: %w", err)
-	}
-
-	if meta.Worktrees == nil {
-		meta.Worktrees = make(map[string]WorktreeEntry)
-	}
-
-	return &meta, nil
+	return storage.Load()
 }
 
-// Save writes the metadata to the repository.
+// Save writes the metadata to the repository's configured storage backend. Callers also
+// record it in the user's registry with UpdateRegistry.
 func Save(repoPath string, meta *Metadata) error {
-	path := metadataPath(repoPath)
-
-	data, err := json.MarshalIndent(meta, "", "  ")
+	storage, err := OpenStorage(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return err
 	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
-	}
-
-	return nil
+	return storage.Save(meta)
 }
 
-// AddWorktree adds a new worktree entry to the metadata.
-func (m *Metadata) AddWorktree(ticket, path, branch string) {
+// AddWorktree adds a new worktree entry, created at the given time, to the metadata.
+func (m *Metadata) AddWorktree(ticket, path, branch string, created time.Time) {
 	m.Worktrees[ticket] = WorktreeEntry{
 		Path:    path,
 		Branch:  branch,
-		Created: time.Now(),
+		Created: created,
 		Ticket:  ticket,
 	}
 }
@@ -102,6 +157,30 @@ func (m *Metadata) GetWorktree(ticket string) (WorktreeEntry, bool) {
 	return entry, ok
 }
 
+// FindWorktree returns the ticket ID of the worktree entry matching ticket, ignoring case,
+// so "proj-123" finds an entry created as "PROJ-123".
+func (m *Metadata) FindWorktree(ticket string) (string, bool) {
+	if _, ok := m.Worktrees[ticket]; ok {
+		return ticket, true
+	}
+	for ticketID := range m.Worktrees {
+		if strings.EqualFold(ticketID, ticket) {
+			return ticketID, true
+		}
+	}
+	return "", false
+}
+
+// Tickets returns the ticket IDs of all worktree entries in sorted order.
+func (m *Metadata) Tickets() []string {
+	tickets := make([]string, 0, len(m.Worktrees))
+	for ticketID := range m.Worktrees {
+		tickets = append(tickets, ticketID)
+	}
+	sort.Strings(tickets)
+	return tickets
+}
+
 // HasWorktree checks if a worktree exists for the given ticket.
 func (m *Metadata) HasWorktree(ticket string) bool {
 	_, ok := m.Worktrees[ticket]
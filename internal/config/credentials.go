@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Credentials holds the account details used to authenticate with an issue tracker.
+type Credentials struct {
+	// User is the account name, for trackers that use basic authentication.
+	User string
+
+	// Token is the API token.
+	Token string
+}
+
+// CredentialsPath returns the path to the user's credentials file, reading the environment
+// with getenv. It can be overridden with GIT_TREE_CREDENTIALS and otherwise lives under
+// XDG_CONFIG_HOME.
+func CredentialsPath(getenv func(string) string) string {
+	if path := getenv("GIT_TREE_CREDENTIALS"); path != "" {
+		return path
+	}
+
+	configHome := getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home := getenv("HOME")
+		if home == "" {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "git-tree", "credentials")
+}
+
+// LoadCredentials reads the credentials for a tracker type from the credentials file at path.
+// The file uses git config syntax:
+//
+//	[tracker "jira"]
+//		user = me@example.com
+//		token = ...
+//
+// Returns empty credentials if the file doesn't exist, and an error if it is
+// readable by other users.
+func LoadCredentials(path, trackerType string) (*Credentials, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) || path == "" {
+			return &Credentials{}, nil
+		}
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("credentials file %s is accessible by other users, run: chmod 600 %s", path, path)
+	}
+
+	section := "tracker." + trackerType + "."
+	return &Credentials{
+		User:  credentialValue(path, section+"user"),
+		Token: credentialValue(path, section+"token"),
+	}, nil
+}
+
+// credentialValue reads a single key from the credentials file, or returns "" if it isn't set.
+func credentialValue(path, key string) string {
+	output, err := git.NewRepo("").Run("config", "--file", path, "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
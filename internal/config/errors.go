@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors callers may want to tell apart, wrapped in an *Error. Check for them with errors.Is.
+var (
+	// ErrNotFound means a ticket has no worktree.
+	ErrNotFound = errors.New("worktree not found")
+
+	// ErrExists means a ticket already has a worktree, or its directory already exists.
+	ErrExists = errors.New("worktree already exists")
+
+	// ErrArchived means a worktree is archived, so it has no directory to work in.
+	ErrArchived = errors.New("worktree is archived")
+
+	// ErrNoMainline means the mainline branch hasn't been detected yet.
+	ErrNoMainline = errors.New("mainline branch not set")
+
+	// ErrInvalidTicket means a ticket ID can't be used, or doesn't match the configured patterns.
+	ErrInvalidTicket = errors.New("invalid ticket ID")
+
+	// ErrInvalidSettings means the tree.* settings in git config are invalid.
+	ErrInvalidSettings = errors.New("invalid settings")
+
+	// ErrCorrupt means the metadata or journal can't be parsed.
+	ErrCorrupt = errors.New("corrupt metadata")
+)
+
+// Error is a failure that errors.Is matches against its Kind, with a message of its own.
+type Error struct {
+	// Kind is the sentinel error the failure is an instance of.
+	Kind error
+
+	// Ticket is the ticket the failure concerns, if any.
+	Ticket string
+
+	// Err is the underlying error, if any.
+	Err error
+
+	// Message describes the failure. If it is empty, the underlying error's message is used.
+	Message string
+}
+
+// NewError returns an *Error of a kind concerning a ticket, which may be "", with a
+// formatted message.
+func NewError(kind error, ticket, format string, args ...any) *Error {
+	return &Error{Kind: kind, Ticket: ticket, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return e.Kind.Error()
+	}
+}
+
+// Unwrap returns the kind of error and the underlying error, so errors.Is and errors.As
+// match either.
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
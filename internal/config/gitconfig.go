@@ -0,0 +1,390 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Git config sections of the gitconfig storage backend. Each worktree is a
+// tree-ticket.<ticket> section, and the mainline is tree-metadata.mainline.
+const (
+	ticketSection   = "tree-ticket"
+	keyMetaMainline = "tree-metadata.mainline"
+)
+
+// Sections a worktree's entry is held in while its section is rewritten: the new entry
+// is written to a pending section, the old one is moved to a replaced section, and the
+// pending section is renamed into place.
+const (
+	pendingSection  = "tree-ticket-pending"
+	replacedSection = "tree-ticket-replaced"
+)
+
+// gitConfigStorage stores the metadata in the repository's own git config, one section
+// per worktree:
+//
+//	[tree-ticket "PROJ-123"]
+//		path = /home/me/src/worktrees/repo/PROJ-123
+//		branch = PROJ-123
+//		created = 2026-01-13T10:30:00Z
+//		label = blocked
+//		note = 2026-01-14T09:00:00Z waiting on API review
+type gitConfigStorage struct {
+	repo *git.Repo
+	path string
+}
+
+// Name returns StorageGitConfig.
+func (s *gitConfigStorage) Name() string {
+	return StorageGitConfig
+}
+
+// Path returns the path of the repository's git config file.
+func (s *gitConfigStorage) Path() string {
+	return s.path
+}
+
+// Load reads the tree-ticket sections of the repository's git config. Unknown keys are
+// ignored. A worktree whose section was moved aside by a save that didn't finish is read
+// from where it was moved to.
+func (s *gitConfigStorage) Load() (*Metadata, error) {
+	items, err := s.read(`^tree-(ticket|ticket-replaced|metadata)\.`)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := newMetadata()
+	replaced := newMetadata()
+	for _, item := range items {
+		key, value := item[0], item[1]
+		if key == keyMetaMainline {
+			meta.Mainline = value
+			continue
+		}
+
+		section, ticketID, name, ok := splitTicketKey(key)
+		if !ok {
+			continue
+		}
+		worktrees := meta.Worktrees
+		if section == replacedSection {
+			worktrees = replaced.Worktrees
+		}
+		entry := worktrees[ticketID]
+		entry.Ticket = ticketID
+		if err := setEntryField(&entry, name, value); err != nil {
+			return nil, &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse metadata: %s: %v", key, err)}
+		}
+		worktrees[ticketID] = entry
+	}
+
+	for ticketID, entry := range replaced.Worktrees {
+		if _, ok := meta.Worktrees[ticketID]; !ok {
+			meta.Worktrees[ticketID] = entry
+		}
+	}
+	return meta, nil
+}
+
+// Save writes the metadata to the repository's git config. Only the sections of worktrees
+// that changed are rewritten, each in steps that leave a complete entry in place, so a
+// save that fails partway doesn't truncate the metadata.
+func (s *gitConfigStorage) Save(meta *Metadata) error {
+	stored, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if err := s.repairSections(); err != nil {
+		return err
+	}
+
+	if meta.Mainline != stored.Mainline {
+		if err := s.setMainline(meta.Mainline); err != nil {
+			return err
+		}
+	}
+
+	for _, ticketID := range stored.Tickets() {
+		if _, ok := meta.Worktrees[ticketID]; ok {
+			continue
+		}
+		if err := s.removeSection(ticketSection + "." + ticketID); err != nil {
+			return err
+		}
+	}
+	for _, ticketID := range meta.Tickets() {
+		old, ok := stored.Worktrees[ticketID]
+		if ok && sameEntry(meta.Worktrees[ticketID], old) {
+			continue
+		}
+		if err := s.writeSection(ticketID, meta.Worktrees[ticketID], ok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSection writes a worktree's entry to its section. The entry is written to a
+// pending section first; the old section, if replace is set, is then moved aside and the
+// pending section renamed into place. If a step fails, the old section is put back.
+func (s *gitConfigStorage) writeSection(ticketID string, entry WorktreeEntry, replace bool) error {
+	section := ticketSection + "." + ticketID
+	pending := pendingSection + "." + ticketID
+	replaced := replacedSection + "." + ticketID
+
+	for _, field := range entryFields(entry) {
+		if _, err := s.repo.Run("config", "--local", "--add", pending+"."+field[0], field[1]); err != nil {
+			s.removeSection(pending)
+			return fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+
+	if replace {
+		if err := s.renameSection(section, replaced); err != nil {
+			s.removeSection(pending)
+			return err
+		}
+	}
+	if err := s.renameSection(pending, section); err != nil {
+		if replace {
+			s.renameSection(replaced, section)
+		}
+		s.removeSection(pending)
+		return err
+	}
+	if replace {
+		return s.removeSection(replaced)
+	}
+	return nil
+}
+
+// repairSections cleans up after a save that didn't finish: pending sections are removed,
+// and sections that were moved aside are put back, or removed if they were replaced.
+func (s *gitConfigStorage) repairSections() error {
+	items, err := s.read(`^tree-ticket(-pending|-replaced)?\.`)
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]bool)
+	leftovers := make(map[string]bool)
+	for _, item := range items {
+		if section, ticketID, _, ok := splitTicketKey(item[0]); ok {
+			if section == ticketSection {
+				stored[ticketID] = true
+			} else {
+				leftovers[section+"."+ticketID] = true
+			}
+		}
+	}
+
+	for name := range leftovers {
+		section, ticketID, _ := strings.Cut(name, ".")
+		var err error
+		if section == replacedSection && !stored[ticketID] {
+			err = s.renameSection(name, ticketSection+"."+ticketID)
+		} else {
+			err = s.removeSection(name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes the tree-ticket and tree-metadata sections from the repository's git
+// config.
+func (s *gitConfigStorage) Clear() error {
+	stored, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if err := s.repairSections(); err != nil {
+		return err
+	}
+	for _, ticketID := range stored.Tickets() {
+		if err := s.removeSection(ticketSection + "." + ticketID); err != nil {
+			return err
+		}
+	}
+	return s.setMainline("")
+}
+
+// setMainline sets tree-metadata.mainline, or unsets it if mainline is "".
+func (s *gitConfigStorage) setMainline(mainline string) error {
+	var err error
+	if mainline == "" {
+		_, err = s.repo.Run("config", "--local", "--unset", keyMetaMainline)
+		// --unset exits with status 5 when the key isn't set
+		if git.ExitCode(err) == 5 {
+			err = nil
+		}
+	} else {
+		_, err = s.repo.Run("config", "--local", keyMetaMainline, mainline)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// read returns the keys and values in the repository's git config matching pattern.
+func (s *gitConfigStorage) read(pattern string) ([][2]string, error) {
+	// -z separates keys from values with a newline and entries with a NUL, so values
+	// with newlines in them can be read
+	output, err := s.repo.Run("config", "--local", "-z", "--get-regexp", pattern)
+	// git config exits with status 1 when no keys match
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var items [][2]string
+	for _, item := range strings.Split(output, "\x00") {
+		if item != "" {
+			key, value, _ := strings.Cut(item, "\n")
+			items = append(items, [2]string{key, value})
+		}
+	}
+	return items, nil
+}
+
+// removeSection removes a section, such as a worktree's.
+func (s *gitConfigStorage) removeSection(section string) error {
+	if _, err := s.repo.Run("config", "--local", "--remove-section", section); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// renameSection renames a section.
+func (s *gitConfigStorage) renameSection(from, to string) error {
+	if _, err := s.repo.Run("config", "--local", "--rename-section", from, to); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// splitTicketKey splits the key of a worktree field, as reported by git config, into the
+// section, ticket ID and field name. git config reports section and field names in
+// lowercase, but not ticket IDs.
+func splitTicketKey(key string) (section, ticketID, name string, ok bool) {
+	section, rest, ok := strings.Cut(key, ".")
+	dot := strings.LastIndex(rest, ".")
+	if !ok || dot < 0 {
+		return "", "", "", false
+	}
+	switch section {
+	case ticketSection, pendingSection, replacedSection:
+		return section, rest[:dot], rest[dot+1:], true
+	}
+	return "", "", "", false
+}
+
+// entryFields returns the git config keys and values of a worktree entry, skipping those
+// that are empty.
+func entryFields(entry WorktreeEntry) [][2]string {
+	var fields [][2]string
+	add := func(key, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{key, value})
+		}
+	}
+	addTime := func(key string, t time.Time) {
+		if !t.IsZero() {
+			add(key, t.Format(time.RFC3339Nano))
+		}
+	}
+
+	add("path", entry.Path)
+	add("branch", entry.Branch)
+	addTime("created", entry.Created)
+	addTime("lastAccessed", entry.LastAccessed)
+	add("description", entry.Description)
+	for _, label := range entry.Labels {
+		add("label", label)
+	}
+	for _, note := range entry.Notes {
+		add("note", note.Time.Format(time.RFC3339Nano)+" "+note.Text)
+	}
+	add("workspace", entry.Workspace)
+	addTime("archived", entry.Archived)
+	add("stash", entry.Stash)
+	if info := entry.TicketInfo; info != nil {
+		add("ticketTitle", info.Title)
+		add("ticketStatus", info.Status)
+		add("ticketAssignee", info.Assignee)
+		add("ticketUrl", info.URL)
+		// Always written, so details with no fields set are still stored
+		fields = append(fields, [2]string{"ticketFetched", info.Fetched.Format(time.RFC3339Nano)})
+	}
+	return fields
+}
+
+// setEntryField sets the field of a worktree entry stored under a git config key, as
+// reported by git config in lowercase.
+func setEntryField(entry *WorktreeEntry, key, value string) error {
+	parseTime := func(t *time.Time, value string) error {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err == nil {
+			*t = parsed
+		}
+		return err
+	}
+	ticketInfo := func() *TicketInfo {
+		if entry.TicketInfo == nil {
+			entry.TicketInfo = &TicketInfo{}
+		}
+		return entry.TicketInfo
+	}
+
+	switch key {
+	case "path":
+		entry.Path = value
+	case "branch":
+		entry.Branch = value
+	case "created":
+		return parseTime(&entry.Created, value)
+	case "lastaccessed":
+		return parseTime(&entry.LastAccessed, value)
+	case "description":
+		entry.Description = value
+	case "label":
+		entry.Labels = append(entry.Labels, value)
+	case "note":
+		stamp, text, _ := strings.Cut(value, " ")
+		note := Note{Text: text}
+		if err := parseTime(&note.Time, stamp); err != nil {
+			return err
+		}
+		entry.Notes = append(entry.Notes, note)
+	case "workspace":
+		entry.Workspace = value
+	case "archived":
+		return parseTime(&entry.Archived, value)
+	case "stash":
+		entry.Stash = value
+	case "tickettitle":
+		ticketInfo().Title = value
+	case "ticketstatus":
+		ticketInfo().Status = value
+	case "ticketassignee":
+		ticketInfo().Assignee = value
+	case "ticketurl":
+		ticketInfo().URL = value
+	case "ticketfetched":
+		return parseTime(&ticketInfo().Fetched, value)
+	}
+	return nil
+}
+
+// sameEntry reports whether two worktree entries are stored the same way.
+func sameEntry(a, b WorktreeEntry) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// failingRunner runs git, except for commands with the argument fail, which exit with
+// status 1.
+type failingRunner struct {
+	fail string
+}
+
+func (r failingRunner) Run(ctx context.Context, c git.Command) (git.Result, error) {
+	if slices.Contains(c.Args, r.fail) {
+		return git.Result{ExitCode: 1}, &git.ExitError{Args: c.Args, ExitCode: 1}
+	}
+	return git.DefaultRunner.Run(ctx, c)
+}
+
+// newGitConfigStorage returns gitconfig storage in a new repository.
+func newGitConfigStorage(t *testing.T) *gitConfigStorage {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	repo := git.NewRepo(dir)
+	if _, err := repo.Run("init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+	return &gitConfigStorage{repo: repo}
+}
+
+// sections returns the names of the tree-ticket sections in the storage's git config.
+func sections(t *testing.T, s *gitConfigStorage) []string {
+	t.Helper()
+	items, err := s.read(`^tree-ticket`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, item := range items {
+		if section, ticketID, _, ok := splitTicketKey(item[0]); ok && !slices.Contains(names, section+"."+ticketID) {
+			names = append(names, section+"."+ticketID)
+		}
+	}
+	return names
+}
+
+func TestGitConfigSaveFailure(t *testing.T) {
+	s := newGitConfigStorage(t)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	meta := newMetadata()
+	meta.AddWorktree("PROJ-1", "/src/worktrees/PROJ-1", "PROJ-1", created)
+	if err := s.Save(meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write that fails partway leaves the stored entry whole
+	s.repo.Runner = failingRunner{fail: pendingSection + ".PROJ-1.label"}
+	changed := newMetadata()
+	changed.AddWorktree("PROJ-1", "/src/worktrees/PROJ-1", "PROJ-1", created)
+	entry := changed.Worktrees["PROJ-1"]
+	entry.Description = "Fix the thing"
+	entry.Labels = []string{"blocked"}
+	changed.Worktrees["PROJ-1"] = entry
+	if err := s.Save(changed); err == nil {
+		t.Fatal("Save succeeded with a failing git config")
+	}
+	s.repo.Runner = nil
+
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameEntry(loaded.Worktrees["PROJ-1"], meta.Worktrees["PROJ-1"]) {
+		t.Errorf("entry after failed save = %+v, want %+v", loaded.Worktrees["PROJ-1"], meta.Worktrees["PROJ-1"])
+	}
+	if got := sections(t, s); strings.Join(got, ",") != "tree-ticket.PROJ-1" {
+		t.Errorf("sections after failed save = %v", got)
+	}
+}
+
+func TestGitConfigInterruptedSave(t *testing.T) {
+	s := newGitConfigStorage(t)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	meta := newMetadata()
+	meta.AddWorktree("PROJ-1", "/src/worktrees/PROJ-1", "PROJ-1", created)
+	if err := s.Save(meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stop a save after the old section was moved aside, before the new one was renamed
+	for _, args := range [][]string{
+		{"config", "--local", "--add", pendingSection + ".PROJ-1.path", "/elsewhere"},
+		{"config", "--local", "--rename-section", ticketSection + ".PROJ-1", replacedSection + ".PROJ-1"},
+	} {
+		if _, err := s.repo.Run(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameEntry(loaded.Worktrees["PROJ-1"], meta.Worktrees["PROJ-1"]) {
+		t.Errorf("entry after interrupted save = %+v, want %+v", loaded.Worktrees["PROJ-1"], meta.Worktrees["PROJ-1"])
+	}
+
+	// The next save puts the moved section back and discards the pending one
+	meta.AddWorktree("PROJ-2", "/src/worktrees/PROJ-2", "PROJ-2", created)
+	if err := s.Save(meta); err != nil {
+		t.Fatal(err)
+	}
+	if got := sections(t, s); strings.Join(got, ",") != "tree-ticket.PROJ-1,tree-ticket.PROJ-2" {
+		t.Errorf("sections after next save = %v", got)
+	}
+}
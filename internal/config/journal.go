@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// Change records how a single ticket was affected by an operation.
+type Change struct {
+	// Ticket is the ticket/task identifier that was affected.
+	Ticket string `json:"ticket"`
+
+	// Before is the metadata entry prior to the operation, or nil if it did not exist.
+	Before *WorktreeEntry `json:"before,omitempty"`
+
+	// After is the metadata entry following the operation, or nil if it was removed.
+	After *WorktreeEntry `json:"after,omitempty"`
+
+	// TipBefore is the commit the branch pointed to prior to the operation.
+	TipBefore string `json:"tip_before,omitempty"`
+
+	// TipAfter is the commit the branch pointed to following the operation.
+	TipAfter string `json:"tip_after,omitempty"`
+}
+
+// Operation represents a single mutating command recorded in the journal.
+type Operation struct {
+	// ID is the sequence number of the operation within the journal.
+	ID int `json:"id"`
+
+	// Command is the name of the command that was run (e.g., "create", "delete").
+	Command string `json:"command"`
+
+	// Args are the arguments the command was invoked with.
+	Args []string `json:"args,omitempty"`
+
+	// Timestamp is when the operation completed.
+	Timestamp time.Time `json:"timestamp"`
+
+	// MainlineBefore is the mainline recorded in metadata prior to the operation.
+	MainlineBefore string `json:"mainline_before,omitempty"`
+
+	// MainlineAfter is the mainline recorded in metadata following the operation.
+	MainlineAfter string `json:"mainline_after,omitempty"`
+
+	// Changes lists the per-ticket effects of the operation.
+	Changes []Change `json:"changes,omitempty"`
+
+	// Reverts is the ID of the operation this one undid, if any.
+	Reverts int `json:"reverts,omitempty"`
+}
+
+// journalPath returns the path to the operation journal for a repository.
+func journalPath(repoPath string) (string, error) {
+	gitDir, err := util.GitCommonDir(repoPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	return filepath.Join(gitDir, "worktree-journal.jsonl"), nil
+}
+
+// LoadJournal reads all operations from the repository's journal, oldest first.
+// If the journal doesn't exist, returns an empty slice.
+func LoadJournal(repoPath string) ([]Operation, error) {
+	path, err := journalPath(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer f.Close()
+
+	var ops []Operation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal(line, &op); err != nil {
+			return nil, &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse journal: %v", err)}
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return ops, nil
+}
+
+// AppendOperation assigns the next sequence number to op and appends it to the journal.
+func AppendOperation(repoPath string, op *Operation) error {
+	ops, err := LoadJournal(repoPath)
+	if err != nil {
+		return err
+	}
+
+	op.ID = 1
+	if len(ops) > 0 {
+		op.ID = ops[len(ops)-1].ID + 1
+	}
+	if op.Timestamp.IsZero() {
+		op.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation: %w", err)
+	}
+
+	path, err := journalPath(repoPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+
+	return nil
+}
+
+// LastOperation returns the most recent operation that has not been undone and is not
+// itself an undo, whether or not it can be undone. Returns nil if there is no such
+// operation.
+func LastOperation(ops []Operation) *Operation {
+	reverted := Reverted(ops)
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Reverts != 0 || reverted[op.ID] {
+			continue
+		}
+		return &ops[i]
+	}
+
+	return nil
+}
+
+// Reversible reports whether an operation can be undone. Operations that changed no
+// worktrees, such as push, can't be.
+func (op *Operation) Reversible() bool {
+	return len(op.Changes) > 0
+}
+
+// Reverted returns the set of operation IDs that have been undone.
+func Reverted(ops []Operation) map[int]bool {
+	reverted := make(map[int]bool)
+	for _, op := range ops {
+		if op.Reverts != 0 {
+			reverted[op.Reverts] = true
+		}
+	}
+	return reverted
+}
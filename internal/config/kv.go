@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// kvHeader is the first line of a key-value metadata file.
+const kvHeader = "# git-tree worktree metadata"
+
+// Keys in a key-value metadata file. Each worktree is stored under kvWorktreePrefix
+// followed by its ticket ID.
+const (
+	kvMainline       = "mainline"
+	kvWorktreePrefix = "worktree/"
+)
+
+// kvStorage stores the metadata as sorted key-value records, one per line: a key, a tab,
+// and the value as JSON. Each worktree is a record of its own, so a change to one ticket
+// changes one line, and the file can be read and edited with line-based tools.
+type kvStorage struct {
+	path string
+}
+
+// Name returns StorageKV.
+func (s *kvStorage) Name() string {
+	return StorageKV
+}
+
+// Path returns the path of the key-value file.
+func (s *kvStorage) Path() string {
+	return s.path
+}
+
+// Load reads and parses the key-value file. Records with unknown keys are ignored.
+func (s *kvStorage) Load() (*Metadata, error) {
+	meta := newMetadata()
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return meta, nil
+		}
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, NewError(ErrCorrupt, "", "failed to parse metadata: %s:%d: missing value", s.path, n)
+		}
+		var err error
+		switch {
+		case key == kvMainline:
+			err = json.Unmarshal([]byte(value), &meta.Mainline)
+		case strings.HasPrefix(key, kvWorktreePrefix):
+			var entry WorktreeEntry
+			err = json.Unmarshal([]byte(value), &entry)
+			meta.Worktrees[strings.TrimPrefix(key, kvWorktreePrefix)] = entry
+		}
+		if err != nil {
+			return nil, &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse metadata: %s:%d: %v", s.path, n, err)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// Save writes the metadata to the key-value file, sorted by key. The file is replaced in
+// one step, so readers never see a partly written file.
+func (s *kvStorage) Save(meta *Metadata) error {
+	records := make(map[string]any, len(meta.Worktrees)+1)
+	if meta.Mainline != "" {
+		records[kvMainline] = meta.Mainline
+	}
+	for ticketID, entry := range meta.Worktrees {
+		records[kvWorktreePrefix+ticketID] = entry
+	}
+
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintln(&b, kvHeader)
+	for _, key := range keys {
+		value, err := json.Marshal(records[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", key, value)
+	}
+
+	if err := writeAtomic(s.path, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the key-value file.
+func (s *kvStorage) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove metadata: %w", err)
+	}
+	return nil
+}
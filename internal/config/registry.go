@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// keyCodeRoot is the git config key listing the directories index rebuild scans for
+// repositories.
+const keyCodeRoot = "tree.codeRoot"
+
+// Registry is the user's index of every repository on the machine with git-tree worktrees,
+// so tickets can be found from any directory. It is a copy of the repositories' metadata,
+// kept up to date whenever metadata is saved, and can be rebuilt from it.
+type Registry struct {
+	// Repos maps the paths of primary repositories to their worktrees.
+	Repos map[string]RegistryRepo `json:"repos"`
+}
+
+// RegistryRepo is a repository's entry in the registry.
+type RegistryRepo struct {
+	// Mainline is the name of the repository's mainline branch.
+	Mainline string `json:"mainline"`
+
+	// Updated is when the entry was last written.
+	Updated time.Time `json:"updated"`
+
+	// Worktrees maps ticket IDs to their metadata.
+	Worktrees map[string]WorktreeEntry `json:"worktrees"`
+}
+
+// RegistryMatch is a worktree found in the registry.
+type RegistryMatch struct {
+	// RepoPath is the path of the primary repository the worktree belongs to.
+	RepoPath string
+
+	// Entry is the worktree's metadata as last saved.
+	Entry WorktreeEntry
+}
+
+// RegistryPath returns the path to the user's registry, reading the environment with
+// getenv. It can be overridden with GIT_TREE_REGISTRY and otherwise lives under
+// XDG_DATA_HOME. It is "" if there is no home directory to put it in.
+func RegistryPath(getenv func(string) string) string {
+	if path := getenv("GIT_TREE_REGISTRY"); path != "" {
+		return path
+	}
+
+	dataHome := getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home := getenv("HOME")
+		if home == "" {
+			return ""
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "git-tree", "registry.json")
+}
+
+// LoadRegistry reads the registry at path. If the file doesn't exist, it returns an empty
+// registry.
+func LoadRegistry(path string) (*Registry, error) {
+	reg := &Registry{Repos: make(map[string]RegistryRepo)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return reg, nil
+		}
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse registry %s: %v (run: git tree index rebuild)", path, err)}
+	}
+	if reg.Repos == nil {
+		reg.Repos = make(map[string]RegistryRepo)
+	}
+	return reg, nil
+}
+
+// SaveRegistry writes the registry to path, creating its directory if needed. The file is
+// replaced in one step, so readers never see a partly written registry.
+func SaveRegistry(path string, reg *Registry) error {
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write registry: %w", err)
+	}
+	return nil
+}
+
+// Set records a repository's metadata in the registry. A repository without worktrees is
+// removed from it.
+func (r *Registry) Set(repoPath string, meta *Metadata, now time.Time) {
+	if len(meta.Worktrees) == 0 {
+		delete(r.Repos, repoPath)
+		return
+	}
+	worktrees := make(map[string]WorktreeEntry, len(meta.Worktrees))
+	for ticketID, entry := range meta.Worktrees {
+		worktrees[ticketID] = entry
+	}
+	r.Repos[repoPath] = RegistryRepo{Mainline: meta.Mainline, Updated: now, Worktrees: worktrees}
+}
+
+// RepoPaths returns the paths of the registered repositories, sorted.
+func (r *Registry) RepoPaths() []string {
+	paths := make([]string, 0, len(r.Repos))
+	for path := range r.Repos {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Find returns the worktrees registered for a ticket, ignoring case, in the order of
+// their repositories' paths.
+func (r *Registry) Find(ticketID string) []RegistryMatch {
+	var matches []RegistryMatch
+	for _, repoPath := range r.RepoPaths() {
+		for id, entry := range r.Repos[repoPath].Worktrees {
+			if strings.EqualFold(id, ticketID) {
+				matches = append(matches, RegistryMatch{RepoPath: repoPath, Entry: entry})
+			}
+		}
+	}
+	return matches
+}
+
+// Registry lock timing. A lock older than staleRegistryLock was left behind by a process
+// that died, and is taken over.
+const (
+	registryLockWait  = 5 * time.Second
+	staleRegistryLock = 30 * time.Second
+)
+
+// UpdateRegistry records a repository's metadata in the registry at path, as of now. The
+// registry is locked while it is read, changed and written, so concurrent updates for
+// other repositories aren't lost.
+func UpdateRegistry(path, repoPath string, meta *Metadata, now time.Time) error {
+	unlock, err := lockRegistry(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reg, err := LoadRegistry(path)
+	if err != nil {
+		return err
+	}
+	reg.Set(repoPath, meta, now)
+	return SaveRegistry(path, reg)
+}
+
+// lockRegistry locks the registry at path by creating a lock file next to it, waiting
+// for another process holding the lock to release it. It returns a function that
+// releases the lock.
+func lockRegistry(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	lock := path + ".lock"
+	deadline := time.Now().Add(registryLockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock registry: %w", err)
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleRegistryLock {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock registry: %s is held by another process", lock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// LoadCodeRoots reads the directories index rebuild scans for repositories from git
+// config for dir, with a leading ~ expanded.
+func LoadCodeRoots(dir string) ([]string, error) {
+	output, err := git.NewRepo(dir).Run("config", "--type=path", "--get-all", keyCodeRoot)
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read %s: %w", keyCodeRoot, err)
+	}
+
+	var roots []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			roots = append(roots, line)
+		}
+	}
+	return roots, nil
+}
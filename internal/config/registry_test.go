@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateRegistryConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "git-tree", "registry.json")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Each update reads, changes and writes the whole registry, so without the lock
+	// concurrent updates drop each other's repositories
+	const repos = 20
+	var wg sync.WaitGroup
+	for i := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta := newMetadata()
+			meta.AddWorktree("PROJ-1", "/src/worktrees/PROJ-1", "PROJ-1", now)
+			if err := UpdateRegistry(path, fmt.Sprintf("/src/repo-%d", i), meta, now); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	reg, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.Repos) != repos {
+		t.Errorf("registry has %d repositories, want %d", len(reg.Repos), repos)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock not released: %v", err)
+	}
+}
+
+func TestUpdateRegistryStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	lock := path + ".lock"
+	if err := os.WriteFile(lock, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleRegistryLock)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lock left behind by a process that died is taken over
+	meta := newMetadata()
+	meta.AddWorktree("PROJ-1", "/src/worktrees/PROJ-1", "PROJ-1", old)
+	if err := UpdateRegistry(path, "/src/repo", meta, old); err != nil {
+		t.Fatal(err)
+	}
+	if reg, err := LoadRegistry(path); err != nil || len(reg.Repos) != 1 {
+		t.Errorf("LoadRegistry = %+v, %v", reg, err)
+	}
+}
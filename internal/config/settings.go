@@ -0,0 +1,336 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/ticket"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// Settings keys in git config. Settings live in the "tree" section so they can be
+// set per repository or globally with plain git (e.g., git config --global tree.branchTemplate).
+const (
+	keyRemote         = "tree.remote"
+	keyRoot           = "tree.root"
+	keyBranchTemplate = "tree.branchTemplate"
+	keyTicketPattern  = "tree.ticketPattern"
+	keyTicketCase     = "tree.ticketCase"
+	keyTracker        = "tree.tracker"
+	keyTrackerURL     = "tree.trackerUrl"
+	keyTrackerProject = "tree.trackerProject"
+	keyTrackerUser    = "tree.trackerUser"
+	keyOnCreate       = "tree.onCreate"
+	keyOnPush         = "tree.onPush"
+	keyOnDelete       = "tree.onDelete"
+)
+
+// DefaultBranchTemplate is the branch template used when none is configured.
+const DefaultBranchTemplate = "{ticket}"
+
+// Settings represents the git-tree configuration for a repository.
+type Settings struct {
+	// Remote is the name of the remote to fetch from (e.g., "origin").
+	Remote string
+
+	// WorktreeRoot is the absolute path of the directory worktrees are created in.
+	// If empty, the default location next to the repository is used.
+	WorktreeRoot string
+
+	// BranchTemplate is the template for new branch names. "{ticket}" is replaced with the
+	// ticket ID and "{slug}" with a slug of the ticket title.
+	BranchTemplate string
+
+	// TicketPatterns are regular expressions, typically one per project key, that ticket IDs
+	// must match in full. If empty, any ticket ID is accepted.
+	TicketPatterns []string
+
+	// TicketCase is how ticket IDs are normalized: "upper", "lower" or "preserve".
+	TicketCase string
+
+	// Tracker is the issue tracker type ("jira", "github", "gitlab" or "linear"), or empty for none.
+	Tracker string
+
+	// TrackerURL is the base URL of the issue tracker.
+	TrackerURL string
+
+	// TrackerProject identifies the project within the tracker (e.g., "owner/repo" for GitHub).
+	TrackerProject string
+
+	// TrackerUser is the account name for trackers that use basic authentication.
+	TrackerUser string
+
+	// OnCreate lists tracker actions to perform after a worktree is created.
+	// Each action is "transition <state>", "assign" or "comment [<text>]".
+	OnCreate []string
+
+	// OnPush lists tracker actions to perform after a worktree's branch is pushed.
+	OnPush []string
+
+	// OnDelete lists tracker actions to perform after a worktree is deleted.
+	OnDelete []string
+}
+
+// LoadSettings reads the git-tree settings from git config for a repository,
+// filling in defaults for anything that isn't set.
+func LoadSettings(repoPath string) (*Settings, error) {
+	output, err := git.NewRepo(repoPath).Run("config", "--get-regexp", `^tree\.`)
+	// git config exits with status 1 when no keys match
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+
+	settings := &Settings{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		// git config reports keys in lowercase
+		switch key {
+		case strings.ToLower(keyRemote):
+			settings.Remote = value
+		case strings.ToLower(keyRoot):
+			settings.WorktreeRoot = value
+		case strings.ToLower(keyBranchTemplate):
+			settings.BranchTemplate = value
+		case strings.ToLower(keyTicketPattern):
+			settings.TicketPatterns = append(settings.TicketPatterns, value)
+		case strings.ToLower(keyTicketCase):
+			settings.TicketCase = value
+		case strings.ToLower(keyTracker):
+			settings.Tracker = value
+		case strings.ToLower(keyTrackerURL):
+			settings.TrackerURL = value
+		case strings.ToLower(keyTrackerProject):
+			settings.TrackerProject = value
+		case strings.ToLower(keyTrackerUser):
+			settings.TrackerUser = value
+		case strings.ToLower(keyOnCreate):
+			settings.OnCreate = append(settings.OnCreate, value)
+		case strings.ToLower(keyOnPush):
+			settings.OnPush = append(settings.OnPush, value)
+		case strings.ToLower(keyOnDelete):
+			settings.OnDelete = append(settings.OnDelete, value)
+		}
+	}
+
+	if settings.Remote == "" {
+		settings.Remote = git.DefaultRemote
+	}
+	if settings.BranchTemplate == "" {
+		settings.BranchTemplate = DefaultBranchTemplate
+	}
+
+	return settings, nil
+}
+
+// aliasPrefix is the git config prefix of command aliases: tree.alias.<name> holds the
+// command line the alias expands to.
+const aliasPrefix = "tree.alias."
+
+// LoadAliases reads the command aliases that apply in dir from git config. Outside a
+// repository only the global and system aliases are read.
+func LoadAliases(dir string) (map[string]string, error) {
+	output, err := git.NewRepo(dir).Run("config", "--get-regexp", `^tree\.alias\.`)
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+
+	aliases := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if name, ok := strings.CutPrefix(key, aliasPrefix); ok && name != "" {
+			aliases[name] = value
+		}
+	}
+	return aliases, nil
+}
+
+// SaveSettings writes the settings to the repository's git config.
+func SaveSettings(repoPath string, settings *Settings) error {
+	values := []struct {
+		key   string
+		value string
+	}{
+		{keyRemote, settings.Remote},
+		{keyRoot, settings.WorktreeRoot},
+		{keyBranchTemplate, settings.BranchTemplate},
+		{keyTicketCase, settings.TicketCase},
+		{keyTracker, settings.Tracker},
+		{keyTrackerURL, settings.TrackerURL},
+		{keyTrackerProject, settings.TrackerProject},
+		{keyTrackerUser, settings.TrackerUser},
+	}
+
+	repo := git.NewRepo(repoPath)
+	for _, v := range values {
+		var err error
+		if v.value == "" {
+			_, err = repo.Run("config", "--unset", v.key)
+		} else {
+			_, err = repo.Run("config", v.key, v.value)
+		}
+		if err != nil {
+			// --unset exits with status 5 when the key isn't set
+			if v.value == "" && git.ExitCode(err) == 5 {
+				continue
+			}
+			return fmt.Errorf("failed to write %s: %w", v.key, err)
+		}
+	}
+
+	lists := []struct {
+		key    string
+		values []string
+	}{
+		{keyTicketPattern, settings.TicketPatterns},
+		{keyOnCreate, settings.OnCreate},
+		{keyOnPush, settings.OnPush},
+		{keyOnDelete, settings.OnDelete},
+	}
+
+	for _, l := range lists {
+		if err := setConfigValues(repoPath, l.key, l.values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setConfigValues replaces all values of a multi-valued git config key.
+func setConfigValues(repoPath, key string, values []string) error {
+	repo := git.NewRepo(repoPath)
+	// --unset-all exits with status 5 when the key isn't set
+	if _, err := repo.Run("config", "--unset-all", key); err != nil && git.ExitCode(err) != 5 {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+
+	for _, value := range values {
+		if _, err := repo.Run("config", "--add", key, value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the settings are well-formed. Problems are reported as ErrInvalidSettings.
+func (s *Settings) Validate() error {
+	if err := s.validate(); err != nil {
+		return &Error{Kind: ErrInvalidSettings, Err: err}
+	}
+	return nil
+}
+
+// validate returns the first problem with the settings.
+func (s *Settings) validate() error {
+	if !strings.Contains(s.BranchTemplate, "{ticket}") {
+		return fmt.Errorf("branch template %q must contain {ticket}", s.BranchTemplate)
+	}
+	if _, err := ticket.NewMatcher(s.TicketPatterns); err != nil {
+		return err
+	}
+	if !ticket.ValidCase(s.TicketCase) {
+		return fmt.Errorf("ticket case %q must be upper, lower or preserve", s.TicketCase)
+	}
+	for _, actions := range [][]string{s.OnCreate, s.OnPush, s.OnDelete} {
+		for _, action := range actions {
+			if _, _, err := ParseAction(action); err != nil {
+				return err
+			}
+		}
+	}
+	if s.WorktreeRoot != "" && !filepath.IsAbs(s.WorktreeRoot) {
+		return fmt.Errorf("worktree root %q must be an absolute path", s.WorktreeRoot)
+	}
+	return nil
+}
+
+// WorktreeBasePath returns the directory worktrees for the repository are created in.
+func (s *Settings) WorktreeBasePath(repoPath string) string {
+	if s.WorktreeRoot != "" {
+		return s.WorktreeRoot
+	}
+	return util.GetWorktreeBasePath(repoPath)
+}
+
+// WorktreePath returns the full path for a specific worktree.
+func (s *Settings) WorktreePath(repoPath, ticketID string) string {
+	return filepath.Join(s.WorktreeBasePath(repoPath), ticketID)
+}
+
+// BranchName returns the default branch name for a ticket.
+// If slug is empty, "{slug}" is dropped from the template along with an adjoining separator.
+func (s *Settings) BranchName(ticketID, slug string) string {
+	name := s.BranchTemplate
+	if slug == "" {
+		for _, sep := range []string{"-", "_", "/", "."} {
+			name = strings.ReplaceAll(name, sep+"{slug}", "")
+			name = strings.ReplaceAll(name, "{slug}"+sep, "")
+		}
+	}
+	name = strings.ReplaceAll(name, "{slug}", slug)
+	return strings.ReplaceAll(name, "{ticket}", ticketID)
+}
+
+// MainlineRef returns the remote-tracking ref for the mainline branch (e.g., "origin/main").
+func (s *Settings) MainlineRef(mainline string) string {
+	return s.Remote + "/" + mainline
+}
+
+// NormalizeTicket applies the configured case normalization to a ticket ID and checks that
+// it is safe to use in paths and branch names and matches one of the configured patterns.
+// It must be called before a new ticket ID is used for any filesystem or git operation.
+// An unusable ticket ID is reported as ErrInvalidTicket.
+func (s *Settings) NormalizeTicket(ticketID string) (string, error) {
+	ticketID = ticket.Normalize(ticketID, s.TicketCase)
+	if err := ticket.Validate(ticketID); err != nil {
+		return "", &Error{Kind: ErrInvalidTicket, Ticket: ticketID, Err: err}
+	}
+	matcher, err := ticket.NewMatcher(s.TicketPatterns)
+	if err != nil {
+		return "", &Error{Kind: ErrInvalidSettings, Err: err}
+	}
+	if err := matcher.Check(ticketID); err != nil {
+		return "", &Error{Kind: ErrInvalidTicket, Ticket: ticketID, Err: err}
+	}
+	return ticketID, nil
+}
+
+// ExtractTicket returns the normalized ticket ID found in a branch name or commit message,
+// or "" if there is none.
+func (s *Settings) ExtractTicket(text string) string {
+	matcher, err := ticket.NewMatcher(s.TicketPatterns)
+	if err != nil {
+		return ""
+	}
+	id := matcher.Extract(text)
+	if id == "" {
+		return ""
+	}
+	return ticket.Normalize(id, s.TicketCase)
+}
+
+// Tracker lifecycle actions.
+const (
+	ActionTransition = "transition"
+	ActionAssign     = "assign"
+	ActionComment    = "comment"
+)
+
+// ParseAction splits a lifecycle action such as "transition In Progress" into its kind and argument.
+func ParseAction(action string) (string, string, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(action), " ")
+	arg = strings.TrimSpace(arg)
+	switch kind {
+	case ActionTransition:
+		if arg == "" {
+			return "", "", fmt.Errorf("tracker action %q requires a state", action)
+		}
+	case ActionAssign, ActionComment:
+	default:
+		return "", "", fmt.Errorf("unknown tracker action %q", action)
+	}
+	return kind, arg, nil
+}
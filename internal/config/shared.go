@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// SharedRef is the ref the shared metadata is committed to, so it can be pushed to and
+// fetched from a remote like any other ref.
+const SharedRef = "refs/git-tree/metadata"
+
+// sharedFile is the name of the file holding the shared metadata in SharedRef's commits.
+const sharedFile = "metadata.json"
+
+// SharedEntry is the part of a worktree's metadata that is the same on every machine.
+// Paths, access times and archived changes are left out since they only make sense where
+// the worktree is checked out.
+// There is no parent ticket to share, since WorktreeEntry doesn't record one.
+type SharedEntry struct {
+	Ticket      string      `json:"ticket"`
+	Branch      string      `json:"branch"`
+	Created     time.Time   `json:"created"`
+	TicketInfo  *TicketInfo `json:"ticket_info,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	Notes       []Note      `json:"notes,omitempty"`
+	Workspace   string      `json:"workspace,omitempty"`
+}
+
+// SharedMetadata is the metadata stored in SharedRef.
+type SharedMetadata struct {
+	// Worktrees maps ticket IDs to their shared metadata.
+	Worktrees map[string]SharedEntry `json:"worktrees"`
+
+	// Mainline is the name of the mainline branch.
+	Mainline string `json:"mainline"`
+}
+
+// Share returns the shared part of the metadata.
+func Share(meta *Metadata) *SharedMetadata {
+	shared := &SharedMetadata{Worktrees: make(map[string]SharedEntry), Mainline: meta.Mainline}
+	for ticketID, entry := range meta.Worktrees {
+		shared.Worktrees[ticketID] = ShareEntry(entry)
+	}
+	return shared
+}
+
+// ShareEntry returns the shared part of a worktree's metadata.
+func ShareEntry(entry WorktreeEntry) SharedEntry {
+	return SharedEntry{
+		Ticket:      entry.Ticket,
+		Branch:      entry.Branch,
+		Created:     entry.Created,
+		TicketInfo:  entry.TicketInfo,
+		Description: entry.Description,
+		Labels:      entry.Labels,
+		Notes:       entry.Notes,
+		Workspace:   entry.Workspace,
+	}
+}
+
+// Apply copies the shared metadata into a worktree entry, keeping its machine-specific fields.
+func (s SharedEntry) Apply(entry *WorktreeEntry) {
+	entry.Ticket = s.Ticket
+	entry.Branch = s.Branch
+	entry.Created = s.Created
+	entry.TicketInfo = s.TicketInfo
+	entry.Description = s.Description
+	entry.Labels = s.Labels
+	entry.Notes = s.Notes
+	entry.Workspace = s.Workspace
+}
+
+// LoadShared reads the shared metadata committed to ref, and the commit it was read from.
+// If ref doesn't exist, it returns empty metadata and no commit.
+func LoadShared(repo *git.Repo, ref string) (*SharedMetadata, string, error) {
+	shared := &SharedMetadata{Worktrees: make(map[string]SharedEntry)}
+	data, ok, err := repo.ReadFile(ref, sharedFile)
+	if err != nil || !ok {
+		return shared, "", err
+	}
+	commit, err := repo.ResolveCommit(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := json.Unmarshal(data, shared); err != nil {
+		return nil, "", &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse shared metadata in %s: %v", ref, err)}
+	}
+	if shared.Worktrees == nil {
+		shared.Worktrees = make(map[string]SharedEntry)
+	}
+	return shared, commit, nil
+}
+
+// CommitShared commits the shared metadata on top of the given parents and points ref at
+// the new commit, which it returns.
+func CommitShared(repo *git.Repo, ref string, shared *SharedMetadata, message string, parents ...string) (string, error) {
+	data, err := json.MarshalIndent(shared, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal shared metadata: %w", err)
+	}
+
+	commit, err := repo.CommitFile(sharedFile, append(data, '\n'), message, parents...)
+	if err != nil {
+		return "", err
+	}
+	if err := repo.UpdateRef(ref, commit); err != nil {
+		return "", err
+	}
+	return commit, nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// keyStorage is the git config key selecting where the metadata is stored.
+const keyStorage = "tree.storage"
+
+// Storage backends, as set in tree.storage.
+const (
+	// StorageJSON keeps the metadata in worktree-metadata.json in the common git directory.
+	StorageJSON = "json"
+
+	// StorageGitConfig keeps the metadata in tree-ticket sections of the repository's git
+	// config, where plain git config can read and edit it.
+	StorageGitConfig = "gitconfig"
+
+	// StorageKV keeps the metadata in worktree-metadata.kv in the common git directory, one
+	// line per ticket.
+	StorageKV = "kv"
+)
+
+// DefaultStorage is the storage backend used when none is configured.
+const DefaultStorage = StorageJSON
+
+// StorageBackends are the names of the storage backends.
+var StorageBackends = []string{StorageJSON, StorageGitConfig, StorageKV}
+
+// Storage reads and writes a repository's worktree metadata.
+type Storage interface {
+	// Name returns the name of the backend, as set in tree.storage.
+	Name() string
+
+	// Path returns the path of the file the metadata is stored in.
+	Path() string
+
+	// Load reads the metadata. If none has been stored, it returns empty Metadata.
+	Load() (*Metadata, error)
+
+	// Save writes the metadata, replacing what was stored.
+	Save(meta *Metadata) error
+
+	// Clear removes the stored metadata.
+	Clear() error
+}
+
+// OpenStorage returns the storage backend configured in tree.storage for a repository.
+// An unknown backend is reported as ErrInvalidSettings.
+func OpenStorage(repoPath string) (Storage, error) {
+	output, err := git.NewRepo(repoPath).Run("config", "--get", keyStorage)
+	// git config exits with status 1 when the key isn't set
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read %s: %w", keyStorage, err)
+	}
+
+	name := strings.TrimSpace(output)
+	if name == "" {
+		name = DefaultStorage
+	}
+	return NewStorage(repoPath, name)
+}
+
+// NewStorage returns the named storage backend for a repository. An unknown backend is
+// reported as ErrInvalidSettings.
+func NewStorage(repoPath, name string) (Storage, error) {
+	gitDir, err := util.GitCommonDir(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git directory: %w", err)
+	}
+
+	switch name {
+	case StorageJSON:
+		return &jsonStorage{path: filepath.Join(gitDir, "worktree-metadata.json")}, nil
+	case StorageGitConfig:
+		return &gitConfigStorage{repo: git.NewRepo(repoPath), path: filepath.Join(gitDir, "config")}, nil
+	case StorageKV:
+		return &kvStorage{path: filepath.Join(gitDir, "worktree-metadata.kv")}, nil
+	}
+	return nil, NewError(ErrInvalidSettings, "", "%s %q must be one of %s", keyStorage, name, strings.Join(StorageBackends, ", "))
+}
+
+// SetStorage selects the storage backend in the repository's git config.
+func SetStorage(repoPath, name string) error {
+	if _, err := git.NewRepo(repoPath).Run("config", keyStorage, name); err != nil {
+		return fmt.Errorf("failed to write %s: %w", keyStorage, err)
+	}
+	return nil
+}
+
+// newMetadata returns empty Metadata.
+func newMetadata() *Metadata {
+	return &Metadata{Worktrees: make(map[string]WorktreeEntry)}
+}
+
+// jsonStorage stores the metadata as a single JSON document.
+type jsonStorage struct {
+	path string
+}
+
+// Name returns StorageJSON.
+func (s *jsonStorage) Name() string {
+	return StorageJSON
+}
+
+// Path returns the path of the JSON file.
+func (s *jsonStorage) Path() string {
+	return s.path
+}
+
+// Load reads and parses the JSON file.
+func (s *jsonStorage) Load() (*Metadata, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newMetadata(), nil
+		}
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, &Error{Kind: ErrCorrupt, Err: err, Message: fmt.Sprintf("failed to parse metadata: %v", err)}
+	}
+
+	if meta.Worktrees == nil {
+		meta.Worktrees = make(map[string]WorktreeEntry)
+	}
+
+	return &meta, nil
+}
+
+// Save writes the metadata to the JSON file, indented. The file is replaced in one step,
+// so readers never see a partly written file.
+func (s *jsonStorage) Save(meta *Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := writeAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the JSON file.
+func (s *jsonStorage) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove metadata: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic replaces the file at path with data in one step, by writing a temporary file
+// next to it and renaming it over the file. The file keeps its permissions, and a new
+// file is readable by everyone, like one written by os.WriteFile.
+func writeAtomic(path string, data []byte) error {
+	// Temporary files are only readable by their owner
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
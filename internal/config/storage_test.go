@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomicMode(t *testing.T) {
+	dir := t.TempDir()
+
+	// A new file is readable by everyone, not only by its owner like a temporary file
+	path := filepath.Join(dir, "worktree-metadata.json")
+	if err := writeAtomic(path, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("new file mode = %v, %v, want 0644", info.Mode().Perm(), err)
+	}
+
+	// An existing file keeps its permissions
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAtomic(path, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("replaced file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// workspacePrefix is the git config prefix of workspaces: tree.workspace.<name>.repo lists
+// the member repositories and tree.workspace.<name>.root is the directory ticket
+// directories are created in.
+const workspacePrefix = "tree.workspace."
+
+// Workspace is a named group of repositories whose worktrees for a ticket are created
+// together, in a directory shared by the ticket.
+type Workspace struct {
+	// Name is the name the workspace is configured under.
+	Name string
+
+	// Repos are the absolute paths of the member repositories, in the order configured.
+	Repos []string
+
+	// Root is the absolute path of the directory ticket directories are created in. If
+	// empty, the default location next to the first repository is used.
+	Root string
+}
+
+// LoadWorkspaces reads the workspaces configured in git config for dir, sorted by name.
+// Outside a repository only the global and system configuration is read, which is where
+// workspaces usually live since they span repositories.
+func LoadWorkspaces(dir string) ([]*Workspace, error) {
+	// --type=path expands a leading ~ in the paths
+	output, err := git.NewRepo(dir).Run("config", "--type=path", "--get-regexp", `^tree\.workspace\.`)
+	if err != nil && git.ExitCode(err) != 1 {
+		return nil, fmt.Errorf("failed to read workspaces: %w", err)
+	}
+
+	byName := make(map[string]*Workspace)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		rest, ok := strings.CutPrefix(key, workspacePrefix)
+		if !ok {
+			continue
+		}
+		// The name is a subsection, so it keeps its case and may contain dots
+		i := strings.LastIndex(rest, ".")
+		if i <= 0 {
+			continue
+		}
+		name, field := rest[:i], rest[i+1:]
+		ws := byName[name]
+		if ws == nil {
+			ws = &Workspace{Name: name}
+			byName[name] = ws
+		}
+		switch field {
+		case "repo":
+			ws.Repos = append(ws.Repos, value)
+		case "root":
+			ws.Root = value
+		}
+	}
+
+	workspaces := make([]*Workspace, 0, len(byName))
+	for _, ws := range byName {
+		workspaces = append(workspaces, ws)
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces, nil
+}
+
+// LoadWorkspace reads a workspace from git config for dir. A workspace that isn't
+// configured, or is configured wrongly, is reported as ErrInvalidSettings.
+func LoadWorkspace(dir, name string) (*Workspace, error) {
+	workspaces, err := LoadWorkspaces(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, ws := range workspaces {
+		if ws.Name == name {
+			if err := ws.validate(); err != nil {
+				return nil, &Error{Kind: ErrInvalidSettings, Err: err}
+			}
+			return ws, nil
+		}
+	}
+	return nil, NewError(ErrInvalidSettings, "", "workspace %s not found, add repositories to it with: git config --global --add %s%s.repo <path>", name, workspacePrefix, name)
+}
+
+// validate returns the first problem with the workspace.
+func (w *Workspace) validate() error {
+	if len(w.Repos) == 0 {
+		return fmt.Errorf("workspace %s has no repositories", w.Name)
+	}
+	for _, repo := range w.Repos {
+		if !filepath.IsAbs(repo) {
+			return fmt.Errorf("workspace %s repository %q must be an absolute path", w.Name, repo)
+		}
+	}
+	if w.Root != "" && !filepath.IsAbs(w.Root) {
+		return fmt.Errorf("workspace %s root %q must be an absolute path", w.Name, w.Root)
+	}
+	return nil
+}
+
+// RootPath returns the directory ticket directories are created in. It defaults to
+// <parent>/workspaces/<name>, where <parent> contains the first repository.
+func (w *Workspace) RootPath() string {
+	if w.Root != "" {
+		return w.Root
+	}
+	return filepath.Join(filepath.Dir(w.Repos[0]), "workspaces", w.Name)
+}
+
+// TicketPath returns the directory shared by a ticket's worktrees; each is in a
+// subdirectory named after its repository.
+func (w *Workspace) TicketPath(ticketID string) string {
+	return filepath.Join(w.RootPath(), ticketID)
+}
@@ -0,0 +1,123 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Commit is a one-line summary of a commit.
+type Commit struct {
+	// Hash is the abbreviated commit hash.
+	Hash string
+
+	// Subject is the first line of the commit message.
+	Subject string
+}
+
+// StashEntry is an entry in the stash list.
+type StashEntry struct {
+	// Ref is the stash reference (e.g., "stash@{0}").
+	Ref string
+
+	// Branch is the branch the stash was created on.
+	Branch string
+
+	// Message is the stash message.
+	Message string
+}
+
+// CommitsBetween returns the commits reachable from head but not from base, newest first.
+func (r *Repo) CommitsBetween(base, head string) ([]Commit, error) {
+	output, err := r.Run("log", "--format=%h %s", base+".."+head, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		hash, subject, _ := strings.Cut(line, " ")
+		commits = append(commits, Commit{Hash: hash, Subject: subject})
+	}
+	return commits, nil
+}
+
+// CountCommitsNotIn returns the number of commits reachable from head but from none of
+// refs.
+func (r *Repo) CountCommitsNotIn(head string, refs ...string) (int, error) {
+	args := append([]string{"rev-list", "--count", head, "--not"}, refs...)
+	output, err := r.Run(args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits: %w", err)
+	}
+
+	var count int
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d", &count); err != nil {
+		return 0, fmt.Errorf("failed to parse commit count: %w", err)
+	}
+	return count, nil
+}
+
+// DiffStat returns the diffstat of head against its merge-base with base.
+func (r *Repo) DiffStat(base, head string) (string, error) {
+	output, err := r.Run("diff", "--stat", base+"..."+head, "--")
+	if err != nil {
+		return "", fmt.Errorf("failed to get diffstat: %w", err)
+	}
+	return output, nil
+}
+
+// StashList returns the entries in the stash list. The stash is shared by all worktrees.
+func (r *Repo) StashList() ([]StashEntry, error) {
+	output, err := r.Run("stash", "list", "--format=%gd%x00%gs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stash entries: %w", err)
+	}
+
+	var entries []StashEntry
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		ref, subject, ok := strings.Cut(line, "\x00")
+		if !ok {
+			continue
+		}
+
+		// Subjects look like "WIP on <branch>: <commit>" or "On <branch>: <message>"
+		entry := StashEntry{Ref: ref, Message: subject}
+		rest, ok := strings.CutPrefix(subject, "WIP on ")
+		if !ok {
+			rest, ok = strings.CutPrefix(subject, "On ")
+		}
+		if ok {
+			if branch, message, found := strings.Cut(rest, ": "); found {
+				entry.Branch, entry.Message = branch, message
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// MergeConflicts predicts the files that would conflict when merging theirs into ours,
+// using git merge-tree so that no working tree or index is touched.
+func (r *Repo) MergeConflicts(ours, theirs string) ([]string, error) {
+	output, err := r.Run("merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs)
+	if err != nil {
+		// merge-tree exits with status 1 when the merge has conflicts
+		if ExitCode(err) != 1 {
+			return nil, fmt.Errorf("git merge-tree failed: %w", err)
+		}
+	}
+
+	// The first line is the resulting tree, followed by the conflicted files
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	var conflicts []string
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+		conflicts = append(conflicts, line)
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FakeRunner is a Runner for unit tests. It records every command and replies with
+// canned results instead of running git.
+type FakeRunner struct {
+	mu    sync.Mutex
+	calls []Command
+	stubs []fakeStub
+}
+
+// fakeStub is a canned result for commands starting with args.
+type fakeStub struct {
+	args   []string
+	result Result
+}
+
+// Reply makes commands whose arguments start with args return result. If several
+// replies match a command, the one with the longest args wins.
+func (f *FakeRunner) Reply(result Result, args ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stubs = append(f.stubs, fakeStub{args: args, result: result})
+}
+
+// On makes commands whose arguments start with args succeed and print stdout.
+func (f *FakeRunner) On(stdout string, args ...string) {
+	f.Reply(Result{Stdout: []byte(stdout)}, args...)
+}
+
+// Fail makes commands whose arguments start with args exit with a non-zero status and print stderr.
+func (f *FakeRunner) Fail(exitCode int, stderr string, args ...string) {
+	f.Reply(Result{Stderr: []byte(stderr), ExitCode: exitCode}, args...)
+}
+
+// Run records the command and returns the matching reply. Commands without a reply fail,
+// so tests notice unexpected git invocations.
+func (f *FakeRunner) Run(ctx context.Context, c Command) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, c)
+
+	if err := ctx.Err(); err != nil {
+		return Result{ExitCode: -1}, err
+	}
+
+	var match *fakeStub
+	for i, stub := range f.stubs {
+		if len(stub.args) <= len(c.Args) && slices.Equal(stub.args, c.Args[:len(stub.args)]) &&
+			(match == nil || len(stub.args) >= len(match.args)) {
+			match = &f.stubs[i]
+		}
+	}
+	if match == nil {
+		return Result{ExitCode: -1}, fmt.Errorf("unexpected git command: %s", c)
+	}
+
+	if match.result.ExitCode != 0 {
+		return match.result, &ExitError{Args: c.Args, ExitCode: match.result.ExitCode, Stdout: match.result.Stdout, Stderr: match.result.Stderr}
+	}
+	return match.result, nil
+}
+
+// Calls returns the commands run so far, in order.
+func (f *FakeRunner) Calls() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// Commands returns the arguments of the commands run so far, each joined with spaces.
+func (f *FakeRunner) Commands() []string {
+	var commands []string
+	for _, c := range f.Calls() {
+		commands = append(commands, strings.Join(c.Args, " "))
+	}
+	return commands
+}
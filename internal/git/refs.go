@@ -0,0 +1,93 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// runInput runs git in the repository with stdin as its standard input and returns its
+// trimmed standard output.
+func (r *Repo) runInput(stdin io.Reader, args ...string) (string, error) {
+	runner := r.Runner
+	if runner == nil {
+		runner = DefaultRunner
+	}
+	result, err := runner.Run(context.Background(), Command{Dir: r.Path, Args: args, Stdin: stdin})
+	return strings.TrimSpace(string(result.Stdout)), err
+}
+
+// ReadFile returns the contents of the file at path in the tree of the commit rev points
+// to. It returns false if rev doesn't exist.
+func (r *Repo) ReadFile(rev, path string) ([]byte, bool, error) {
+	if _, err := r.ResolveCommit(rev); err != nil {
+		return nil, false, nil
+	}
+	output, err := r.Run("cat-file", "blob", rev+":"+path)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read %s in %s: %w", path, rev, err)
+	}
+	return []byte(output), true, nil
+}
+
+// CommitFile creates a commit whose tree holds a single file, with the given parents, and
+// returns its hash. No branch or index is touched; point a ref at the commit to keep it.
+func (r *Repo) CommitFile(name string, content []byte, message string, parents ...string) (string, error) {
+	blob, err := r.runInput(strings.NewReader(string(content)), "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	tree, err := r.runInput(strings.NewReader(fmt.Sprintf("100644 blob %s\t%s\n", blob, name)), "mktree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %w", err)
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	commit, err := r.runInput(nil, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", name, err)
+	}
+	return commit, nil
+}
+
+// FetchRef fetches ref from the remote into dst, replacing whatever dst pointed to. It
+// returns false, leaving dst alone, if the remote doesn't have ref.
+func (r *Repo) FetchRef(ref, dst string) (bool, error) {
+	if _, err := r.Run("ls-remote", "--exit-code", r.Remote, ref); err != nil {
+		// ls-remote exits with status 2 when no matching refs are found
+		if ExitCode(err) == 2 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git ls-remote failed: %w", err)
+	}
+	if _, err := r.Run("fetch", r.Remote, "+"+ref+":"+dst); err != nil {
+		return false, fmt.Errorf("git fetch failed: %w", err)
+	}
+	return true, nil
+}
+
+// PushRef pushes ref to the same name on the remote. It fails if the remote's ref has
+// commits that ref doesn't.
+func (r *Repo) PushRef(ref string) error {
+	if _, err := r.Run("push", r.Remote, ref+":"+ref); err != nil {
+		return fmt.Errorf("git push failed: %w", err)
+	}
+	return nil
+}
+
+// MergeBase returns the best common ancestor of two commits, or "" if they have none.
+func (r *Repo) MergeBase(a, b string) (string, error) {
+	output, err := r.Run("merge-base", a, b)
+	if err != nil {
+		// merge-base exits with status 1 when there is no common ancestor
+		if ExitCode(err) == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git merge-base failed: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
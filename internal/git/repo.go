@@ -2,46 +2,79 @@
 package git
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultRemote is the remote used when none is configured.
+const DefaultRemote = "origin"
+
 // Repo represents a git repository.
 type Repo struct {
 	// Path is the absolute path to the repository.
 	Path string
+
+	// Remote is the name of the remote to fetch from and compare against.
+	Remote string
+
+	// Runner runs the repository's git commands.
+	Runner Runner
 }
 
-// NewRepo creates a new Repo instance.
+// NewRepo creates a new Repo instance using the default remote and runner.
 func NewRepo(path string) *Repo {
-	return &Repo{Path: path}
+	return &Repo{Path: path, Remote: DefaultRemote, Runner: DefaultRunner}
+}
+
+// WithPath returns a Repo for the repository or worktree at path that runs git the same
+// way and uses the same remote.
+func (r *Repo) WithPath(path string) *Repo {
+	return &Repo{Path: path, Remote: r.Remote, Runner: r.Runner}
+}
+
+// Run runs git in the repository and returns its standard output.
+// If git exits with a non-zero status, the error is an *ExitError.
+func (r *Repo) Run(args ...string) (string, error) {
+	runner := r.Runner
+	if runner == nil {
+		runner = DefaultRunner
+	}
+	result, err := runner.Run(context.Background(), Command{Dir: r.Path, Args: args})
+	return string(result.Stdout), err
+}
+
+// RemoteHead returns the branch the remote's HEAD points to (e.g., "main" for origin/HEAD -> origin/main).
+// Returns an error if the remote HEAD is not set locally.
+func (r *Repo) RemoteHead() (string, error) {
+	output, err := r.Run("rev-parse", "--abbrev-ref", r.Remote+"/HEAD")
+	if err != nil {
+		return "", fmt.Errorf("%s/HEAD is not set", r.Remote)
+	}
+
+	// Format is typically "origin/main" or "origin/master"
+	branch, found := strings.CutPrefix(strings.TrimSpace(output), r.Remote+"/")
+	if !found || branch == "" || branch == "HEAD" {
+		return "", fmt.Errorf("%s/HEAD is not set", r.Remote)
+	}
+	return branch, nil
 }
 
 // DetectMainline detects the mainline branch name from the remote.
-// It attempts to determine this by checking origin's HEAD, then falls back to common names.
+// It attempts to determine this by checking the remote's HEAD, then falls back to common names.
 func (r *Repo) DetectMainline() (string, error) {
-	// Try to detect from origin HEAD
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "origin/HEAD")
-	cmd.Dir = r.Path
-	output, err := cmd.Output()
-	if err == nil {
-		branch := strings.TrimSpace(string(output))
-		if branch != "" {
-			// Format is typically "origin/main" or "origin/master"
-			parts := strings.Split(branch, "/")
-			if len(parts) == 2 {
-				return parts[1], nil
-			}
-		}
+	// Try to detect from the remote HEAD
+	if branch, err := r.RemoteHead(); err == nil {
+		return branch, nil
 	}
 
 	// Fall back to checking common branch names
 	for _, branch := range []string{"main", "master", "develop"} {
-		cmd := exec.Command("git", "rev-parse", "--verify", "origin/"+branch)
-		cmd.Dir = r.Path
-		if err := cmd.Run(); err == nil {
+		if _, err := r.Run("rev-parse", "--verify", r.Remote+"/"+branch); err == nil {
 			return branch, nil
 		}
 	}
@@ -49,60 +82,48 @@ func (r *Repo) DetectMainline() (string, error) {
 	return "", fmt.Errorf("could not detect mainline branch")
 }
 
+// HasRemote returns true if a remote with the given name is configured.
+func (r *Repo) HasRemote(name string) bool {
+	_, err := r.Run("remote", "get-url", name)
+	return err == nil
+}
+
 // Fetch fetches the latest changes from the remote.
 func (r *Repo) Fetch() error {
-	cmd := exec.Command("git", "fetch", "origin")
-	cmd.Dir = r.Path
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git fetch failed: %w\n%s", err, output)
+	if _, err := r.Run("fetch", r.Remote); err != nil {
+		return fmt.Errorf("git fetch failed: %w", err)
 	}
 	return nil
 }
 
-// GetStatus returns the porcelain status output for the repository.
-func (r *Repo) GetStatus() (string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = r.Path
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("git status failed: %w", err)
-	}
-	return string(output), nil
-}
-
-// IsClean returns true if the repository has no uncommitted changes.
+// IsClean returns true if the repository has no uncommitted changes or untracked files.
 func (r *Repo) IsClean() (bool, error) {
-	status, err := r.GetStatus()
+	status, err := r.Status()
 	if err != nil {
 		return false, err
 	}
-	return strings.TrimSpace(status) == "", nil
+	return status.Clean(), nil
 }
 
 // GetCurrentBranch returns the name of the current branch.
 func (r *Repo) GetCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = r.Path
-	output, err := cmd.Output()
+	output, err := r.Run("rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(output), nil
 }
 
 // GetCommitCount returns the number of commits ahead and behind between branch and target.
 // Returns (ahead, behind, error).
 func (r *Repo) GetCommitCount(branch, target string) (int, int, error) {
-	cmd := exec.Command("git", "rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s", branch, target))
-	cmd.Dir = r.Path
-	output, err := cmd.Output()
+	output, err := r.Run("rev-list", "--left-right", "--count", fmt.Sprintf("%s...%s", branch, target))
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to get commit count: %w", err)
 	}
 
 	var ahead, behind int
-	_, err = fmt.Sscanf(strings.TrimSpace(string(output)), "%d\t%d", &ahead, &behind)
+	_, err = fmt.Sscanf(strings.TrimSpace(output), "%d\t%d", &ahead, &behind)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to parse commit count: %w", err)
 	}
@@ -112,25 +133,240 @@ func (r *Repo) GetCommitCount(branch, target string) (int, int, error) {
 
 // DeleteBranch deletes a local branch.
 func (r *Repo) DeleteBranch(branch string) error {
-	cmd := exec.Command("git", "branch", "-D", branch)
-	cmd.Dir = r.Path
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to delete branch: %w\n%s", err, output)
+	if _, err := r.Run("branch", "-D", branch); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
 	}
 	return nil
 }
 
 // Rebase rebases the current branch onto the target branch.
 func (r *Repo) Rebase(target string) error {
-	cmd := exec.Command("git", "rebase", target)
-	cmd.Dir = r.Path
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+	output, err := r.Run("rebase", target)
+	if err != nil {
+		// Conflicts are reported on stdout
+		if output = strings.TrimSpace(output); output != "" {
+			return fmt.Errorf("rebase failed: %w\n%s", err, output)
+		}
+		return fmt.Errorf("rebase failed: %w", err)
+	}
+	return nil
+}
+
+// RebaseAbort aborts a rebase in progress and restores the original branch.
+func (r *Repo) RebaseAbort() error {
+	if _, err := r.Run("rebase", "--abort"); err != nil {
+		return fmt.Errorf("git rebase --abort failed: %w", err)
+	}
+	return nil
+}
+
+// ResolveCommit returns the full commit hash that ref points to.
+func (r *Repo) ResolveCommit(ref string) (string, error) {
+	output, err := r.Run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
 	if err != nil {
-		return fmt.Errorf("rebase failed: %w\n%s%s", err, stdout.String(), stderr.String())
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// BranchExists returns true if a local branch with the given name exists.
+func (r *Repo) BranchExists(branch string) bool {
+	_, err := r.Run("show-ref", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// Checkout switches the working tree to an existing branch. It fails, leaving the working
+// tree alone, if the branch is checked out in another worktree or local changes would be
+// overwritten.
+func (r *Repo) Checkout(branch string) error {
+	if _, err := r.Run("checkout", "--quiet", branch); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", branch, err)
+	}
+	return nil
+}
+
+// ResetHard resets the current branch, index and working tree to the given commit.
+func (r *Repo) ResetHard(commit string) error {
+	if _, err := r.Run("reset", "--hard", commit); err != nil {
+		return fmt.Errorf("failed to reset: %w", err)
+	}
+	return nil
+}
+
+// RemoteBranchExists checks whether a branch exists on the remote.
+// This contacts the remote, so it returns an error if the remote is unreachable.
+func (r *Repo) RemoteBranchExists(branch string) (bool, error) {
+	if _, err := r.Run("ls-remote", "--exit-code", "--heads", r.Remote, branch); err != nil {
+		// ls-remote exits with status 2 when no matching refs are found
+		if ExitCode(err) == 2 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git ls-remote failed: %w", err)
+	}
+	return true, nil
+}
+
+// CloneBare clones url into a bare repository at path and configures it so that
+// remote-tracking branches are fetched, as they are for a regular clone. A relative
+// url or path is resolved against dir.
+func CloneBare(dir, url, path string) (*Repo, error) {
+	if _, err := NewRepo(dir).Run("clone", "--bare", url, path); err != nil {
+		return nil, fmt.Errorf("git clone failed: %w", err)
+	}
+
+	repo := NewRepo(path)
+	if err := repo.SetConfig("remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// SetConfig sets a configuration value in the repository's config.
+func (r *Repo) SetConfig(key, value string) error {
+	if _, err := r.Run("config", key, value); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+// SetRemoteHead sets the remote's HEAD (e.g., origin/HEAD) to the remote's default branch.
+func (r *Repo) SetRemoteHead() error {
+	if _, err := r.Run("remote", "set-head", r.Remote, "--auto"); err != nil {
+		return fmt.Errorf("failed to set %s/HEAD: %w", r.Remote, err)
+	}
+	return nil
+}
+
+// SetUpstream sets the upstream of a local branch.
+func (r *Repo) SetUpstream(branch, upstream string) error {
+	if _, err := r.Run("branch", "--set-upstream-to="+upstream, branch); err != nil {
+		return fmt.Errorf("failed to set upstream of %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Push pushes a branch to the remote and sets it as the branch's upstream.
+func (r *Repo) Push(branch string) error {
+	if _, err := r.Run("push", "--set-upstream", r.Remote, branch); err != nil {
+		return fmt.Errorf("git push failed: %w", err)
+	}
+	return nil
+}
+
+// LastCommitTime returns the author date of the newest commit on ref that isn't on base,
+// or the zero time if ref has no commits of its own. Unlike the committer date, the
+// author date isn't changed by rebasing, so it tells when the work was done.
+func (r *Repo) LastCommitTime(ref, base string) (time.Time, error) {
+	output, err := r.Run("log", "-1", "--format=%at", base+".."+ref, "--")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last commit time: %w", err)
+	}
+	if strings.TrimSpace(output) == "" {
+		return time.Time{}, nil
+	}
+
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse last commit time: %w", err)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// LastModified returns the most recent modification time of the files with uncommitted
+// changes in the working tree, including untracked files. It returns the zero time if
+// the working tree is clean.
+func (r *Repo) LastModified() (time.Time, error) {
+	status, err := r.status("--untracked-files=all")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, f := range status.Files {
+		info, err := os.Lstat(filepath.Join(r.Path, f.Path))
+		if err != nil {
+			// Deleted files have no modification time
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// IgnoredFiles returns the paths of the ignored files in the working tree, which StashAll
+// doesn't save. A directory whose contents are all ignored is returned as one path.
+func (r *Repo) IgnoredFiles() ([]string, error) {
+	status, err := r.status("--ignored")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, f := range status.Ignored() {
+		paths = append(paths, f.Path)
+	}
+	return paths, nil
+}
+
+// StashAll stashes all uncommitted changes, including untracked files, and returns the
+// stash commit, or "" if there was nothing to stash. The entry is dropped from the stash
+// list, so the caller must keep the commit alive with a ref.
+func (r *Repo) StashAll(message string) (string, error) {
+	clean, err := r.IsClean()
+	if err != nil {
+		return "", err
+	}
+	if clean {
+		return "", nil
+	}
+
+	if _, err := r.Run("stash", "push", "--include-untracked", "--message", message); err != nil {
+		return "", fmt.Errorf("git stash failed: %w", err)
+	}
+
+	commit, err := r.ResolveCommit("refs/stash")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := r.Run("stash", "drop", "--quiet"); err != nil {
+		return "", fmt.Errorf("git stash drop failed: %w", err)
+	}
+
+	return commit, nil
+}
+
+// StashApply applies a stash commit, including its untracked files, and restores the index.
+func (r *Repo) StashApply(commit string) error {
+	if _, err := r.Run("stash", "apply", "--index", commit); err != nil {
+		return fmt.Errorf("git stash apply failed: %w", err)
+	}
+	return nil
+}
+
+// CreateBranch creates a branch at a commit without checking it out.
+func (r *Repo) CreateBranch(branch, commit string) error {
+	if _, err := r.Run("branch", branch, commit); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+	return nil
+}
+
+// UpdateRef points a ref at a commit, creating it if needed.
+func (r *Repo) UpdateRef(ref, commit string) error {
+	if _, err := r.Run("update-ref", ref, commit); err != nil {
+		return fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+	return nil
+}
+
+// DeleteRef deletes a ref.
+func (r *Repo) DeleteRef(ref string) error {
+	if _, err := r.Run("update-ref", "-d", ref); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", ref, err)
 	}
 	return nil
 }
@@ -0,0 +1,243 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is a single git invocation.
+type Command struct {
+	// Dir is the directory to run git in. If empty, the current directory is used.
+	Dir string
+
+	// Args are the arguments passed to git, starting with the subcommand.
+	Args []string
+
+	// Env holds extra environment variables ("KEY=value") added to the inherited environment.
+	Env []string
+
+	// Stdin is the command's standard input. If nil, git reads from the null device.
+	Stdin io.Reader
+}
+
+// String returns the command as it could be typed into a shell.
+func (c Command) String() string {
+	words := []string{"git"}
+	if c.Dir != "" {
+		words = append(words, "-C", quoteArg(c.Dir))
+	}
+	for _, arg := range c.Args {
+		words = append(words, quoteArg(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// quoteArg quotes an argument if it contains anything a shell would interpret.
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:@^~+,%", r))
+	}) {
+		return arg
+	}
+	return strconv.Quote(arg)
+}
+
+// Result is the outcome of a git invocation.
+type Result struct {
+	// Stdout and Stderr are the captured output streams.
+	Stdout []byte
+	Stderr []byte
+
+	// ExitCode is the exit status, or -1 if git didn't run to completion.
+	ExitCode int
+}
+
+// ExitError is returned when git exits with a non-zero status.
+type ExitError struct {
+	// Args are the arguments git was run with.
+	Args []string
+
+	// ExitCode is the exit status.
+	ExitCode int
+
+	// Stdout and Stderr are the captured output streams.
+	Stdout []byte
+	Stderr []byte
+}
+
+// Error describes the failure, including what git printed to stderr.
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("git %s exited with status %d", subcommand(e.Args), e.ExitCode)
+	stderr := strings.TrimSpace(string(e.Stderr))
+	switch {
+	case stderr == "":
+		return msg
+	case strings.Contains(stderr, "\n"):
+		return msg + "\n" + stderr
+	default:
+		return msg + ": " + stderr
+	}
+}
+
+// Errors that an *ExitError matches with errors.Is, according to what git printed.
+var (
+	// ErrNotRepository means git was run outside a repository.
+	ErrNotRepository = errors.New("not a git repository")
+
+	// ErrDirty means git refused to run because of uncommitted changes.
+	ErrDirty = errors.New("uncommitted changes")
+
+	// ErrConflict means a rebase, merge or stash apply stopped on conflicts.
+	ErrConflict = errors.New("conflicts")
+)
+
+// exitErrorMessages are the messages, in git's stdout or stderr, that identify each of the
+// errors above.
+var exitErrorMessages = map[error][]string{
+	ErrNotRepository: {"not a git repository"},
+	ErrDirty:         {"contains modified or untracked files", "You have unstaged changes", "Your local changes to the following files would be overwritten"},
+	ErrConflict:      {"CONFLICT (", "could not apply", "Resolve all conflicts"},
+}
+
+// Is reports whether git's output identifies the failure as target, one of the errors above.
+func (e *ExitError) Is(target error) bool {
+	for _, msg := range exitErrorMessages[target] {
+		if bytes.Contains(e.Stderr, []byte(msg)) || bytes.Contains(e.Stdout, []byte(msg)) {
+			return true
+		}
+	}
+	return false
+}
+
+// subcommand returns the git subcommand in args, skipping global options such as -c.
+func subcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case !strings.HasPrefix(args[i], "-"):
+			return args[i]
+		}
+	}
+	return ""
+}
+
+// ExitCode returns the exit status of a failed git command, or -1 if err isn't an ExitError.
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode
+	}
+	return -1
+}
+
+// Runner runs git commands. Every git invocation in git-tree goes through a Runner, so
+// tests can substitute a FakeRunner and tracing can be enabled in one place.
+type Runner interface {
+	// Run runs a git command and waits for it to finish. If git exits with a non-zero
+	// status, the returned error is an *ExitError and the result holds the captured output.
+	Run(ctx context.Context, c Command) (Result, error)
+}
+
+// DefaultRunner is the runner used by repositories created with NewRepo.
+var DefaultRunner Runner = &ExecRunner{}
+
+// ExecRunner runs git as a child process.
+type ExecRunner struct {
+	// Trace, if not nil, receives a line for every command with its duration and exit status.
+	Trace io.Writer
+
+	// mu serializes trace output.
+	mu sync.Mutex
+}
+
+// Run runs the git executable found in PATH.
+func (e *ExecRunner) Run(ctx context.Context, c Command) (Result, error) {
+	cmd := exec.CommandContext(ctx, "git", c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start)
+
+	result := Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			result.ExitCode = -1
+			e.trace(c, elapsed, "failed")
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return result, fmt.Errorf("failed to run %s: %w", c, err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+		e.trace(c, elapsed, fmt.Sprintf("exit %d", result.ExitCode))
+		return result, &ExitError{Args: c.Args, ExitCode: result.ExitCode, Stdout: result.Stdout, Stderr: result.Stderr}
+	}
+
+	e.trace(c, elapsed, "")
+	return result, nil
+}
+
+// trace writes a trace line for a finished command, if tracing is enabled.
+func (e *ExecRunner) trace(c Command, elapsed time.Duration, outcome string) {
+	if e.Trace == nil {
+		return
+	}
+	line := fmt.Sprintf("trace: %8s  %s", elapsed.Round(100*time.Microsecond), c)
+	if outcome != "" {
+		line += "  (" + outcome + ")"
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintln(e.Trace, line)
+}
+
+// OpenTrace returns the trace destination for a GIT_TREE_TRACE value, following the
+// conventions of GIT_TRACE: "1", "2" or "true" trace to stderr, an absolute path appends
+// to that file, and an empty value, "0" or "false" disables tracing (nil writer). Closing
+// the destination closes the file, but leaves stderr open.
+func OpenTrace(value string, stderr io.Writer) (io.WriteCloser, error) {
+	switch strings.ToLower(value) {
+	case "", "0", "false":
+		return nil, nil
+	case "1", "2", "true":
+		return nopCloser{stderr}, nil
+	}
+	if !filepath.IsAbs(value) {
+		return nil, fmt.Errorf("GIT_TREE_TRACE must be 1, true or an absolute path, not %q", value)
+	}
+	f, err := os.OpenFile(value, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return f, nil
+}
+
+// nopCloser is a writer whose Close does nothing.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,251 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecRunner(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	var trace bytes.Buffer
+	runner := &ExecRunner{Trace: &trace}
+	ctx := context.Background()
+
+	// stdin is passed through and stdout captured
+	result, err := runner.Run(ctx, Command{Dir: dir, Args: []string{"hash-object", "--stdin"}, Stdin: strings.NewReader("hello\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(result.Stdout)); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("hash-object = %q", got)
+	}
+
+	// extra environment variables are added to the inherited environment
+	result, err = runner.Run(ctx, Command{Dir: dir, Args: []string{"var", "GIT_AUTHOR_IDENT"}, Env: []string{"GIT_AUTHOR_NAME=Trace Test", "GIT_AUTHOR_EMAIL=trace@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(result.Stdout), "Trace Test <trace@example.com>") {
+		t.Errorf("GIT_AUTHOR_IDENT = %q", result.Stdout)
+	}
+
+	// a non-zero exit is an ExitError carrying stderr
+	result, err = runner.Run(ctx, Command{Dir: dir, Args: []string{"rev-parse", "--verify", "missing"}})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("error = %v, want *ExitError", err)
+	}
+	if exitErr.ExitCode != 128 || result.ExitCode != 128 || ExitCode(err) != 128 {
+		t.Errorf("exit code = %d, result %d", exitErr.ExitCode, result.ExitCode)
+	}
+	if !strings.HasPrefix(err.Error(), "git rev-parse exited with status 128: ") || len(exitErr.Stderr) == 0 {
+		t.Errorf("error = %q", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("trace has %d lines, want 3:\n%s", len(lines), trace.String())
+	}
+	if !strings.Contains(lines[0], "git -C "+dir+" hash-object --stdin") {
+		t.Errorf("trace line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], "rev-parse --verify missing  (exit 128)") {
+		t.Errorf("trace line = %q", lines[2])
+	}
+}
+
+func TestExecRunnerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&ExecRunner{}).Run(ctx, Command{Args: []string{"version"}})
+	if !errors.Is(err, context.Canceled) || ExitCode(err) != -1 {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	c := Command{Dir: "/tmp/my repo", Args: []string{"log", "--format=%h %s", "main..topic", ""}}
+	want := `git -C "/tmp/my repo" log "--format=%h %s" main..topic ""`
+	if got := c.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestExitErrorIs(t *testing.T) {
+	tests := []struct {
+		stdout, stderr string
+		want           error
+	}{
+		{"", "fatal: not a git repository (or any of the parent directories): .git", ErrNotRepository},
+		{"", "fatal: '/tmp/wt' contains modified or untracked files, use --force to delete it", ErrDirty},
+		{"", "error: cannot rebase: You have unstaged changes.", ErrDirty},
+		{"CONFLICT (content): Merge conflict in README.md", "error: could not apply 1234567... Change", ErrConflict},
+		{"", "fatal: invalid reference: missing", nil},
+	}
+	for _, tt := range tests {
+		err := error(&ExitError{Args: []string{"rebase"}, ExitCode: 1, Stdout: []byte(tt.stdout), Stderr: []byte(tt.stderr)})
+		for _, target := range []error{ErrNotRepository, ErrDirty, ErrConflict} {
+			if got := errors.Is(fmt.Errorf("wrapped: %w", err), target); got != (target == tt.want) {
+				t.Errorf("%q: errors.Is(%v) = %v", tt.stderr, target, got)
+			}
+		}
+	}
+}
+
+func TestOpenTrace(t *testing.T) {
+	for _, value := range []string{"", "0", "false"} {
+		if w, err := OpenTrace(value, os.Stderr); w != nil || err != nil {
+			t.Errorf("OpenTrace(%q) = %v, %v, want disabled", value, w, err)
+		}
+	}
+	if w, err := OpenTrace("1", os.Stderr); w == nil || err != nil {
+		t.Errorf("OpenTrace(1) = %v, %v, want stderr", w, err)
+	}
+	if _, err := OpenTrace("relative.log", os.Stderr); err == nil {
+		t.Error("OpenTrace accepted a relative path")
+	}
+	w, err := OpenTrace(filepath.Join(t.TempDir(), "trace.log"), os.Stderr)
+	if w == nil || err != nil {
+		t.Fatalf("OpenTrace(file) = %v, %v", w, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("closing trace file: %v", err)
+	}
+}
+
+// fakeRepo returns a repository whose git commands are answered by a FakeRunner.
+func fakeRepo() (*Repo, *FakeRunner) {
+	fake := &FakeRunner{}
+	repo := NewRepo("/repo")
+	repo.Runner = fake
+	return repo, fake
+}
+
+func TestFakeRunner(t *testing.T) {
+	repo, fake := fakeRepo()
+	fake.On("general\n", "rev-parse")
+	fake.On("specific\n", "rev-parse", "--abbrev-ref")
+
+	if out, _ := repo.Run("rev-parse", "--abbrev-ref", "HEAD"); out != "specific\n" {
+		t.Errorf("longest match = %q", out)
+	}
+	if out, _ := repo.Run("rev-parse", "HEAD"); out != "general\n" {
+		t.Errorf("prefix match = %q", out)
+	}
+	if _, err := repo.Run("push"); err == nil || !strings.Contains(err.Error(), "unexpected git command") {
+		t.Errorf("unstubbed command error = %v", err)
+	}
+
+	want := []string{"rev-parse --abbrev-ref HEAD", "rev-parse HEAD", "push"}
+	if got := fake.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands() = %q, want %q", got, want)
+	}
+	for _, c := range fake.Calls() {
+		if c.Dir != "/repo" {
+			t.Errorf("command ran in %q", c.Dir)
+		}
+	}
+}
+
+func TestRemoteBranchExists(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		want     bool
+		wantErr  bool
+	}{
+		{exitCode: 0, want: true},
+		{exitCode: 2, want: false},
+		{exitCode: 128, wantErr: true},
+	}
+	for _, tt := range tests {
+		repo, fake := fakeRepo()
+		if tt.exitCode == 0 {
+			fake.On("abc\trefs/heads/topic\n", "ls-remote")
+		} else {
+			fake.Fail(tt.exitCode, "fatal: unable to access remote", "ls-remote")
+		}
+
+		got, err := repo.RemoteBranchExists("topic")
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("exit %d: RemoteBranchExists = %v, %v", tt.exitCode, got, err)
+		}
+		if tt.wantErr && !strings.Contains(err.Error(), "unable to access remote") {
+			t.Errorf("error %q is missing stderr", err)
+		}
+		if got := fake.Commands(); got[0] != "ls-remote --exit-code --heads origin topic" {
+			t.Errorf("ran %q", got)
+		}
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	repo, fake := fakeRepo()
+	fake.Reply(Result{Stdout: []byte("4b825dc\na.txt\nb.txt\n\n"), ExitCode: 1}, "merge-tree")
+
+	conflicts, err := repo.MergeConflicts("topic", "origin/main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("conflicts = %q, want %q", conflicts, want)
+	}
+}
+
+func TestGetCommitCount(t *testing.T) {
+	repo, fake := fakeRepo()
+	fake.On("3\t7\n", "rev-list")
+
+	ahead, behind, err := repo.GetCommitCount("topic", "origin/main")
+	if err != nil || ahead != 3 || behind != 7 {
+		t.Errorf("GetCommitCount = %d, %d, %v", ahead, behind, err)
+	}
+	if got := fake.Commands(); got[0] != "rev-list --left-right --count topic...origin/main" {
+		t.Errorf("ran %q", got)
+	}
+}
+
+func TestListWorktrees(t *testing.T) {
+	repo, fake := fakeRepo()
+	fake.On(`worktree /repo
+bare
+
+worktree /wt/PROJ-1
+HEAD 1111111111111111111111111111111111111111
+branch refs/heads/feature/PROJ-1
+
+worktree /wt/detached
+HEAD 2222222222222222222222222222222222222222
+detached
+locked moving disks
+
+worktree /wt/gone
+HEAD 3333333333333333333333333333333333333333
+branch refs/heads/gone
+prunable gitdir file points to non-existent location
+`, "worktree", "list")
+
+	worktrees, err := repo.ListWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WorktreeInfo{
+		{Path: "/repo", Bare: true},
+		{Path: "/wt/PROJ-1", Branch: "feature/PROJ-1", Commit: "1111111111111111111111111111111111111111"},
+		{Path: "/wt/detached", Commit: "2222222222222222222222222222222222222222", Detached: true, Locked: true, LockReason: "moving disks"},
+		{Path: "/wt/gone", Branch: "gone", Commit: "3333333333333333333333333333333333333333", Prunable: true},
+	}
+	if !reflect.DeepEqual(worktrees, want) {
+		t.Errorf("worktrees = %+v\nwant %+v", worktrees, want)
+	}
+}
@@ -0,0 +1,292 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileKind identifies the kind of an entry in git status output.
+type FileKind int
+
+const (
+	// FileChanged is a tracked file with staged or unstaged changes.
+	FileChanged FileKind = iota
+
+	// FileRenamed is a tracked file that was renamed or copied; OrigPath holds the source.
+	FileRenamed
+
+	// FileUnmerged is a file with merge conflicts.
+	FileUnmerged
+
+	// FileUntracked is a file git doesn't track.
+	FileUntracked
+
+	// FileIgnored is a file matched by an ignore rule.
+	FileIgnored
+)
+
+// Unmodified is the status code for a side (index or worktree) without changes.
+const Unmodified = '.'
+
+// SubmoduleState describes the state of a submodule entry.
+type SubmoduleState struct {
+	// IsSubmodule indicates the entry is a submodule.
+	IsSubmodule bool
+
+	// CommitChanged indicates the submodule's checked out commit differs from the recorded one.
+	CommitChanged bool
+
+	// Modified indicates the submodule has tracked changes.
+	Modified bool
+
+	// Untracked indicates the submodule has untracked files.
+	Untracked bool
+}
+
+// FileStatus is a single file in git status output.
+type FileStatus struct {
+	// Kind is the kind of entry.
+	Kind FileKind
+
+	// Path is the path relative to the top of the working tree.
+	Path string
+
+	// OrigPath is the path a renamed or copied file came from.
+	OrigPath string
+
+	// Index and Worktree are the status codes for the index and working tree sides
+	// (e.g., 'M', 'A', 'D', 'R', 'C', 'T' or Unmodified). For unmerged entries they
+	// describe the conflict (e.g., "UU" for both modified).
+	Index    byte
+	Worktree byte
+
+	// Score is the rename or copy similarity, from 0 to 100.
+	Score int
+
+	// Submodule describes the entry if it is a submodule.
+	Submodule SubmoduleState
+}
+
+// Staged reports whether the file has changes in the index.
+func (f FileStatus) Staged() bool {
+	return (f.Kind == FileChanged || f.Kind == FileRenamed) && f.Index != Unmodified
+}
+
+// Unstaged reports whether the file has changes in the working tree that aren't staged.
+func (f FileStatus) Unstaged() bool {
+	return (f.Kind == FileChanged || f.Kind == FileRenamed) && f.Worktree != Unmodified
+}
+
+// BranchStatus is the branch information in git status output.
+type BranchStatus struct {
+	// Commit is the commit HEAD points to, or empty before the first commit.
+	Commit string
+
+	// Head is the current branch, or empty if HEAD is detached.
+	Head string
+
+	// Upstream is the upstream branch (e.g., "origin/main"), or empty if none is set.
+	Upstream string
+
+	// HasCounts indicates Ahead and Behind are known. They aren't when the upstream is gone.
+	HasCounts bool
+
+	// Ahead and Behind count the commits the branch has that the upstream doesn't, and vice versa.
+	Ahead  int
+	Behind int
+}
+
+// Status is the parsed output of git status --porcelain=v2 --branch.
+type Status struct {
+	// Branch describes HEAD and its upstream.
+	Branch BranchStatus
+
+	// Files lists every changed, untracked and (if requested) ignored file.
+	Files []FileStatus
+}
+
+// Clean reports whether the working tree has no changes or untracked files. Ignored files don't count.
+func (s *Status) Clean() bool {
+	for _, f := range s.Files {
+		if f.Kind != FileIgnored {
+			return false
+		}
+	}
+	return true
+}
+
+// Changes returns the number of changed, conflicted and untracked files.
+func (s *Status) Changes() int {
+	n := 0
+	for _, f := range s.Files {
+		if f.Kind != FileIgnored {
+			n++
+		}
+	}
+	return n
+}
+
+// Staged returns the files with changes in the index.
+func (s *Status) Staged() []FileStatus {
+	return s.filter(FileStatus.Staged)
+}
+
+// Unstaged returns the files with unstaged changes in the working tree.
+func (s *Status) Unstaged() []FileStatus {
+	return s.filter(FileStatus.Unstaged)
+}
+
+// Conflicted returns the files with merge conflicts.
+func (s *Status) Conflicted() []FileStatus {
+	return s.filter(func(f FileStatus) bool { return f.Kind == FileUnmerged })
+}
+
+// Renamed returns the files that were renamed or copied.
+func (s *Status) Renamed() []FileStatus {
+	return s.filter(func(f FileStatus) bool { return f.Kind == FileRenamed })
+}
+
+// Untracked returns the untracked files.
+func (s *Status) Untracked() []FileStatus {
+	return s.filter(func(f FileStatus) bool { return f.Kind == FileUntracked })
+}
+
+// Ignored returns the ignored files. They are only present if requested.
+func (s *Status) Ignored() []FileStatus {
+	return s.filter(func(f FileStatus) bool { return f.Kind == FileIgnored })
+}
+
+// filter returns the files for which keep returns true.
+func (s *Status) filter(keep func(FileStatus) bool) []FileStatus {
+	var files []FileStatus
+	for _, f := range s.Files {
+		if keep(f) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Status returns the parsed status of the working tree. Untracked directories are
+// reported as a single entry and ignored files are left out.
+func (r *Repo) Status() (*Status, error) {
+	return r.status()
+}
+
+// status runs git status --porcelain=v2 with extra arguments and parses the result.
+func (r *Repo) status(args ...string) (*Status, error) {
+	args = append([]string{"status", "--porcelain=v2", "-z", "--branch"}, args...)
+	output, err := r.Run(args...)
+	if err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+	return ParseStatus([]byte(output))
+}
+
+// ParseStatus parses the output of git status --porcelain=v2 -z [--branch].
+func ParseStatus(data []byte) (*Status, error) {
+	status := &Status{}
+
+	// Every record, including the last, is NUL-terminated
+	records := bytes.Split(bytes.TrimSuffix(data, []byte{0}), []byte{0})
+	for i := 0; i < len(records); i++ {
+		record := string(records[i])
+		if record == "" {
+			continue
+		}
+
+		switch record[0] {
+		case '#':
+			parseBranchHeader(&status.Branch, record)
+		case '1':
+			// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+			fields := strings.SplitN(record, " ", 9)
+			if len(fields) != 9 {
+				return nil, fmt.Errorf("malformed status entry: %q", record)
+			}
+			f, err := newFileStatus(FileChanged, fields[1], fields[2], fields[8])
+			if err != nil {
+				return nil, err
+			}
+			status.Files = append(status.Files, f)
+		case '2':
+			// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path>, then <origPath> in the next record
+			fields := strings.SplitN(record, " ", 10)
+			if len(fields) != 10 || i+1 >= len(records) {
+				return nil, fmt.Errorf("malformed status entry: %q", record)
+			}
+			f, err := newFileStatus(FileRenamed, fields[1], fields[2], fields[9])
+			if err != nil {
+				return nil, err
+			}
+			if len(fields[8]) > 1 {
+				f.Score, _ = strconv.Atoi(fields[8][1:])
+			}
+			i++
+			f.OrigPath = string(records[i])
+			status.Files = append(status.Files, f)
+		case 'u':
+			// u <XY> <sub> <m1> <m2> <m3> <mW> <h1> <h2> <h3> <path>
+			fields := strings.SplitN(record, " ", 11)
+			if len(fields) != 11 {
+				return nil, fmt.Errorf("malformed status entry: %q", record)
+			}
+			f, err := newFileStatus(FileUnmerged, fields[1], fields[2], fields[10])
+			if err != nil {
+				return nil, err
+			}
+			status.Files = append(status.Files, f)
+		case '?':
+			status.Files = append(status.Files, FileStatus{Kind: FileUntracked, Path: strings.TrimPrefix(record, "? ")})
+		case '!':
+			status.Files = append(status.Files, FileStatus{Kind: FileIgnored, Path: strings.TrimPrefix(record, "! ")})
+		default:
+			return nil, fmt.Errorf("unknown status entry: %q", record)
+		}
+	}
+
+	return status, nil
+}
+
+// parseBranchHeader parses a "# branch.*" header line.
+func parseBranchHeader(branch *BranchStatus, line string) {
+	key, value, _ := strings.Cut(strings.TrimPrefix(line, "# "), " ")
+	switch key {
+	case "branch.oid":
+		if value != "(initial)" {
+			branch.Commit = value
+		}
+	case "branch.head":
+		if value != "(detached)" {
+			branch.Head = value
+		}
+	case "branch.upstream":
+		branch.Upstream = value
+	case "branch.ab":
+		// +<ahead> -<behind>
+		if _, err := fmt.Sscanf(value, "+%d -%d", &branch.Ahead, &branch.Behind); err == nil {
+			branch.HasCounts = true
+		}
+	}
+}
+
+// newFileStatus builds a file entry from its XY status codes and submodule field.
+func newFileStatus(kind FileKind, xy, sub, path string) (FileStatus, error) {
+	if len(xy) != 2 || len(sub) != 4 {
+		return FileStatus{}, fmt.Errorf("malformed status fields: %q %q", xy, sub)
+	}
+
+	f := FileStatus{Kind: kind, Path: path, Index: xy[0], Worktree: xy[1]}
+	// <sub> is "N..." for files and "S<c><m><u>" for submodules
+	if sub[0] == 'S' {
+		f.Submodule = SubmoduleState{
+			IsSubmodule:   true,
+			CommitChanged: sub[1] == 'C',
+			Modified:      sub[2] == 'M',
+			Untracked:     sub[3] == 'U',
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,210 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// porcelain joins records into NUL-terminated porcelain v2 output.
+func porcelain(records ...string) []byte {
+	return []byte(strings.Join(records, "\x00") + "\x00")
+}
+
+func TestParseStatus(t *testing.T) {
+	const modes = "100644 100644 100644"
+	const hashes = "1111111111111111111111111111111111111111 2222222222222222222222222222222222222222"
+
+	tests := []struct {
+		name   string
+		data   []byte
+		branch BranchStatus
+		files  []FileStatus
+	}{
+		{
+			name: "clean with upstream",
+			data: porcelain(
+				"# branch.oid abc123",
+				"# branch.head feature/PROJ-1",
+				"# branch.upstream origin/feature/PROJ-1",
+				"# branch.ab +2 -3",
+			),
+			branch: BranchStatus{Commit: "abc123", Head: "feature/PROJ-1", Upstream: "origin/feature/PROJ-1", HasCounts: true, Ahead: 2, Behind: 3},
+		},
+		{
+			name: "initial commit and detached head",
+			data: porcelain("# branch.oid (initial)", "# branch.head (detached)"),
+		},
+		{
+			name:   "upstream gone",
+			data:   porcelain("# branch.oid abc", "# branch.head main", "# branch.upstream origin/main"),
+			branch: BranchStatus{Commit: "abc", Head: "main", Upstream: "origin/main"},
+		},
+		{
+			name: "ordinary changes",
+			data: porcelain(
+				"1 M. N... "+modes+" "+hashes+" staged.txt",
+				"1 .M N... "+modes+" "+hashes+" unstaged.txt",
+				"1 MM N... "+modes+" "+hashes+" both.txt",
+			),
+			files: []FileStatus{
+				{Kind: FileChanged, Path: "staged.txt", Index: 'M', Worktree: '.'},
+				{Kind: FileChanged, Path: "unstaged.txt", Index: '.', Worktree: 'M'},
+				{Kind: FileChanged, Path: "both.txt", Index: 'M', Worktree: 'M'},
+			},
+		},
+		{
+			name: "spaces and newlines in paths",
+			data: porcelain(
+				"1 A. N... "+modes+" "+hashes+" dir with spaces/new file.txt",
+				"? line\nbreak.txt",
+			),
+			files: []FileStatus{
+				{Kind: FileChanged, Path: "dir with spaces/new file.txt", Index: 'A', Worktree: '.'},
+				{Kind: FileUntracked, Path: "line\nbreak.txt"},
+			},
+		},
+		{
+			name: "rename with origin",
+			data: porcelain(
+				"2 R. N... "+modes+" "+hashes+" R87 new name.txt",
+				"old name.txt",
+				"? after.txt",
+			),
+			files: []FileStatus{
+				{Kind: FileRenamed, Path: "new name.txt", OrigPath: "old name.txt", Index: 'R', Worktree: '.', Score: 87},
+				{Kind: FileUntracked, Path: "after.txt"},
+			},
+		},
+		{
+			name: "unmerged",
+			data: porcelain("u UU N... 100644 100644 100644 100644 " + hashes + " 3333333333333333333333333333333333333333 conflict.txt"),
+			files: []FileStatus{
+				{Kind: FileUnmerged, Path: "conflict.txt", Index: 'U', Worktree: 'U'},
+			},
+		},
+		{
+			name: "submodule",
+			data: porcelain("1 .M SCMU 160000 160000 160000 " + hashes + " vendor/lib"),
+			files: []FileStatus{
+				{Kind: FileChanged, Path: "vendor/lib", Index: '.', Worktree: 'M', Submodule: SubmoduleState{IsSubmodule: true, CommitChanged: true, Modified: true, Untracked: true}},
+			},
+		},
+		{
+			name: "ignored",
+			data: porcelain("! build/"),
+			files: []FileStatus{
+				{Kind: FileIgnored, Path: "build/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := ParseStatus(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Branch != tt.branch {
+				t.Errorf("branch = %+v, want %+v", status.Branch, tt.branch)
+			}
+			if !reflect.DeepEqual(status.Files, tt.files) {
+				t.Errorf("files = %+v, want %+v", status.Files, tt.files)
+			}
+		})
+	}
+}
+
+func TestParseStatusMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		porcelain("1 M. N... short"),
+		porcelain("2 R. N... 100644 100644 100644 a b R100 missing-origin"),
+		porcelain("x unknown"),
+	} {
+		if _, err := ParseStatus(data); err == nil {
+			t.Errorf("ParseStatus(%q) succeeded", data)
+		}
+	}
+}
+
+func TestStatusCategories(t *testing.T) {
+	status := &Status{Files: []FileStatus{
+		{Kind: FileChanged, Path: "a", Index: 'M', Worktree: 'M'},
+		{Kind: FileRenamed, Path: "b", OrigPath: "c", Index: 'R', Worktree: '.'},
+		{Kind: FileUnmerged, Path: "d", Index: 'U', Worktree: 'U'},
+		{Kind: FileUntracked, Path: "e"},
+		{Kind: FileIgnored, Path: "f"},
+	}}
+
+	counts := map[string]int{
+		"staged":     len(status.Staged()),
+		"unstaged":   len(status.Unstaged()),
+		"conflicted": len(status.Conflicted()),
+		"renamed":    len(status.Renamed()),
+		"untracked":  len(status.Untracked()),
+		"ignored":    len(status.Ignored()),
+		"changes":    status.Changes(),
+	}
+	want := map[string]int{"staged": 2, "unstaged": 1, "conflicted": 1, "renamed": 1, "untracked": 1, "ignored": 1, "changes": 4}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+	if status.Clean() {
+		t.Error("status with changes reported clean")
+	}
+	if !(&Status{Files: []FileStatus{{Kind: FileIgnored, Path: "f"}}}).Clean() {
+		t.Error("status with only ignored files reported dirty")
+	}
+}
+
+// TestStatus checks the parser against real git output.
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("keep.txt", "one\n")
+	write("old name.txt", "some content that is long enough to be detected as a rename\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+
+	write("keep.txt", "two\n")
+	run("mv", "old name.txt", "new name.txt")
+	write("line\nbreak.txt", "x\n")
+
+	status, err := NewRepo(dir).Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Branch.Head != "main" || status.Branch.Commit == "" {
+		t.Errorf("branch = %+v", status.Branch)
+	}
+	if renamed := status.Renamed(); len(renamed) != 1 || renamed[0].Path != "new name.txt" || renamed[0].OrigPath != "old name.txt" {
+		t.Errorf("renamed = %+v", renamed)
+	}
+	if unstaged := status.Unstaged(); len(unstaged) != 1 || unstaged[0].Path != "keep.txt" {
+		t.Errorf("unstaged = %+v", unstaged)
+	}
+	if untracked := status.Untracked(); len(untracked) != 1 || untracked[0].Path != "line\nbreak.txt" {
+		t.Errorf("untracked = %+v", untracked)
+	}
+}
@@ -3,7 +3,6 @@ package git
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -19,21 +18,34 @@ type WorktreeInfo struct {
 	// Commit is the current commit hash.
 	Commit string
 
-	// IsPrimary indicates if this is the primary repository (not a worktree).
+	// IsPrimary indicates if this is the primary working tree of a non-bare repository.
 	IsPrimary bool
+
+	// Bare indicates this entry is a bare repository rather than a working tree.
+	Bare bool
+
+	// Detached indicates the worktree has a detached HEAD.
+	Detached bool
+
+	// Locked indicates the worktree is locked against pruning and removal.
+	Locked bool
+
+	// LockReason is the reason given when the worktree was locked, if any.
+	LockReason string
+
+	// Prunable indicates git considers the worktree prunable (e.g., its directory is missing).
+	Prunable bool
 }
 
 // ListWorktrees returns all worktrees for the repository.
 func (r *Repo) ListWorktrees() ([]WorktreeInfo, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	cmd.Dir = r.Path
-	output, err := cmd.Output()
+	output, err := r.Run("worktree", "list", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list worktrees: %w", err)
 	}
 
 	var worktrees []WorktreeInfo
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(output, "\n")
 
 	var current WorktreeInfo
 	for _, line := range lines {
@@ -58,7 +70,16 @@ func (r *Repo) ListWorktrees() ([]WorktreeInfo, error) {
 				current.Branch = branch
 			}
 		} else if line == "bare" {
-			current.IsPrimary = true
+			current.Bare = true
+		} else if line == "detached" {
+			current.Detached = true
+		} else if line == "locked" {
+			current.Locked = true
+		} else if reason, found := strings.CutPrefix(line, "locked "); found {
+			current.Locked = true
+			current.LockReason = reason
+		} else if line == "prunable" || strings.HasPrefix(line, "prunable ") {
+			current.Prunable = true
 		}
 	}
 
@@ -67,18 +88,10 @@ func (r *Repo) ListWorktrees() ([]WorktreeInfo, error) {
 		worktrees = append(worktrees, current)
 	}
 
-	// Mark the first worktree as primary if none are marked
-	if len(worktrees) > 0 {
-		hasPrimary := false
-		for _, wt := range worktrees {
-			if wt.IsPrimary {
-				hasPrimary = true
-				break
-			}
-		}
-		if !hasPrimary {
-			worktrees[0].IsPrimary = true
-		}
+	// git always lists the main working tree first. A bare repository has none,
+	// so none of its worktrees is primary.
+	if len(worktrees) > 0 && !worktrees[0].Bare {
+		worktrees[0].IsPrimary = true
 	}
 
 	return worktrees, nil
@@ -91,11 +104,22 @@ func (r *Repo) AddWorktree(path, branch, startPoint string) error {
 		return fmt.Errorf("failed to create worktree parent directory: %w", err)
 	}
 
-	cmd := exec.Command("git", "worktree", "add", "-b", branch, path, startPoint)
-	cmd.Dir = r.Path
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to add worktree: %w\n%s", err, output)
+	if _, err := r.Run("worktree", "add", "-b", branch, path, startPoint); err != nil {
+		return fmt.Errorf("failed to add worktree: %w", err)
+	}
+
+	return nil
+}
+
+// AttachWorktree creates a new worktree at the specified path for an existing branch.
+func (r *Repo) AttachWorktree(path, branch string) error {
+	// Ensure parent directory exists
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create worktree parent directory: %w", err)
+	}
+
+	if _, err := r.Run("worktree", "add", path, branch); err != nil {
+		return fmt.Errorf("failed to add worktree: %w", err)
 	}
 
 	return nil
@@ -103,16 +127,33 @@ func (r *Repo) AddWorktree(path, branch, startPoint string) error {
 
 // RemoveWorktree removes a worktree at the specified path.
 func (r *Repo) RemoveWorktree(path string) error {
-	cmd := exec.Command("git", "worktree", "remove", path)
-	cmd.Dir = r.Path
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to remove worktree: %w\n%s", err, output)
+	if _, err := r.Run("worktree", "remove", path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
 	}
 
 	return nil
 }
 
+// ForceRemoveWorktree removes a worktree at the specified path, discarding any
+// uncommitted changes and untracked files in it.
+func (r *Repo) ForceRemoveWorktree(path string) error {
+	if _, err := r.Run("worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+
+	return nil
+}
+
+// RepairWorktrees repairs the administrative links between the repository and
+// the worktrees at the given paths (e.g., after a worktree was moved by hand).
+func (r *Repo) RepairWorktrees(paths ...string) error {
+	args := append([]string{"worktree", "repair"}, paths...)
+	if _, err := r.Run(args...); err != nil {
+		return fmt.Errorf("failed to repair worktrees: %w", err)
+	}
+	return nil
+}
+
 // WorktreeExists checks if a worktree exists at the given path.
 func (r *Repo) WorktreeExists(path string) (bool, error) {
 	worktrees, err := r.ListWorktrees()
@@ -140,11 +181,8 @@ func (r *Repo) WorktreeExists(path string) (bool, error) {
 
 // PruneWorktrees removes worktree administrative files for missing worktrees.
 func (r *Repo) PruneWorktrees() error {
-	cmd := exec.Command("git", "worktree", "prune")
-	cmd.Dir = r.Path
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to prune worktrees: %w\n%s", err, output)
+	if _, err := r.Run("worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,284 @@
+// Package harness runs git-tree end to end for tests. Each harness has its own bare
+// "origin" repository and a clone of it acting as the primary repository, so commands
+// can fetch, push and create worktrees without touching the network or the user's setup.
+package harness
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sduncan/git-tree/cmd"
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// update rewrites golden files with the actual output instead of comparing against them.
+var update = flag.Bool("update", false, "update golden files")
+
+// commitDate is the author and committer date of every commit, so commit hashes are stable.
+const commitDate = "2026-01-02T03:04:05Z"
+
+// Harness is a sandbox with an origin repository and a primary clone of it.
+type Harness struct {
+	t *testing.T
+
+	// Root is the temporary directory everything lives in.
+	Root string
+
+	// Origin is the path of the bare repository the primary repository was cloned from.
+	Origin string
+
+	// Repo is the path of the primary repository.
+	Repo string
+
+	// upstream is a second clone used to push commits to the origin, as another developer would.
+	upstream string
+
+	// testdata is the absolute path of the test package's testdata directory.
+	testdata string
+
+	// Now is the clock git-tree runs with. It defaults to the time the harness was created.
+	Now func() time.Time
+}
+
+// Result is the outcome of running git-tree.
+type Result struct {
+	// Stdout and Stderr are the captured output, with sandbox paths and times normalized.
+	Stdout string
+	Stderr string
+
+	// Code is the exit code.
+	Code int
+}
+
+// String formats the result as a transcript: stdout, then stderr and the exit code if
+// there are any.
+func (r Result) String() string {
+	var b strings.Builder
+	b.WriteString(r.Stdout)
+	if r.Stderr != "" {
+		b.WriteString("[stderr]\n" + r.Stderr)
+	}
+	if r.Code != 0 {
+		fmt.Fprintf(&b, "[exit %d]\n", r.Code)
+	}
+	return b.String()
+}
+
+// New creates a sandbox whose origin has a single commit on main. The test's environment
+// is isolated from the user's git configuration, so it must not run in parallel.
+func New(t *testing.T) *Harness {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home := filepath.Join(root, "home")
+	for key, value := range map[string]string{
+		"HOME":                   home,
+		"XDG_CONFIG_HOME":        filepath.Join(home, ".config"),
+		"XDG_DATA_HOME":          filepath.Join(home, ".local", "share"),
+		"GIT_CONFIG_NOSYSTEM":    "1",
+		"GIT_AUTHOR_NAME":        "Test",
+		"GIT_AUTHOR_EMAIL":       "test@example.com",
+		"GIT_AUTHOR_DATE":        commitDate,
+		"GIT_COMMITTER_NAME":     "Test",
+		"GIT_COMMITTER_EMAIL":    "test@example.com",
+		"GIT_COMMITTER_DATE":     commitDate,
+		"GIT_TREE_TRACE":         "",
+		"GIT_TREE_TRACKER_TOKEN": "",
+		"GIT_TREE_CREDENTIALS":   filepath.Join(home, "credentials"),
+	} {
+		t.Setenv(key, value)
+	}
+	for _, key := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_COMMON_DIR", "GIT_INDEX_FILE"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Golden files are relative to the test package, so resolve them before changing directory.
+	// Commands run in the directory given to RunIn, so the process stays outside any repository
+	// to catch commands that use the working directory instead.
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+
+	h := &Harness{
+		t:        t,
+		Root:     root,
+		Origin:   filepath.Join(root, "origin.git"),
+		Repo:     filepath.Join(root, "repo"),
+		upstream: filepath.Join(root, "upstream"),
+		testdata: testdata,
+	}
+	start := time.Now()
+	h.Now = func() time.Time { return start }
+
+	h.Git(root, "init", "--quiet", "--bare", "--initial-branch=main", h.Origin)
+	h.Git(root, "clone", "--quiet", h.Origin, h.upstream)
+	h.Git(h.upstream, "checkout", "--quiet", "-b", "main")
+	h.Commit(h.upstream, "README.md", "# sandbox\n", "Initial commit")
+	h.Git(h.upstream, "push", "--quiet", "origin", "main")
+	h.Git(root, "clone", "--quiet", h.Origin, h.Repo)
+
+	return h
+}
+
+// Clone clones the origin again, as on another machine, into a repository named name and
+// returns its path.
+func (h *Harness) Clone(name string) string {
+	h.t.Helper()
+	repo := filepath.Join(h.Root, name)
+	h.Git(h.Root, "clone", "--quiet", h.Origin, repo)
+	return repo
+}
+
+// AddRepo creates another origin repository with a single commit on main and a clone of it
+// named name, and returns the path of the clone.
+func (h *Harness) AddRepo(name string) string {
+	h.t.Helper()
+	origin := filepath.Join(h.Root, name+".git")
+	repo := filepath.Join(h.Root, name)
+	h.Git(h.Root, "init", "--quiet", "--bare", "--initial-branch=main", origin)
+	h.Git(h.Root, "clone", "--quiet", origin, repo)
+	h.Git(repo, "checkout", "--quiet", "-b", "main")
+	h.Commit(repo, "README.md", "# "+name+"\n", "Initial commit")
+	h.Git(repo, "push", "--quiet", "--set-upstream", "origin", "main")
+	return repo
+}
+
+// Run runs git-tree in the primary repository.
+func (h *Harness) Run(args ...string) Result {
+	h.t.Helper()
+	return h.RunIn(h.Repo, "", args...)
+}
+
+// RunIn runs git-tree in dir with the given standard input. It is interactive only if
+// there is input, so prompts fail rather than read an empty answer.
+func (h *Harness) RunIn(dir, stdin string, args ...string) Result {
+	h.t.Helper()
+	var stdout, stderr strings.Builder
+	ctx := &cmd.Context{
+		Stdin:       strings.NewReader(stdin),
+		Stdout:      &stdout,
+		Stderr:      &stderr,
+		Dir:         dir,
+		Env:         os.Environ(),
+		Now:         h.Now,
+		Interactive: stdin != "",
+	}
+	code := cmd.Main(ctx, args)
+	return Result{Stdout: h.Normalize(stdout.String()), Stderr: h.Normalize(stderr.String()), Code: code}
+}
+
+// MustRun runs git-tree in the primary repository and fails the test if it exits with an error.
+func (h *Harness) MustRun(args ...string) Result {
+	h.t.Helper()
+	result := h.Run(args...)
+	if result.Code != 0 {
+		h.t.Fatalf("git tree %s exited with %d\nstdout:\n%s\nstderr:\n%s", strings.Join(args, " "), result.Code, result.Stdout, result.Stderr)
+	}
+	return result
+}
+
+// Git runs git in dir and returns its output, failing the test on error.
+func (h *Harness) Git(dir string, args ...string) string {
+	h.t.Helper()
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	output, err := c.CombinedOutput()
+	if err != nil {
+		h.t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// Commit writes a file in the working tree at dir and commits it.
+func (h *Harness) Commit(dir, name, content, message string) {
+	h.t.Helper()
+	h.WriteFile(filepath.Join(dir, name), content)
+	h.Git(dir, "add", name)
+	h.Git(dir, "commit", "--quiet", "-m", message)
+}
+
+// WriteFile writes a file, creating its directory if needed.
+func (h *Harness) WriteFile(path, content string) {
+	h.t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// AdvanceMainline commits a file to main in the origin, as if another change was merged.
+func (h *Harness) AdvanceMainline(name, content, message string) {
+	h.t.Helper()
+	h.Commit(h.upstream, name, content, message)
+	h.Git(h.upstream, "push", "--quiet", "origin", "main")
+}
+
+// Metadata returns the primary repository's worktree metadata as normalized, indented JSON.
+func (h *Harness) Metadata() string {
+	h.t.Helper()
+	meta, err := config.Load(h.Repo)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return h.Normalize(string(data) + "\n")
+}
+
+// timestamps matches the times git-tree prints and stores, which differ on every run.
+var timestamps = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?`)
+
+// Normalize replaces the sandbox path with $ROOT and times with <time> so output can be
+// compared across runs.
+func (h *Harness) Normalize(s string) string {
+	s = strings.ReplaceAll(s, h.Root, "$ROOT")
+	return timestamps.ReplaceAllString(s, "<time>")
+}
+
+// Golden compares got with testdata/<name>.golden, or rewrites the file when tests run with -update.
+func (h *Harness) Golden(name, got string) {
+	h.t.Helper()
+	path := filepath.Join(h.testdata, name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			h.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			h.t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		h.t.Errorf("%s does not match golden file %s\ngot:\n%s\nwant:\n%s", name, path, got, want)
+	}
+}
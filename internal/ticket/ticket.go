@@ -0,0 +1,155 @@
+// Package ticket validates, normalizes and extracts ticket IDs.
+package ticket
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Case normalization modes.
+const (
+	CaseUpper    = "upper"
+	CaseLower    = "lower"
+	CasePreserve = "preserve"
+)
+
+// MaxLength is the longest ticket ID accepted.
+const MaxLength = 64
+
+// DefaultPattern matches Jira-style IDs (e.g., "PROJ-123") when extracting a ticket ID
+// and no patterns are configured.
+const DefaultPattern = `[A-Za-z][A-Za-z0-9]*-[0-9]+`
+
+// Validate checks that a ticket ID is safe to use as a directory name and branch name
+// component. It rejects path separators, "." and "..", leading dashes (which git would
+// parse as options), whitespace, control characters and characters git forbids in refs.
+func Validate(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("ticket ID is empty")
+	case len(id) > MaxLength:
+		return fmt.Errorf("ticket ID %q is longer than %d characters", id, MaxLength)
+	case strings.ContainsAny(id, `/\`):
+		return fmt.Errorf("ticket ID %q must not contain path separators", id)
+	case id == "." || id == "..":
+		return fmt.Errorf("ticket ID %q is not allowed", id)
+	case strings.HasPrefix(id, "-"):
+		return fmt.Errorf("ticket ID %q must not start with -", id)
+	case strings.HasPrefix(id, "."), strings.HasSuffix(id, "."):
+		return fmt.Errorf("ticket ID %q must not start or end with .", id)
+	case strings.HasSuffix(id, ".lock"):
+		return fmt.Errorf("ticket ID %q must not end with .lock", id)
+	case strings.Contains(id, ".."), strings.Contains(id, "@{"):
+		return fmt.Errorf("ticket ID %q contains a sequence git does not allow in branch names", id)
+	}
+
+	for _, r := range id {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune("~^:?*[", r) {
+			return fmt.Errorf("ticket ID %q contains invalid character %q", id, r)
+		}
+	}
+
+	return nil
+}
+
+// Normalize applies a case normalization mode to a ticket ID.
+// An empty mode is the same as CasePreserve.
+func Normalize(id, mode string) string {
+	id = strings.TrimSpace(id)
+	switch mode {
+	case CaseUpper:
+		return strings.ToUpper(id)
+	case CaseLower:
+		return strings.ToLower(id)
+	default:
+		return id
+	}
+}
+
+// ValidCase reports whether mode is a known case normalization mode.
+func ValidCase(mode string) bool {
+	switch mode {
+	case "", CaseUpper, CaseLower, CasePreserve:
+		return true
+	}
+	return false
+}
+
+// Matcher checks ticket IDs against a set of patterns, typically one per project key
+// (e.g., `PROJ-\d+` and `OPS-\d+`).
+type Matcher struct {
+	patterns []string
+	exact    []*regexp.Regexp
+	search   []*regexp.Regexp
+}
+
+// NewMatcher compiles the given regular expressions. A ticket ID must match one of them
+// in full. With no patterns, every ID matches and extraction uses DefaultPattern.
+func NewMatcher(patterns []string) (*Matcher, error) {
+	m := &Matcher{patterns: patterns}
+	for _, p := range patterns {
+		// Anchors are implied for validation and get in the way of searching text
+		bare := strings.TrimSuffix(strings.TrimPrefix(p, "^"), "$")
+		exact, err := regexp.Compile(`^(?:` + bare + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket pattern %q: %w", p, err)
+		}
+		search, err := regexp.Compile(`(?i)` + boundary(bare))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ticket pattern %q: %w", p, err)
+		}
+		m.exact = append(m.exact, exact)
+		m.search = append(m.search, search)
+	}
+	if len(patterns) == 0 {
+		m.search = []*regexp.Regexp{regexp.MustCompile(boundary(DefaultPattern))}
+	}
+	return m, nil
+}
+
+// Match reports whether a ticket ID matches one of the patterns.
+func (m *Matcher) Match(id string) bool {
+	if len(m.exact) == 0 {
+		return true
+	}
+	for _, re := range m.exact {
+		if re.MatchString(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if a ticket ID doesn't match any of the patterns.
+func (m *Matcher) Check(id string) error {
+	if m.Match(id) {
+		return nil
+	}
+	return fmt.Errorf("ticket %s does not match pattern %s", id, strings.Join(m.patterns, " or "))
+}
+
+// Extract returns the first ticket ID found in text, such as a branch name or commit
+// message, or "" if there is none. The search ignores case; the ID is returned as it
+// appears in the text.
+func (m *Matcher) Extract(text string) string {
+	best, bestPos := "", -1
+	for _, re := range m.search {
+		loc := re.FindStringSubmatchIndex(text)
+		if loc == nil {
+			continue
+		}
+		i := 2 * re.SubexpIndex("ticket")
+		if bestPos < 0 || loc[i] < bestPos {
+			best, bestPos = text[loc[i]:loc[i+1]], loc[i]
+		}
+	}
+	return best
+}
+
+// boundary wraps a pattern so it only matches a whole ID, delimited by anything other
+// than a letter or digit (e.g., "/", "-" or "_" in branch names).
+func boundary(pattern string) string {
+	return `(?:^|[^A-Za-z0-9])(?P<ticket>` + pattern + `)(?:[^A-Za-z0-9]|$)`
+}
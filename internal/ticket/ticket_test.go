@@ -0,0 +1,133 @@
+package ticket
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"PROJ-123", true},
+		{"proj_123", true},
+		{"42", true},
+		{"v1.2", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../etc", false},
+		{"PROJ/123", false},
+		{`PROJ\123`, false},
+		{"a..b", false},
+		{"-rf", false},
+		{".hidden", false},
+		{"trailing.", false},
+		{"PROJ-1.lock", false},
+		{"PROJ 123", false},
+		{"PROJ\t123", false},
+		{"PROJ~1", false},
+		{"PROJ^1", false},
+		{"PROJ:1", false},
+		{"PROJ*", false},
+		{"PROJ?", false},
+		{"PROJ[1]", false},
+		{"a@{1}", false},
+		{"x\x00y", false},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLMNOPQRSTUVWXYZABCDEFGHIJKLM", false},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.id)
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", tt.id, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		id, mode, want string
+	}{
+		{"proj-123", CaseUpper, "PROJ-123"},
+		{"PROJ-123", CaseLower, "proj-123"},
+		{"Proj-123", CasePreserve, "Proj-123"},
+		{"Proj-123", "", "Proj-123"},
+		{" proj-1 ", CaseUpper, "PROJ-1"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.id, tt.mode); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.id, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m, err := NewMatcher([]string{`PROJ-\d+`, `^OPS-\d+$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"PROJ-123", true},
+		{"OPS-7", true},
+		{"PROJ-123-extra", false},
+		{"xPROJ-1", false},
+		{"proj-123", false},
+		{"OTHER-1", false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.id); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+
+	permissive, err := NewMatcher(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permissive.Match("anything") {
+		t.Error("matcher without patterns should accept any ID")
+	}
+
+	if _, err := NewMatcher([]string{"("}); err == nil {
+		t.Error("NewMatcher accepted an invalid pattern")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     string
+	}{
+		{"branch with slug", nil, "feature/PROJ-123-add-login", "PROJ-123"},
+		{"underscore separator", nil, "PROJ-9_fix", "PROJ-9"},
+		{"commit message", nil, "Fix the build (OPS-42)", "OPS-42"},
+		{"lowercase branch", nil, "proj-5-thing", "proj-5"},
+		{"no ticket", nil, "main", ""},
+		{"first of several", nil, "ABC-1 and DEF-2", "ABC-1"},
+		{"configured pattern", []string{`PROJ-\d+`}, "fix/proj-77", "proj-77"},
+		{"earliest across patterns", []string{`PROJ-\d+`, `OPS-\d+`}, "OPS-1 before PROJ-2", "OPS-1"},
+		{"anchored pattern", []string{`^PROJ-\d+$`}, "feature/PROJ-3-x", "PROJ-3"},
+		{"not inside a word", []string{`PROJ-\d+`}, "XPROJ-1", ""},
+		{"whole number only", []string{`PROJ-\d+`}, "PROJ-12abc", ""},
+		{"issue number", []string{`\d+`}, "fix/1234-crash", "1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Extract(tt.text); got != tt.want {
+				t.Errorf("Extract(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// github looks up issues with the GitHub REST API.
+type github struct {
+	cfg Config
+}
+
+// Name returns the tracker type.
+func (g *github) Name() string {
+	return TypeGitHub
+}
+
+// GetTicket fetches an issue by number. IDs such as "#42" and "GH-42" are accepted.
+func (g *github) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	number, err := issueNumber(id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%s", strings.TrimRight(g.cfg.BaseURL, "/"), g.cfg.Project, number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.authorize(req)
+
+	var issue struct {
+		Number   int    `json:"number"`
+		Title    string `json:"title"`
+		State    string `json:"state"`
+		HTMLURL  string `json:"html_url"`
+		Assignee *struct {
+			Login string `json:"login"`
+		} `json:"assignee"`
+	}
+	if err := doJSON(g.cfg.Client, req, &issue); err != nil {
+		return nil, err
+	}
+
+	ticket := &Ticket{
+		ID:     fmt.Sprintf("%d", issue.Number),
+		Title:  issue.Title,
+		Status: issue.State,
+		URL:    issue.HTMLURL,
+	}
+	if issue.Assignee != nil {
+		ticket.Assignee = issue.Assignee.Login
+	}
+	return ticket, nil
+}
+
+// authorize adds credentials and the GitHub API media type to a request.
+func (g *github) authorize(req *http.Request) {
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+}
+
+// Transition opens or closes an issue. GitHub issues only have the states "open" and "closed".
+func (g *github) Transition(ctx context.Context, id, state string) error {
+	state = strings.ToLower(state)
+	if state != "open" && state != "closed" {
+		return fmt.Errorf("github issues can only be open or closed, not %q", state)
+	}
+	return g.do(ctx, http.MethodPatch, id, "", map[string]string{"state": state}, nil)
+}
+
+// AssignToMe assigns an issue to the authenticated user.
+func (g *github) AssignToMe(ctx context.Context, id string) error {
+	req, err := newJSONRequest(ctx, http.MethodGet, strings.TrimRight(g.cfg.BaseURL, "/")+"/user", nil)
+	if err != nil {
+		return err
+	}
+	g.authorize(req)
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := doJSON(g.cfg.Client, req, &user); err != nil {
+		return err
+	}
+
+	return g.do(ctx, http.MethodPost, id, "/assignees", map[string][]string{"assignees": {user.Login}}, nil)
+}
+
+// Comment adds a comment to an issue.
+func (g *github) Comment(ctx context.Context, id, body string) error {
+	return g.do(ctx, http.MethodPost, id, "/comments", map[string]string{"body": body}, nil)
+}
+
+// do sends an authorized request to a path under an issue and decodes the response into out.
+func (g *github) do(ctx context.Context, method, id, path string, body, out any) error {
+	number, err := issueNumber(id)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%s%s", strings.TrimRight(g.cfg.BaseURL, "/"), g.cfg.Project, number, path)
+	req, err := newJSONRequest(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	g.authorize(req)
+	return doJSON(g.cfg.Client, req, out)
+}
@@ -0,0 +1,120 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlab looks up issues with the GitLab REST API.
+type gitlab struct {
+	cfg Config
+}
+
+// Name returns the tracker type.
+func (g *gitlab) Name() string {
+	return TypeGitLab
+}
+
+// GetTicket fetches an issue by its project-scoped number. IDs such as "#42" are accepted.
+func (g *gitlab) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	iid, err := issueNumber(id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/issues/%s",
+		strings.TrimRight(g.cfg.BaseURL, "/"), url.PathEscape(g.cfg.Project), iid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	g.authorize(req)
+
+	var issue struct {
+		IID      int    `json:"iid"`
+		Title    string `json:"title"`
+		State    string `json:"state"`
+		WebURL   string `json:"web_url"`
+		Assignee *struct {
+			Username string `json:"username"`
+		} `json:"assignee"`
+	}
+	if err := doJSON(g.cfg.Client, req, &issue); err != nil {
+		return nil, err
+	}
+
+	ticket := &Ticket{
+		ID:     fmt.Sprintf("%d", issue.IID),
+		Title:  issue.Title,
+		Status: issue.State,
+		URL:    issue.WebURL,
+	}
+	if issue.Assignee != nil {
+		ticket.Assignee = issue.Assignee.Username
+	}
+	return ticket, nil
+}
+
+// authorize adds credentials to a request.
+func (g *gitlab) authorize(req *http.Request) {
+	if g.cfg.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.cfg.Token)
+	}
+}
+
+// Transition closes or reopens an issue. GitLab issues only have the states "opened" and "closed".
+func (g *gitlab) Transition(ctx context.Context, id, state string) error {
+	var event string
+	switch strings.ToLower(state) {
+	case "closed", "close":
+		event = "close"
+	case "opened", "open", "reopen":
+		event = "reopen"
+	default:
+		return fmt.Errorf("gitlab issues can only be opened or closed, not %q", state)
+	}
+	return g.do(ctx, http.MethodPut, id, "", map[string]string{"state_event": event}, nil)
+}
+
+// AssignToMe assigns an issue to the authenticated user.
+func (g *gitlab) AssignToMe(ctx context.Context, id string) error {
+	req, err := newJSONRequest(ctx, http.MethodGet, strings.TrimRight(g.cfg.BaseURL, "/")+"/api/v4/user", nil)
+	if err != nil {
+		return err
+	}
+	g.authorize(req)
+
+	var user struct {
+		ID int `json:"id"`
+	}
+	if err := doJSON(g.cfg.Client, req, &user); err != nil {
+		return err
+	}
+
+	return g.do(ctx, http.MethodPut, id, "", map[string][]int{"assignee_ids": {user.ID}}, nil)
+}
+
+// Comment adds a note to an issue.
+func (g *gitlab) Comment(ctx context.Context, id, body string) error {
+	return g.do(ctx, http.MethodPost, id, "/notes", map[string]string{"body": body}, nil)
+}
+
+// do sends an authorized request to a path under an issue and decodes the response into out.
+func (g *gitlab) do(ctx context.Context, method, id, path string, body, out any) error {
+	iid, err := issueNumber(id)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/issues/%s%s",
+		strings.TrimRight(g.cfg.BaseURL, "/"), url.PathEscape(g.cfg.Project), iid, path)
+	req, err := newJSONRequest(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	g.authorize(req)
+	return doJSON(g.cfg.Client, req, out)
+}
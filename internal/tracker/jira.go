@@ -0,0 +1,129 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// jira looks up issues with the Jira REST API.
+type jira struct {
+	cfg Config
+}
+
+// Name returns the tracker type.
+func (j *jira) Name() string {
+	return TypeJira
+}
+
+// GetTicket fetches an issue by key (e.g., "PROJ-123").
+func (j *jira) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	endpoint := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=summary,status,assignee",
+		strings.TrimRight(j.cfg.BaseURL, "/"), url.PathEscape(id))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	j.authorize(req)
+
+	var issue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  *struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Assignee *struct {
+				DisplayName string `json:"displayName"`
+			} `json:"assignee"`
+		} `json:"fields"`
+	}
+	if err := doJSON(j.cfg.Client, req, &issue); err != nil {
+		return nil, err
+	}
+
+	ticket := &Ticket{
+		ID:    issue.Key,
+		Title: issue.Fields.Summary,
+		URL:   fmt.Sprintf("%s/browse/%s", strings.TrimRight(j.cfg.BaseURL, "/"), issue.Key),
+	}
+	if issue.Fields.Status != nil {
+		ticket.Status = issue.Fields.Status.Name
+	}
+	if issue.Fields.Assignee != nil {
+		ticket.Assignee = issue.Fields.Assignee.DisplayName
+	}
+	return ticket, nil
+}
+
+// authorize adds credentials to a request. Jira Cloud uses basic authentication with an
+// account email and API token; Jira Server and Data Center accept personal access tokens.
+func (j *jira) authorize(req *http.Request) {
+	if j.cfg.Token == "" {
+		return
+	}
+	if j.cfg.User != "" {
+		req.SetBasicAuth(j.cfg.User, j.cfg.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.cfg.Token)
+	}
+}
+
+// Transition moves an issue through the workflow transition whose name or target status matches state.
+func (j *jira) Transition(ctx context.Context, id, state string) error {
+	var transitions struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(id)+"/transitions", nil, &transitions); err != nil {
+		return err
+	}
+
+	for _, t := range transitions.Transitions {
+		if strings.EqualFold(t.Name, state) || strings.EqualFold(t.To.Name, state) {
+			body := map[string]any{"transition": map[string]string{"id": t.ID}}
+			return j.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(id)+"/transitions", body, nil)
+		}
+	}
+	return fmt.Errorf("no transition to %q is available for %s", state, id)
+}
+
+// AssignToMe assigns an issue to the authenticated user.
+func (j *jira) AssignToMe(ctx context.Context, id string) error {
+	var myself struct {
+		AccountID string `json:"accountId"`
+		Name      string `json:"name"`
+	}
+	if err := j.do(ctx, http.MethodGet, "/myself", nil, &myself); err != nil {
+		return err
+	}
+
+	// Jira Cloud identifies users by account ID, Jira Server by name
+	body := map[string]string{"accountId": myself.AccountID}
+	if myself.AccountID == "" {
+		body = map[string]string{"name": myself.Name}
+	}
+	return j.do(ctx, http.MethodPut, "/issue/"+url.PathEscape(id)+"/assignee", body, nil)
+}
+
+// Comment adds a comment to an issue.
+func (j *jira) Comment(ctx context.Context, id, body string) error {
+	return j.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(id)+"/comment", map[string]string{"body": body}, nil)
+}
+
+// do sends an authorized request to the REST API path and decodes the response into out.
+func (j *jira) do(ctx context.Context, method, path string, body, out any) error {
+	req, err := newJSONRequest(ctx, method, strings.TrimRight(j.cfg.BaseURL, "/")+"/rest/api/2"+path, body)
+	if err != nil {
+		return err
+	}
+	j.authorize(req)
+	return doJSON(j.cfg.Client, req, out)
+}
@@ -0,0 +1,177 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// linear looks up issues with the Linear GraphQL API.
+type linear struct {
+	cfg Config
+}
+
+// Name returns the tracker type.
+func (l *linear) Name() string {
+	return TypeLinear
+}
+
+// linearIssueQuery fetches the fields of an issue by identifier (e.g., "ENG-123").
+const linearIssueQuery = `query Issue($id: String!) {
+  issue(id: $id) {
+    identifier
+    title
+    url
+    state { name }
+    assignee { name }
+  }
+}`
+
+// GetTicket fetches an issue by identifier (e.g., "ENG-123").
+func (l *linear) GetTicket(ctx context.Context, id string) (*Ticket, error) {
+	var data struct {
+		Issue *struct {
+			Identifier string `json:"identifier"`
+			Title      string `json:"title"`
+			URL        string `json:"url"`
+			State      *struct {
+				Name string `json:"name"`
+			} `json:"state"`
+			Assignee *struct {
+				Name string `json:"name"`
+			} `json:"assignee"`
+		} `json:"issue"`
+	}
+	if err := l.query(ctx, linearIssueQuery, map[string]any{"id": id}, &data); err != nil {
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, ErrNotFound
+	}
+
+	ticket := &Ticket{
+		ID:    data.Issue.Identifier,
+		Title: data.Issue.Title,
+		URL:   data.Issue.URL,
+	}
+	if data.Issue.State != nil {
+		ticket.Status = data.Issue.State.Name
+	}
+	if data.Issue.Assignee != nil {
+		ticket.Assignee = data.Issue.Assignee.Name
+	}
+	return ticket, nil
+}
+
+// query runs a GraphQL query and decodes its data into out.
+func (l *linear) query(ctx context.Context, query string, variables map[string]any, out any) error {
+	body := map[string]any{"query": query, "variables": variables}
+	req, err := newJSONRequest(ctx, http.MethodPost, strings.TrimRight(l.cfg.BaseURL, "/")+"/graphql", body)
+	if err != nil {
+		return err
+	}
+	// Linear personal API keys are sent without a scheme
+	if l.cfg.Token != "" {
+		req.Header.Set("Authorization", l.cfg.Token)
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := doJSON(l.cfg.Client, req, &resp); err != nil {
+		return err
+	}
+
+	if len(resp.Errors) > 0 {
+		e := resp.Errors[0]
+		switch {
+		case strings.Contains(strings.ToLower(e.Message), "not found"):
+			return ErrNotFound
+		case e.Extensions.Code == "AUTHENTICATION_ERROR":
+			return ErrUnauthorized
+		}
+		return fmt.Errorf("linear: %s", e.Message)
+	}
+
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to parse tracker response: %w", err)
+	}
+	return nil
+}
+
+// linearStatesQuery fetches the workflow states available to an issue's team.
+const linearStatesQuery = `query IssueStates($id: String!) {
+  issue(id: $id) {
+    team { states { nodes { id name } } }
+  }
+}`
+
+// linearUpdateMutation updates an issue.
+const linearUpdateMutation = `mutation IssueUpdate($id: String!, $input: IssueUpdateInput!) {
+  issueUpdate(id: $id, input: $input) { success }
+}`
+
+// Transition moves an issue to the workflow state with the given name.
+func (l *linear) Transition(ctx context.Context, id, state string) error {
+	var data struct {
+		Issue *struct {
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	if err := l.query(ctx, linearStatesQuery, map[string]any{"id": id}, &data); err != nil {
+		return err
+	}
+	if data.Issue == nil {
+		return ErrNotFound
+	}
+
+	for _, s := range data.Issue.Team.States.Nodes {
+		if strings.EqualFold(s.Name, state) {
+			return l.update(ctx, id, map[string]any{"stateId": s.ID})
+		}
+	}
+	return fmt.Errorf("no workflow state %q is available for %s", state, id)
+}
+
+// AssignToMe assigns an issue to the authenticated user.
+func (l *linear) AssignToMe(ctx context.Context, id string) error {
+	var data struct {
+		Viewer struct {
+			ID string `json:"id"`
+		} `json:"viewer"`
+	}
+	if err := l.query(ctx, `query Viewer { viewer { id } }`, nil, &data); err != nil {
+		return err
+	}
+	return l.update(ctx, id, map[string]any{"assigneeId": data.Viewer.ID})
+}
+
+// Comment adds a comment to an issue.
+func (l *linear) Comment(ctx context.Context, id, body string) error {
+	const mutation = `mutation CommentCreate($input: CommentCreateInput!) {
+  commentCreate(input: $input) { success }
+}`
+	var data json.RawMessage
+	return l.query(ctx, mutation, map[string]any{"input": map[string]string{"issueId": id, "body": body}}, &data)
+}
+
+// update applies an IssueUpdateInput to an issue.
+func (l *linear) update(ctx context.Context, id string, input map[string]any) error {
+	var data json.RawMessage
+	return l.query(ctx, linearUpdateMutation, map[string]any{"id": id, "input": input}, &data)
+}
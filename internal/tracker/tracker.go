@@ -0,0 +1,225 @@
+// Package tracker looks up tickets in issue trackers such as Jira, GitHub Issues, GitLab and Linear.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrNotFound is returned when the tracker has no ticket with the requested ID.
+var ErrNotFound = errors.New("ticket not found")
+
+// ErrUnauthorized is returned when the tracker rejects the configured credentials.
+var ErrUnauthorized = errors.New("tracker rejected credentials")
+
+// Tracker types accepted in Config.Type.
+const (
+	TypeJira   = "jira"
+	TypeGitHub = "github"
+	TypeGitLab = "gitlab"
+	TypeLinear = "linear"
+)
+
+// DefaultTimeout bounds every request to a tracker so an unreachable tracker doesn't stall commands.
+const DefaultTimeout = 10 * time.Second
+
+// Ticket is a tracker's view of a ticket.
+type Ticket struct {
+	// ID is the ticket identifier as the tracker reports it (e.g., "PROJ-123" or "42").
+	ID string
+
+	// Title is the ticket's summary line.
+	Title string
+
+	// Status is the ticket's workflow state (e.g., "In Progress", "open").
+	Status string
+
+	// Assignee is the display name or username of the assignee, if any.
+	Assignee string
+
+	// URL is a link to the ticket in the tracker's web interface.
+	URL string
+}
+
+// Tracker looks up tickets in an issue tracker.
+type Tracker interface {
+	// Name returns the tracker type (e.g., "jira").
+	Name() string
+
+	// GetTicket fetches a ticket by ID. Returns ErrNotFound if it doesn't exist.
+	GetTicket(ctx context.Context, id string) (*Ticket, error)
+}
+
+// Updater is implemented by trackers that can modify tickets.
+type Updater interface {
+	// Transition moves a ticket to the named workflow state (e.g., "In Progress", "closed").
+	Transition(ctx context.Context, id, state string) error
+
+	// AssignToMe assigns a ticket to the authenticated user.
+	AssignToMe(ctx context.Context, id string) error
+
+	// Comment adds a comment to a ticket.
+	Comment(ctx context.Context, id, body string) error
+}
+
+// Config describes how to reach a tracker.
+type Config struct {
+	// Type is the tracker type: "jira", "github", "gitlab" or "linear".
+	Type string
+
+	// BaseURL is the tracker's base URL. Optional for hosted GitHub, GitLab and Linear.
+	BaseURL string
+
+	// Project identifies the project: "owner/repo" for GitHub, the project path or ID for GitLab.
+	Project string
+
+	// User is the account name for trackers using basic authentication (Jira Cloud).
+	User string
+
+	// Token is the API token.
+	Token string
+
+	// Client is the HTTP client to use. If nil, a client with DefaultTimeout is used.
+	Client *http.Client
+}
+
+// New creates a tracker client from its configuration.
+func New(cfg Config) (Tracker, error) {
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	switch strings.ToLower(cfg.Type) {
+	case TypeJira:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("jira tracker requires a base URL")
+		}
+		return &jira{cfg: cfg}, nil
+	case TypeGitHub:
+		if cfg.Project == "" {
+			return nil, fmt.Errorf("github tracker requires a project (owner/repo)")
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.github.com"
+		}
+		return &github{cfg: cfg}, nil
+	case TypeGitLab:
+		if cfg.Project == "" {
+			return nil, fmt.Errorf("gitlab tracker requires a project")
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://gitlab.com"
+		}
+		return &gitlab{cfg: cfg}, nil
+	case TypeLinear:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.linear.app"
+		}
+		return &linear{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracker type %q", cfg.Type)
+	}
+}
+
+// maxSlugLength is the maximum length of a slug derived from a ticket title.
+const maxSlugLength = 40
+
+// Slug derives a branch-name-safe slug from a ticket title.
+// Slugs are lowercase, hyphen-separated, and at most maxSlugLength characters.
+func Slug(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		// Cut at a word boundary when there is one
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	return slug
+}
+
+// newJSONRequest creates a request with a JSON-encoded body. If body is nil, the request has no body.
+func newJSONRequest(ctx context.Context, method, endpoint string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// doJSON sends req and decodes a JSON response into out.
+// HTTP status codes are mapped to ErrNotFound and ErrUnauthorized where appropriate.
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracker request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracker returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse tracker response: %w", err)
+	}
+	return nil
+}
+
+// issueNumber extracts the trailing number from a ticket ID such as "#42", "42" or "GH-42".
+func issueNumber(id string) (string, error) {
+	end := len(id)
+	start := end
+	for start > 0 && id[start-1] >= '0' && id[start-1] <= '9' {
+		start--
+	}
+	if start == end {
+		return "", fmt.Errorf("ticket %s has no issue number", id)
+	}
+	return id[start:end], nil
+}
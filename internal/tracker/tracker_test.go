@@ -0,0 +1,364 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "secret-token"
+
+// standIn returns a handler that serves a single known ticket for a tracker type,
+// answers 404 for anything else, and 401 for requests without the expected credentials.
+func standIn(t *testing.T, trackerType string) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+
+	switch trackerType {
+	case TypeJira:
+		mux.HandleFunc("/rest/api/2/issue/{key}", func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "me@example.com" || pass != testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.PathValue("key") != "PROJ-123" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{"key":"PROJ-123","fields":{"summary":"Add OAuth login","status":{"name":"To Do"},"assignee":{"displayName":"Pat"}}}`)
+		})
+	case TypeGitHub:
+		mux.HandleFunc("/repos/org/repo/issues/{number}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.PathValue("number") != "123" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{"number":123,"title":"Add OAuth login","state":"open","html_url":"https://github.com/org/repo/issues/123","assignee":{"login":"pat"}}`)
+		})
+	case TypeGitLab:
+		mux.HandleFunc("/api/v4/projects/{project}/issues/{iid}", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("PRIVATE-TOKEN") != testToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.PathValue("project") != "group/repo" || r.PathValue("iid") != "123" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{"iid":123,"title":"Add OAuth login","state":"opened","web_url":"https://gitlab.com/group/repo/-/issues/123","assignee":{"username":"pat"}}`)
+		})
+	case TypeLinear:
+		mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != testToken {
+				io.WriteString(w, `{"errors":[{"message":"Authentication required","extensions":{"code":"AUTHENTICATION_ERROR"}}]}`)
+				return
+			}
+			var body struct {
+				Variables map[string]string `json:"variables"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("invalid GraphQL request: %v", err)
+			}
+			if body.Variables["id"] != "PROJ-123" {
+				io.WriteString(w, `{"data":null,"errors":[{"message":"Entity not found: Issue"}]}`)
+				return
+			}
+			io.WriteString(w, `{"data":{"issue":{"identifier":"PROJ-123","title":"Add OAuth login","url":"https://linear.app/org/issue/PROJ-123","state":{"name":"Todo"},"assignee":{"name":"Pat"}}}}`)
+		})
+	}
+
+	return mux
+}
+
+func TestGetTicket(t *testing.T) {
+	tests := []struct {
+		trackerType string
+		project     string
+		user        string
+		id          string
+		want        Ticket
+	}{
+		{TypeJira, "", "me@example.com", "PROJ-123", Ticket{ID: "PROJ-123", Title: "Add OAuth login", Status: "To Do", Assignee: "Pat"}},
+		{TypeGitHub, "org/repo", "", "#123", Ticket{ID: "123", Title: "Add OAuth login", Status: "open", Assignee: "pat"}},
+		{TypeGitLab, "group/repo", "", "GL-123", Ticket{ID: "123", Title: "Add OAuth login", Status: "opened", Assignee: "pat"}},
+		{TypeLinear, "", "", "PROJ-123", Ticket{ID: "PROJ-123", Title: "Add OAuth login", Status: "Todo", Assignee: "Pat"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.trackerType, func(t *testing.T) {
+			server := httptest.NewServer(standIn(t, tt.trackerType))
+			defer server.Close()
+
+			cfg := Config{Type: tt.trackerType, BaseURL: server.URL, Project: tt.project, User: tt.user, Token: testToken}
+			tr, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			got, err := tr.GetTicket(context.Background(), tt.id)
+			if err != nil {
+				t.Fatalf("GetTicket(%s) failed: %v", tt.id, err)
+			}
+			got.URL = ""
+			if *got != tt.want {
+				t.Errorf("GetTicket(%s) = %+v, want %+v", tt.id, *got, tt.want)
+			}
+
+			// Unknown tickets are reported as not found
+			if _, err := tr.GetTicket(context.Background(), "PROJ-999"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("GetTicket(unknown) error = %v, want ErrNotFound", err)
+			}
+
+			// Bad credentials are reported as unauthorized
+			cfg.Token = "wrong"
+			tr, _ = New(cfg)
+			if _, err := tr.GetTicket(context.Background(), tt.id); !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("GetTicket(bad token) error = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestGetTicketOffline(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	tr, err := New(Config{Type: TypeJira, BaseURL: url})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	_, err = tr.GetTicket(context.Background(), "PROJ-123")
+	if err == nil {
+		t.Fatal("expected error from unreachable tracker")
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("unreachable tracker error = %v, want a connection error", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		wantErr string
+	}{
+		{Config{Type: "jira"}, "base URL"},
+		{Config{Type: "github"}, "project"},
+		{Config{Type: "gitlab"}, "project"},
+		{Config{Type: "redmine"}, "unknown tracker"},
+		{Config{Type: "Linear"}, ""},
+	}
+
+	for _, tt := range tests {
+		_, err := New(tt.cfg)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("New(%s) failed: %v", tt.cfg.Type, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("New(%s) error = %v, want %q", tt.cfg.Type, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Add OAuth login", "add-oauth-login"},
+		{"  Fix: crash when user's name has émojis 🎉!! ", "fix-crash-when-user-s-name-has-mojis"},
+		{"Support HTTP/2 & gRPC", "support-http-2-grpc"},
+		{"Refactor the authentication middleware so it supports pluggable providers", "refactor-the-authentication-middleware"},
+		{"", ""},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Slug(tt.title); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+// recorded is a request captured by a recording stand-in server.
+type recorded struct {
+	method string
+	path   string
+	body   string
+}
+
+// recorder returns a server that records every request and answers with the response
+// registered for its "METHOD /path" (or "POST /graphql <operation>"), or an empty object if there is none.
+func recorder(t *testing.T, responses map[string]string) (*httptest.Server, *[]recorded) {
+	t.Helper()
+	var requests []recorded
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, recorded{r.Method, r.URL.EscapedPath(), string(body)})
+
+		key := r.Method + " " + r.URL.EscapedPath()
+		empty := `{}`
+		if r.URL.Path == "/graphql" {
+			// GraphQL requests are distinguished by operation name
+			var gql struct {
+				Query string `json:"query"`
+			}
+			json.Unmarshal(body, &gql)
+			name := strings.FieldsFunc(gql.Query, func(r rune) bool { return strings.ContainsRune(" ({\n", r) })[1]
+			key = "POST /graphql " + name
+			empty = `{"data":{}}`
+		}
+		if response, ok := responses[key]; ok {
+			io.WriteString(w, response)
+			return
+		}
+		io.WriteString(w, empty)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestUpdater(t *testing.T) {
+	tests := []struct {
+		trackerType string
+		project     string
+		id          string
+		state       string
+		responses   map[string]string
+
+		// want lists the expected "METHOD /path" of each request, in order
+		want []string
+
+		// wantBodies lists substrings expected in the bodies of the mutating requests
+		wantBodies []string
+	}{
+		{
+			trackerType: TypeJira,
+			id:          "PROJ-1",
+			state:       "in progress",
+			responses: map[string]string{
+				"GET /rest/api/2/issue/PROJ-1/transitions": `{"transitions":[{"id":"11","name":"Start","to":{"name":"In Progress"}}]}`,
+				"GET /rest/api/2/myself":                   `{"accountId":"abc"}`,
+			},
+			want: []string{
+				"GET /rest/api/2/issue/PROJ-1/transitions",
+				"POST /rest/api/2/issue/PROJ-1/transitions",
+				"GET /rest/api/2/myself",
+				"PUT /rest/api/2/issue/PROJ-1/assignee",
+				"POST /rest/api/2/issue/PROJ-1/comment",
+			},
+			wantBodies: []string{`"id":"11"`, `"accountId":"abc"`, `"body":"on branch PROJ-1"`},
+		},
+		{
+			trackerType: TypeGitHub,
+			project:     "org/repo",
+			id:          "#7",
+			state:       "closed",
+			responses:   map[string]string{"GET /user": `{"login":"pat"}`},
+			want: []string{
+				"PATCH /repos/org/repo/issues/7",
+				"GET /user",
+				"POST /repos/org/repo/issues/7/assignees",
+				"POST /repos/org/repo/issues/7/comments",
+			},
+			wantBodies: []string{`"state":"closed"`, `"assignees":["pat"]`, `"body":"on branch PROJ-1"`},
+		},
+		{
+			trackerType: TypeGitLab,
+			project:     "group/repo",
+			id:          "7",
+			state:       "closed",
+			responses:   map[string]string{"GET /api/v4/user": `{"id":42}`},
+			want: []string{
+				"PUT /api/v4/projects/group%2Frepo/issues/7",
+				"GET /api/v4/user",
+				"PUT /api/v4/projects/group%2Frepo/issues/7",
+				"POST /api/v4/projects/group%2Frepo/issues/7/notes",
+			},
+			wantBodies: []string{`"state_event":"close"`, `"assignee_ids":[42]`, `"body":"on branch PROJ-1"`},
+		},
+		{
+			trackerType: TypeLinear,
+			id:          "ENG-1",
+			state:       "In Progress",
+			responses: map[string]string{
+				"POST /graphql IssueStates": `{"data":{"issue":{"team":{"states":{"nodes":[{"id":"s1","name":"Todo"},{"id":"s2","name":"In Progress"}]}}}}}`,
+				"POST /graphql Viewer":      `{"data":{"viewer":{"id":"u1"}}}`,
+			},
+			want: []string{
+				"POST /graphql",
+				"POST /graphql",
+				"POST /graphql",
+				"POST /graphql",
+				"POST /graphql",
+			},
+			wantBodies: []string{`"stateId":"s2"`, `"assigneeId":"u1"`, `"body":"on branch PROJ-1"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.trackerType, func(t *testing.T) {
+			server, requests := recorder(t, tt.responses)
+
+			tr, err := New(Config{Type: tt.trackerType, BaseURL: server.URL, Project: tt.project, Token: testToken})
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+			updater, ok := tr.(Updater)
+			if !ok {
+				t.Fatalf("%s tracker does not implement Updater", tt.trackerType)
+			}
+
+			ctx := context.Background()
+			if err := updater.Transition(ctx, tt.id, tt.state); err != nil {
+				t.Errorf("Transition() failed: %v", err)
+			}
+			if err := updater.AssignToMe(ctx, tt.id); err != nil {
+				t.Errorf("AssignToMe() failed: %v", err)
+			}
+			if err := updater.Comment(ctx, tt.id, "on branch PROJ-1"); err != nil {
+				t.Errorf("Comment() failed: %v", err)
+			}
+
+			var got []string
+			var bodies strings.Builder
+			for _, r := range *requests {
+				got = append(got, r.method+" "+r.path)
+				bodies.WriteString(r.body)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("requests =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, want := range tt.wantBodies {
+				if !strings.Contains(bodies.String(), want) {
+					t.Errorf("request bodies missing %s:\n%s", want, bodies.String())
+				}
+			}
+		})
+	}
+}
+
+func TestTransitionUnknownState(t *testing.T) {
+	server, _ := recorder(t, map[string]string{
+		"GET /rest/api/2/issue/PROJ-1/transitions": `{"transitions":[{"id":"11","name":"Start","to":{"name":"In Progress"}}]}`,
+	})
+
+	tr, _ := New(Config{Type: TypeJira, BaseURL: server.URL})
+	err := tr.(Updater).Transition(context.Background(), "PROJ-1", "Done")
+	if err == nil || !strings.Contains(err.Error(), "no transition") {
+		t.Errorf("Transition(Done) error = %v, want no transition available", err)
+	}
+}
@@ -1,80 +1,206 @@
-// Package util provides utility functions for path calculations.
+// Package util provides utility functions for repository discovery and path calculations.
 package util
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/git"
 )
 
-// GetPrimaryRepoPath returns the absolute path to the primary git repository.
-// It searches upward from the current directory to find the .git directory.
-func GetPrimaryRepoPath() (string, error) {
-	cwd, err := os.Getwd()
+// RepoLocation describes where a directory sits within a git repository, as reported by git.
+type RepoLocation struct {
+	// CommonDir is the absolute path to the git directory shared by all worktrees.
+	CommonDir string
+
+	// GitDir is the absolute path to the git directory of the current worktree.
+	// It differs from CommonDir inside a linked worktree.
+	GitDir string
+
+	// TopLevel is the absolute path to the root of the current working tree.
+	// It is empty when not inside a working tree (e.g., inside the .git directory).
+	TopLevel string
+
+	// InsideWorkTree indicates the directory is inside a working tree.
+	InsideWorkTree bool
+
+	// Bare indicates the repository is bare.
+	Bare bool
+}
+
+// notRepositoryError is returned by Locate outside a repository. It matches
+// git.ErrNotRepository, whatever git printed.
+type notRepositoryError struct {
+	err error
+}
+
+func (e notRepositoryError) Error() string {
+	return "not in a git repository"
+}
+
+func (e notRepositoryError) Unwrap() []error {
+	return []error{git.ErrNotRepository, e.err}
+}
+
+// Locate asks git where dir sits within a repository.
+// It honors GIT_DIR, GIT_WORK_TREE and the other variables git itself uses for discovery.
+func Locate(dir string) (*RepoLocation, error) {
+	output, err := revParse(dir, "--git-common-dir", "--git-dir", "--is-inside-work-tree", "--is-bare-repository")
 	if err != nil {
-		return "", fmt.Errorf("failed to get current directory: %w", err)
+		return nil, notRepositoryError{err}
 	}
 
-	// Walk upward to find .git directory
-	dir := cwd
-	for {
-		gitPath := filepath.Join(dir, ".git")
-		info, err := os.Stat(gitPath)
-		if err == nil {
-			// Check if it's a directory (primary repo) or file (worktree)
-			if info.IsDir() {
-				return dir, nil
-			}
-			// If .git is a file, we're in a worktree - read it to find primary repo
-			return getPrimaryRepoFromWorktree(gitPath)
-		}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("unexpected git rev-parse output: %q", output)
+	}
 
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return "", fmt.Errorf("not in a git repository")
+	loc := &RepoLocation{
+		CommonDir:      resolvePath(dir, lines[0]),
+		GitDir:         resolvePath(dir, lines[1]),
+		InsideWorkTree: lines[2] == "true",
+		Bare:           lines[3] == "true",
+	}
+
+	if loc.InsideWorkTree {
+		toplevel, err := revParse(dir, "--show-toplevel")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find working tree root: %w", err)
 		}
-		dir = parent
+		loc.TopLevel = resolvePath(dir, strings.TrimSpace(toplevel))
+	}
+
+	return loc, nil
+}
+
+// IsLinkedWorktree returns true if the location is within a linked worktree rather than the primary one.
+func (l *RepoLocation) IsLinkedWorktree() bool {
+	return l.GitDir != l.CommonDir
+}
+
+// PrimaryPath returns the root of the primary working tree of the repository.
+func (l *RepoLocation) PrimaryPath() (string, error) {
+	// In the primary working tree git already knows the answer. This also covers
+	// GIT_DIR/GIT_WORK_TREE and --separate-git-dir layouts.
+	if !l.IsLinkedWorktree() && l.TopLevel != "" {
+		return l.TopLevel, nil
+	}
+
+	// Submodules and repos with an explicit core.worktree record it in the common config
+	if worktree := configWorktree(l.CommonDir); worktree != "" {
+		return resolvePath(l.CommonDir, worktree), nil
+	}
+
+	// The standard layout keeps the common dir at <primary>/.git
+	if filepath.Base(l.CommonDir) == ".git" {
+		return filepath.Dir(l.CommonDir), nil
+	}
+
+	// Bare hubs have no primary working tree, so the bare repository itself stands in for it.
+	// Inside a linked worktree git reports the worktree as non-bare, so check the common config.
+	if l.Bare || configBare(l.CommonDir) {
+		return l.CommonDir, nil
 	}
+
+	return "", fmt.Errorf("cannot locate the primary worktree for git directory %s", l.CommonDir)
 }
 
-// getPrimaryRepoFromWorktree reads the .git file in a worktree to find the primary repo path.
-func getPrimaryRepoFromWorktree(gitFilePath string) (string, error) {
-	content, err := os.ReadFile(gitFilePath)
+// GetPrimaryRepoPath returns the absolute path to the primary git repository
+// containing the current directory.
+func GetPrimaryRepoPath() (string, error) {
+	cwd, err := os.Getwd()
 	if err != nil {
-		return "", fmt.Errorf("failed to read .git file: %w", err)
+		return "", fmt.Errorf("failed to get current directory: %w", err)
 	}
+	return FindPrimaryRepoPath(cwd)
+}
 
-	// Format: "gitdir: /path/to/primary/.git/worktrees/name"
-	// We need to extract the primary repo path
-	gitdir := string(content)
-	if len(gitdir) < 8 || gitdir[:8] != "gitdir: " {
-		return "", fmt.Errorf("invalid .git file format")
+// FindPrimaryRepoPath returns the absolute path to the primary git repository containing dir.
+func FindPrimaryRepoPath(dir string) (string, error) {
+	loc, err := Locate(dir)
+	if err != nil {
+		return "", err
 	}
 
-	worktreePath := gitdir[8:]
-	worktreePath = filepath.Clean(worktreePath)
+	return loc.PrimaryPath()
+}
+
+// revParse runs git rev-parse with the given arguments in dir.
+func revParse(dir string, args ...string) (string, error) {
+	return git.NewRepo(dir).Run(append([]string{"rev-parse"}, args...)...)
+}
 
-	// Remove trailing newline if present
-	if len(worktreePath) > 0 && worktreePath[len(worktreePath)-1] == '\n' {
-		worktreePath = worktreePath[:len(worktreePath)-1]
+// configWorktree returns the core.worktree setting of a git directory, if any.
+func configWorktree(gitDir string) string {
+	output, err := git.NewRepo("").Run("config", "--file", filepath.Join(gitDir, "config"), "core.worktree")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+// configBare returns true if the git directory is configured as a bare repository.
+func configBare(gitDir string) bool {
+	output, err := git.NewRepo("").Run("config", "--file", filepath.Join(gitDir, "config"), "--bool", "core.bare")
+	if err != nil {
+		return false
 	}
+	return strings.TrimSpace(output) == "true"
+}
 
-	// The path is like: /path/to/primary/.git/worktrees/name
-	// We need to get: /path/to/primary
-	gitDir := filepath.Dir(filepath.Dir(worktreePath))
-	primaryRepo := filepath.Dir(gitDir)
+// resolvePath makes path absolute relative to base and resolves symlinks where possible.
+func resolvePath(base, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// GitCommonDir returns the git directory shared by all worktrees of the repository at repoPath.
+func GitCommonDir(repoPath string) (string, error) {
+	loc, err := Locate(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return loc.CommonDir, nil
+}
 
-	return primaryRepo, nil
+// IsBareRepo returns true if repoPath is a bare repository.
+func IsBareRepo(repoPath string) bool {
+	loc, err := Locate(repoPath)
+	return err == nil && loc.Bare
 }
 
 // GetRepoName returns the basename of the repository.
+// For bare repositories the ".git" suffix is dropped, and hidden hub directories
+// such as <name>/.bare are named after the directory that contains them.
 func GetRepoName(repoPath string) string {
-	return filepath.Base(repoPath)
+	name := filepath.Base(repoPath)
+	if !IsBareRepo(repoPath) {
+		return name
+	}
+	if strings.HasPrefix(name, ".") {
+		return filepath.Base(filepath.Dir(repoPath))
+	}
+	return strings.TrimSuffix(name, ".git")
 }
 
 // GetWorktreeBasePath returns the base path where all worktrees for a repo are stored.
 // Format: <repo-parent>/worktrees/<repo-name>
+//
+// Bare repositories are treated as hubs: worktrees are stored next to the bare
+// repository, in the directory that contains it.
 func GetWorktreeBasePath(repoPath string) string {
+	if IsBareRepo(repoPath) {
+		return filepath.Dir(repoPath)
+	}
+
 	repoParent := filepath.Dir(repoPath)
 	repoName := GetRepoName(repoPath)
 	return filepath.Join(repoParent, "worktrees", repoName)
@@ -93,20 +219,80 @@ func IsInWorktree() (bool, error) {
 		return false, fmt.Errorf("failed to get current directory: %w", err)
 	}
 
-	// Walk upward to find .git
-	dir := cwd
-	for {
-		gitPath := filepath.Join(dir, ".git")
-		info, err := os.Stat(gitPath)
-		if err == nil {
-			// If .git is a file, we're in a worktree
-			return !info.IsDir(), nil
+	loc, err := Locate(cwd)
+	if err != nil {
+		return false, err
+	}
+
+	return loc.IsLinkedWorktree(), nil
+}
+
+// ReadGitLink reads the .git file of a worktree directory and returns the gitdir it points to.
+// Relative gitdir paths are resolved against the worktree directory.
+func ReadGitLink(worktreePath string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(worktreePath, ".git"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read .git file: %w", err)
+	}
+
+	gitdir, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+	if !found {
+		return "", fmt.Errorf("invalid .git file format")
+	}
+
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(worktreePath, gitdir)
+	}
+
+	return filepath.Clean(gitdir), nil
+}
+
+// FindRepositories returns the primary repositories found in root and its subdirectories,
+// up to maxDepth levels down, sorted and without duplicates. Linked worktrees and bare hubs
+// are resolved to the repositories they belong to. Hidden directories aren't searched, and
+// neither are repositories' own subdirectories.
+func FindRepositories(root string, maxDepth int) []string {
+	seen := make(map[string]bool)
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		_, err := os.Stat(filepath.Join(dir, ".git"))
+		if err == nil || looksBare(dir) {
+			if repoPath, err := FindPrimaryRepoPath(dir); err == nil {
+				seen[repoPath] = true
+			}
+			return
+		}
+		if depth >= maxDepth {
+			return
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				walk(filepath.Join(dir, entry.Name()), depth+1)
+			}
 		}
+	}
+	walk(root, 0)
+
+	repos := make([]string, 0, len(seen))
+	for repoPath := range seen {
+		repos = append(repos, repoPath)
+	}
+	sort.Strings(repos)
+	return repos
+}
 
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return false, fmt.Errorf("not in a git repository")
+// looksBare reports whether dir has the layout of a bare repository, so git only needs to
+// be asked about directories that are likely to be one.
+func looksBare(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
 		}
-		dir = parent
 	}
+	return true
 }
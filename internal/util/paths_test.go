@@ -0,0 +1,343 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// isolateGit skips the test if git is unavailable and isolates it from the user's git configuration.
+func isolateGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+// runGit runs a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+// initRepo creates a repository with a single commit at dir.
+func initRepo(t *testing.T, dir string, extra ...string) {
+	t.Helper()
+	runGit(t, filepath.Dir(dir), append([]string{"init", "-q"}, append(extra, dir)...)...)
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "initial")
+}
+
+// bareHub creates a bare hub at <root>/hub with the bare repository in .bare
+// and returns the path to the bare repository.
+func bareHub(t *testing.T, root string) string {
+	t.Helper()
+	src := filepath.Join(root, "src")
+	initRepo(t, src)
+	bare := filepath.Join(root, "hub", ".bare")
+	runGit(t, root, "clone", "-q", "--bare", src, bare)
+	if err := os.WriteFile(filepath.Join(root, "hub", ".git"), []byte("gitdir: ./.bare\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return bare
+}
+
+// realpath resolves symlinks so paths compare equal on systems with symlinked temp dirs.
+func realpath(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", path, err)
+	}
+	return resolved
+}
+
+func TestLocate(t *testing.T) {
+	isolateGit(t)
+
+	tests := []struct {
+		name string
+
+		// setup builds the layout under root and returns the directory to run from,
+		// the expected primary path, and any environment to set.
+		setup func(t *testing.T, root string) (dir, primary string, env map[string]string)
+
+		wantLinked bool
+		wantErr    bool
+	}{
+		{
+			name: "primary root",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				return repo, repo, nil
+			},
+		},
+		{
+			name: "primary subdirectory",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				sub := filepath.Join(repo, "a", "b")
+				if err := os.MkdirAll(sub, 0755); err != nil {
+					t.Fatal(err)
+				}
+				return sub, repo, nil
+			},
+		},
+		{
+			name: "inside .git",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				return filepath.Join(repo, ".git", "objects"), repo, nil
+			},
+		},
+		{
+			name: "linked worktree",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				wt := filepath.Join(root, "worktrees", "repo", "PROJ-1")
+				runGit(t, repo, "worktree", "add", "-q", "-b", "PROJ-1", wt)
+				return wt, repo, nil
+			},
+			wantLinked: true,
+		},
+		{
+			name: "linked worktree with relative gitdir",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				wt := filepath.Join(root, "wt")
+				runGit(t, repo, "worktree", "add", "-q", "-b", "wt", wt)
+				link := []byte("gitdir: ../repo/.git/worktrees/wt\n")
+				if err := os.WriteFile(filepath.Join(wt, ".git"), link, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return wt, repo, nil
+			},
+			wantLinked: true,
+		},
+		{
+			name: "separate git dir",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo, "--separate-git-dir", filepath.Join(root, "repo.git"))
+				return repo, repo, nil
+			},
+		},
+		{
+			name: "GIT_DIR and GIT_WORK_TREE",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				repo := filepath.Join(root, "repo")
+				initRepo(t, repo)
+				elsewhere := filepath.Join(root, "elsewhere")
+				if err := os.MkdirAll(elsewhere, 0755); err != nil {
+					t.Fatal(err)
+				}
+				return elsewhere, repo, map[string]string{
+					"GIT_DIR":       filepath.Join(repo, ".git"),
+					"GIT_WORK_TREE": repo,
+				}
+			},
+		},
+		{
+			name: "submodule",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				lib := filepath.Join(root, "lib")
+				initRepo(t, lib)
+				super := filepath.Join(root, "super")
+				initRepo(t, super)
+				runGit(t, super, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "lib")
+				sub := filepath.Join(super, "lib")
+				return sub, sub, nil
+			},
+		},
+		{
+			name: "submodule git dir",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				lib := filepath.Join(root, "lib")
+				initRepo(t, lib)
+				super := filepath.Join(root, "super")
+				initRepo(t, super)
+				runGit(t, super, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "lib")
+				return filepath.Join(super, ".git", "modules", "lib"), filepath.Join(super, "lib"), nil
+			},
+		},
+		{
+			name: "bare repository",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				bare := bareHub(t, root)
+				return bare, bare, nil
+			},
+		},
+		{
+			name: "bare hub directory",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				bare := bareHub(t, root)
+				return filepath.Dir(bare), bare, nil
+			},
+		},
+		{
+			name: "bare hub worktree",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				bare := bareHub(t, root)
+				wt := filepath.Join(filepath.Dir(bare), "PROJ-1")
+				runGit(t, bare, "worktree", "add", "-q", "-b", "PROJ-1", wt)
+				return wt, bare, nil
+			},
+			wantLinked: true,
+		},
+		{
+			name: "not a repository",
+			setup: func(t *testing.T, root string) (string, string, map[string]string) {
+				return root, "", map[string]string{"GIT_CEILING_DIRECTORIES": filepath.Dir(root)}
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := realpath(t, t.TempDir())
+			dir, primary, env := tt.setup(t, root)
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			loc, err := Locate(dir)
+			if tt.wantErr {
+				if !errors.Is(err, git.ErrNotRepository) {
+					t.Fatalf("expected git.ErrNotRepository, got %+v, %v", loc, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Locate(%s) failed: %v", dir, err)
+			}
+
+			got, err := loc.PrimaryPath()
+			if err != nil {
+				t.Fatalf("PrimaryPath() failed: %v", err)
+			}
+			if want := realpath(t, primary); got != want {
+				t.Errorf("PrimaryPath() = %s, want %s", got, want)
+			}
+
+			if linked := loc.IsLinkedWorktree(); linked != tt.wantLinked {
+				t.Errorf("IsLinkedWorktree() = %v, want %v", linked, tt.wantLinked)
+			}
+		})
+	}
+}
+
+func TestGetPrimaryRepoPath(t *testing.T) {
+	isolateGit(t)
+
+	root := realpath(t, t.TempDir())
+	repo := filepath.Join(root, "repo")
+	initRepo(t, repo)
+	wt := filepath.Join(root, "wt")
+	runGit(t, repo, "worktree", "add", "-q", "-b", "wt", wt)
+
+	t.Chdir(wt)
+
+	got, err := GetPrimaryRepoPath()
+	if err != nil {
+		t.Fatalf("GetPrimaryRepoPath() failed: %v", err)
+	}
+	if got != repo {
+		t.Errorf("GetPrimaryRepoPath() = %s, want %s", got, repo)
+	}
+
+	inWorktree, err := IsInWorktree()
+	if err != nil {
+		t.Fatalf("IsInWorktree() failed: %v", err)
+	}
+	if !inWorktree {
+		t.Errorf("IsInWorktree() = false, want true")
+	}
+}
+
+func TestGetWorktreeBasePath(t *testing.T) {
+	isolateGit(t)
+
+	root := realpath(t, t.TempDir())
+	repo := filepath.Join(root, "myrepo")
+	initRepo(t, repo)
+	bare := bareHub(t, root)
+	named := filepath.Join(root, "other.git")
+	runGit(t, root, "clone", "-q", "--bare", repo, named)
+
+	tests := []struct {
+		name     string
+		repoPath string
+		wantBase string
+		wantName string
+	}{
+		{"primary checkout", repo, filepath.Join(root, "worktrees", "myrepo"), "myrepo"},
+		{"bare hub", bare, filepath.Join(root, "hub"), "hub"},
+		{"bare repository", named, root, "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetWorktreeBasePath(tt.repoPath); got != tt.wantBase {
+				t.Errorf("GetWorktreeBasePath() = %s, want %s", got, tt.wantBase)
+			}
+			if got := GetRepoName(tt.repoPath); got != tt.wantName {
+				t.Errorf("GetRepoName() = %s, want %s", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestFindRepositories(t *testing.T) {
+	isolateGit(t)
+
+	root := realpath(t, t.TempDir())
+	repo := filepath.Join(root, "code", "repo")
+	if err := os.MkdirAll(filepath.Dir(repo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	initRepo(t, repo)
+	runGit(t, repo, "worktree", "add", "-q", "-b", "wt", filepath.Join(root, "code", "worktrees", "wt"))
+	bare := bareHub(t, root)
+	named := filepath.Join(root, "other.git")
+	runGit(t, root, "clone", "-q", "--bare", repo, named)
+	deep := filepath.Join(root, "a", "b", "c", "deep")
+	if err := os.MkdirAll(filepath.Dir(deep), 0755); err != nil {
+		t.Fatal(err)
+	}
+	initRepo(t, deep)
+
+	got := FindRepositories(root, 3)
+	want := []string{bare, repo, named, filepath.Join(root, "src")}
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("FindRepositories() = %v, want %v", got, want)
+	}
+
+	if got := FindRepositories(root, 4); len(got) != len(want)+1 {
+		t.Errorf("FindRepositories() with a deeper search = %v", got)
+	}
+}
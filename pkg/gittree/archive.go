@@ -0,0 +1,167 @@
+package gittree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// ArchiveOptions configures Archive.
+type ArchiveOptions struct {
+	// Force archives a worktree with ignored files, such as build output, which aren't
+	// saved and are deleted with the worktree.
+	Force bool
+}
+
+// Archive frees the disk space used by a ticket's worktree without losing its state: its
+// uncommitted changes, including untracked files, are saved in ArchiveRef, the worktree
+// directory is removed, and the branch and metadata are kept. It returns the archived
+// worktree. Ignored files aren't saved, so a worktree with any is only archived with
+// opts.Force.
+func (m *Manager) Archive(ticketID string, opts ArchiveOptions) (Worktree, error) {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+	if entry.IsArchived() {
+		return Worktree{}, fmt.Errorf("worktree for %s is already archived", id)
+	}
+	return m.transition("archive", id, entry, func(ticketID string, entry config.WorktreeEntry) (config.WorktreeEntry, error) {
+		return m.archiveWorktree(ticketID, entry, opts.Force)
+	})
+}
+
+// Restore recreates an archived worktree at its original path and reapplies its saved
+// changes, returning the restored worktree. If the changes don't apply cleanly, the
+// worktree is still restored and the changes stay in ArchiveRef.
+func (m *Manager) Restore(ticketID string) (Worktree, error) {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+	if !entry.IsArchived() {
+		return Worktree{}, fmt.Errorf("worktree for %s is not archived", id)
+	}
+	return m.transition("restore", id, entry, m.restoreWorktree)
+}
+
+// transition archives or restores a worktree with fn, saves the metadata and records the
+// change in the journal as command.
+func (m *Manager) transition(command, ticketID string, entry config.WorktreeEntry, fn func(string, config.WorktreeEntry) (config.WorktreeEntry, error)) (Worktree, error) {
+	updated, err := fn(ticketID, entry)
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	m.meta.Worktrees[ticketID] = updated
+	if err := m.save(); err != nil {
+		return Worktree{}, err
+	}
+
+	m.record(&config.Operation{
+		Command:        command,
+		Args:           []string{ticketID},
+		MainlineBefore: m.meta.Mainline,
+		MainlineAfter:  m.meta.Mainline,
+		Changes: []config.Change{{
+			Ticket: ticketID,
+			Before: entryRef(entry),
+			After:  entryRef(updated),
+		}},
+	})
+	return newWorktree(updated), nil
+}
+
+// archiveWorktree saves a worktree's uncommitted changes and removes its directory,
+// returning the archived entry. Unless force is set, it refuses if the worktree has
+// ignored files, which would be lost.
+func (m *Manager) archiveWorktree(ticketID string, entry config.WorktreeEntry, force bool) (config.WorktreeEntry, error) {
+	if entry.Stash != "" {
+		return entry, fmt.Errorf("changes saved by an earlier archive are still in %s, apply them with 'git stash apply %s' and delete the ref first", config.ArchiveRef(ticketID), entry.Stash)
+	}
+
+	wtRepo := m.repo.WithPath(entry.Path)
+	if !force {
+		ignored, err := wtRepo.IgnoredFiles()
+		if err != nil {
+			return entry, err
+		}
+		if len(ignored) > 0 {
+			return entry, newError(ErrDirty, ticketID, "worktree at %s has ignored files that archiving would delete: %s (use --force to delete them)", entry.Path, strings.Join(ignored, ", "))
+		}
+	}
+
+	m.logf("Saving uncommitted changes in %s...\n", entry.Path)
+	stash, err := wtRepo.StashAll("git-tree archive " + ticketID)
+	if err != nil {
+		return entry, err
+	}
+	if stash != "" {
+		if err := m.repo.UpdateRef(config.ArchiveRef(ticketID), stash); err != nil {
+			return entry, restoreStash(wtRepo, stash, err)
+		}
+	}
+
+	m.logf("Removing worktree at %s...\n", entry.Path)
+	if err := m.repo.RemoveWorktree(entry.Path); err != nil {
+		err = restoreStash(wtRepo, stash, err)
+		if stash != "" {
+			if refErr := m.repo.DeleteRef(config.ArchiveRef(ticketID)); refErr != nil {
+				err = fmt.Errorf("%w (%s could not be deleted: %v)", err, config.ArchiveRef(ticketID), refErr)
+			}
+		}
+		return entry, err
+	}
+
+	entry.Archived = m.now()
+	entry.Stash = stash
+	return entry, nil
+}
+
+// restoreStash puts back changes stashed by a failed archive and returns the original error.
+func restoreStash(wtRepo *git.Repo, stash string, cause error) error {
+	if stash == "" {
+		return cause
+	}
+	if err := wtRepo.StashApply(stash); err != nil {
+		return fmt.Errorf("%w (saved changes could not be reapplied, recover them with: git stash apply %s)", cause, stash)
+	}
+	return cause
+}
+
+// restoreWorktree checks out an archived worktree's branch at its original path and
+// reapplies the saved changes, returning the restored entry. If the changes don't apply
+// cleanly, the worktree is still restored and the changes stay in the archive ref.
+func (m *Manager) restoreWorktree(ticketID string, entry config.WorktreeEntry) (config.WorktreeEntry, error) {
+	if _, err := os.Stat(entry.Path); err == nil {
+		return entry, newError(ErrExists, entry.Ticket, "path already exists: %s", entry.Path)
+	}
+	if !m.repo.BranchExists(entry.Branch) {
+		return entry, fmt.Errorf("branch %s no longer exists", entry.Branch)
+	}
+
+	m.logf("Recreating worktree at %s...\n", entry.Path)
+	if err := m.repo.AttachWorktree(entry.Path, entry.Branch); err != nil {
+		return entry, err
+	}
+	entry.Archived = time.Time{}
+
+	if entry.Stash != "" {
+		m.logf("Reapplying saved changes...\n")
+		ref := config.ArchiveRef(ticketID)
+		if err := m.repo.WithPath(entry.Path).StashApply(entry.Stash); err != nil {
+			m.logf("Warning: saved changes did not apply cleanly and were kept in %s: %v\n", ref, err)
+			return entry, nil
+		}
+		if err := m.repo.DeleteRef(ref); err != nil {
+			m.logf("Warning: %v\n", err)
+		}
+		entry.Stash = ""
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,122 @@
+package gittree
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/tracker"
+)
+
+// CreateOptions configures Create.
+type CreateOptions struct {
+	// Branch is the name of the branch to create. It defaults to the configured branch
+	// template.
+	Branch string
+
+	// Lookup, if set, fetches the ticket's details once the ticket ID has been validated.
+	// An error stops the creation. The details are stored with the worktree, and their
+	// title fills in the {slug} of the branch template.
+	Lookup func(ticketID string) (*TicketInfo, error)
+
+	// Path is the directory to create the worktree in. It defaults to the configured
+	// worktree location.
+	Path string
+
+	// Workspace is recorded as the name of the workspace the worktree belongs to.
+	Workspace string
+}
+
+// Create creates a branch for a ticket from the latest mainline and checks it out in a new
+// worktree. The ticket ID is normalized and validated as configured, and the mainline
+// branch is detected if it hasn't been yet.
+func (m *Manager) Create(ticketID string, opts CreateOptions) (Worktree, error) {
+	// Normalize and validate the ticket ID before it is used in any path or branch name
+	ticketID, err := m.settings.NormalizeTicket(ticketID)
+	if err != nil {
+		return Worktree{}, wrapError(err)
+	}
+
+	// Check if worktree already exists, in any case
+	if existing, ok := m.meta.FindWorktree(ticketID); ok {
+		entry := m.meta.Worktrees[existing]
+		return Worktree{}, newError(ErrExists, existing, "worktree for %s already exists at %s", existing, entry.Path)
+	}
+
+	var info *TicketInfo
+	if opts.Lookup != nil {
+		if info, err = opts.Lookup(ticketID); err != nil {
+			return Worktree{}, err
+		}
+	}
+
+	branchName := opts.Branch
+	if branchName == "" {
+		slug := ""
+		if info != nil {
+			slug = tracker.Slug(info.Title)
+		}
+		branchName = m.settings.BranchName(ticketID, slug)
+	}
+
+	mainlineBefore := m.meta.Mainline
+
+	// Detect mainline if not set
+	if m.meta.Mainline == "" {
+		m.logf("Detecting mainline branch...\n")
+		mainline, err := m.repo.DetectMainline()
+		if err != nil {
+			return Worktree{}, fmt.Errorf("failed to detect mainline branch: %w", err)
+		}
+		m.meta.Mainline = mainline
+		m.logf("Detected mainline: %s\n", mainline)
+	}
+
+	if err := m.Fetch(); err != nil {
+		return Worktree{}, err
+	}
+
+	// Check if path already exists
+	worktreePath := opts.Path
+	if worktreePath == "" {
+		worktreePath = m.settings.WorktreePath(m.repoPath, ticketID)
+	}
+	if _, err := os.Stat(worktreePath); err == nil {
+		return Worktree{}, newError(ErrExists, ticketID, "path already exists: %s", worktreePath)
+	}
+
+	// Create worktree
+	m.logf("Creating worktree at %s...\n", worktreePath)
+	if err := m.repo.AddWorktree(worktreePath, branchName, m.MainlineRef()); err != nil {
+		return Worktree{}, err
+	}
+
+	// Save metadata
+	m.meta.AddWorktree(ticketID, worktreePath, branchName, m.now())
+	entry := m.meta.Worktrees[ticketID]
+	entry.TicketInfo = info.ticketInfo()
+	entry.Workspace = opts.Workspace
+	m.meta.Worktrees[ticketID] = entry
+	if err := m.save(); err != nil {
+		return Worktree{}, err
+	}
+
+	args := []string{ticketID}
+	if opts.Branch != "" {
+		args = append(args, opts.Branch)
+	}
+	tip, _ := m.repo.ResolveCommit(branchName)
+	m.record(&config.Operation{
+		Command:        "create",
+		Args:           args,
+		MainlineBefore: mainlineBefore,
+		MainlineAfter:  m.meta.Mainline,
+		Changes: []config.Change{{
+			Ticket:   ticketID,
+			After:    entryRef(entry),
+			TipAfter: tip,
+		}},
+	})
+
+	return newWorktree(entry), nil
+}
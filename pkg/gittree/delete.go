@@ -0,0 +1,170 @@
+package gittree
+
+import (
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// DeleteOptions configures Delete.
+type DeleteOptions struct {
+	// Force discards uncommitted changes in the worktree.
+	Force bool
+}
+
+// Delete removes a ticket's worktree and deletes its local branch, or for an archived
+// worktree, its branch and saved changes. A worktree with uncommitted changes is only
+// removed with opts.Force; otherwise the error is an ErrDirty. It returns the removed
+// worktree.
+func (m *Manager) Delete(ticketID string, opts DeleteOptions) (Worktree, error) {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	// Check if worktree has uncommitted changes
+	clean, err := m.repo.WithPath(entry.Path).IsClean()
+	dirty := err == nil && !clean
+	if dirty && !opts.Force {
+		return Worktree{}, newError(ErrDirty, id, "worktree for %s has uncommitted changes", id)
+	}
+
+	var tip string
+	if entry.IsArchived() {
+		tip = m.removeArchived(id, entry)
+	} else if tip, err = m.removeWorktree(entry, dirty); err != nil {
+		return Worktree{}, err
+	}
+
+	m.meta.RemoveWorktree(id)
+	if err := m.save(); err != nil {
+		return Worktree{}, err
+	}
+
+	m.record(&config.Operation{
+		Command:        "delete",
+		Args:           []string{ticketID},
+		MainlineBefore: m.meta.Mainline,
+		MainlineAfter:  m.meta.Mainline,
+		Changes: []config.Change{{
+			Ticket:    id,
+			Before:    entryRef(entry),
+			TipBefore: tip,
+		}},
+	})
+	return newWorktree(entry), nil
+}
+
+// CleanOptions configures Clean.
+type CleanOptions struct {
+	// Inactive is how long a worktree must have been inactive to be removed.
+	Inactive time.Duration
+
+	// Confirm, if set, is called with the worktrees about to be removed, if there are
+	// any. Nothing is removed unless it returns true, and an error stops the clean.
+	Confirm func(candidates []Status) (bool, error)
+}
+
+// Clean removes the worktrees, and their branches, that haven't been active for longer than
+// opts.Inactive. Worktrees with uncommitted changes are always kept. It returns the
+// removed worktrees; one that can't be removed is skipped with a warning.
+func (m *Manager) Clean(opts CleanOptions) ([]Worktree, error) {
+	now := m.now()
+	var candidates []Status
+	for _, ticketID := range m.meta.Tickets() {
+		result := m.inspect(m.meta.Worktrees[ticketID], "")
+		if now.Sub(result.LastActive) < opts.Inactive {
+			continue
+		}
+		switch result.State {
+		case StateDirty:
+			m.logf("Keeping %s: worktree has uncommitted changes\n", ticketID)
+			continue
+		case StateUnknown:
+			m.logf("Keeping %s: cannot read worktree status\n", ticketID)
+			continue
+		case StateArchived:
+			continue
+		}
+		candidates = append(candidates, result)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	if opts.Confirm != nil {
+		if ok, err := opts.Confirm(candidates); !ok || err != nil {
+			return nil, err
+		}
+	}
+
+	var removed []Worktree
+	var changes []config.Change
+	for _, c := range candidates {
+		entry := m.meta.Worktrees[c.Ticket]
+		tip, err := m.removeWorktree(entry, false)
+		if err != nil {
+			m.logf("Warning: skipping %s: %v\n", c.Ticket, err)
+			continue
+		}
+		m.meta.RemoveWorktree(c.Ticket)
+		removed = append(removed, c.Worktree)
+		changes = append(changes, config.Change{Ticket: c.Ticket, Before: entryRef(entry), TipBefore: tip})
+	}
+
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+
+	m.record(&config.Operation{
+		Command:        "clean",
+		Args:           []string{"--inactive", opts.Inactive.String()},
+		MainlineBefore: m.meta.Mainline,
+		MainlineAfter:  m.meta.Mainline,
+		Changes:        changes,
+	})
+	return removed, nil
+}
+
+// removeWorktree removes a worktree and deletes its branch, returning the branch tip
+// so the removal can be undone. With force, uncommitted changes are discarded.
+func (m *Manager) removeWorktree(entry config.WorktreeEntry, force bool) (string, error) {
+	// Record the branch tip so the deletion can be undone
+	tip, _ := m.repo.ResolveCommit(entry.Branch)
+
+	m.logf("Removing worktree at %s...\n", entry.Path)
+	remove := m.repo.RemoveWorktree
+	if force {
+		remove = m.repo.ForceRemoveWorktree
+	}
+	if err := remove(entry.Path); err != nil {
+		return "", err
+	}
+
+	m.logf("Deleting branch %s...\n", entry.Branch)
+	if err := m.repo.DeleteBranch(entry.Branch); err != nil {
+		// Don't fail if branch deletion fails (might be merged/deleted already)
+		m.logf("Warning: failed to delete branch: %v\n", err)
+	}
+
+	return tip, nil
+}
+
+// removeArchived deletes the branch and saved changes of an archived worktree, returning
+// the branch tip so the removal can be undone.
+func (m *Manager) removeArchived(ticketID string, entry config.WorktreeEntry) string {
+	tip, _ := m.repo.ResolveCommit(entry.Branch)
+
+	m.logf("Deleting branch %s...\n", entry.Branch)
+	if err := m.repo.DeleteBranch(entry.Branch); err != nil {
+		m.logf("Warning: failed to delete branch: %v\n", err)
+	}
+	if entry.Stash != "" {
+		m.logf("Deleting saved changes in %s...\n", config.ArchiveRef(ticketID))
+		if err := m.repo.DeleteRef(config.ArchiveRef(ticketID)); err != nil {
+			m.logf("Warning: %v\n", err)
+		}
+	}
+
+	return tip
+}
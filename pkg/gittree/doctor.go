@@ -0,0 +1,432 @@
+package gittree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// Problem categories reported by Doctor.
+const (
+	ProblemMissing        = "missing"
+	ProblemMoved          = "moved"
+	ProblemBranchMismatch = "branch-mismatch"
+	ProblemDetached       = "detached"
+	ProblemBrokenLink     = "broken-link"
+	ProblemUnregistered   = "unregistered"
+	ProblemLocked         = "locked"
+	ProblemUntracked      = "untracked"
+	ProblemMainline       = "mainline"
+	ProblemArchived       = "archived"
+)
+
+// Problem is an inconsistency between the metadata, git and the filesystem found by
+// Doctor.
+type Problem struct {
+	// Category is one of the Problem constants.
+	Category string
+
+	// Ticket is the ticket the problem concerns, if any.
+	Ticket string
+
+	// Message describes the problem.
+	Message string
+
+	// Fixable reports whether the problem can be repaired safely.
+	Fixable bool
+
+	// Fixed reports whether Doctor repaired the problem, and FixErr why it couldn't if the
+	// repair failed.
+	Fixed  bool
+	FixErr error
+
+	// fix repairs the problem, or is nil if it can't be repaired safely.
+	fix func() error
+}
+
+// Result describes the outcome of repairing the problem: "fixed", "manual" if it has to
+// be repaired by hand, or "failed: " and the first line of the error.
+func (p Problem) Result() string {
+	switch {
+	case p.Fixed:
+		return "fixed"
+	case p.FixErr != nil:
+		return "failed: " + firstLine(p.FixErr.Error())
+	default:
+		return "manual"
+	}
+}
+
+// Doctor cross-checks the metadata against git and the filesystem, and returns the
+// problems found: missing or untracked worktrees, branch mismatches, missing branches and
+// similar. With fix, the problems that can be repaired safely are repaired, and the
+// metadata changes saved and recorded in the journal. A branch mismatch is repaired by
+// checking out the recorded branch again.
+func (m *Manager) Doctor(fix bool) ([]Problem, error) {
+	problems, err := m.diagnose()
+	if err != nil {
+		return nil, err
+	}
+	for i := range problems {
+		problems[i].Fixable = problems[i].fix != nil
+	}
+	if !fix || len(problems) == 0 {
+		return problems, nil
+	}
+
+	// Snapshot entries so repaired metadata can be journaled
+	before := make(map[string]config.WorktreeEntry, len(m.meta.Worktrees))
+	for ticketID, entry := range m.meta.Worktrees {
+		before[ticketID] = entry
+	}
+	mainlineBefore := m.meta.Mainline
+
+	for i := range problems {
+		if p := &problems[i]; p.fix != nil {
+			p.FixErr = p.fix()
+			p.Fixed = p.FixErr == nil
+		}
+	}
+
+	if err := m.save(); err != nil {
+		return problems, err
+	}
+	if changes := metadataChanges(before, m.meta.Worktrees); len(changes) > 0 || mainlineBefore != m.meta.Mainline {
+		m.record(&config.Operation{
+			Command:        "doctor",
+			Args:           []string{"--fix"},
+			MainlineBefore: mainlineBefore,
+			MainlineAfter:  m.meta.Mainline,
+			Changes:        changes,
+		})
+	}
+	return problems, nil
+}
+
+// diagnose finds inconsistencies between metadata, git's worktree list and the filesystem.
+func (m *Manager) diagnose() ([]Problem, error) {
+	repo, meta := m.repo, m.meta
+	worktrees, err := repo.ListWorktrees()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	registered := make(map[string]git.WorktreeInfo)
+	for _, wt := range worktrees {
+		absPath, err := filepath.Abs(wt.Path)
+		if err == nil {
+			registered[absPath] = wt
+		}
+	}
+
+	tracked := make(map[string]bool)
+	for _, entry := range meta.Worktrees {
+		if absPath, err := filepath.Abs(entry.Path); err == nil {
+			tracked[absPath] = true
+		}
+	}
+
+	// Worktree directories that exist on disk but aren't registered at their current path,
+	// keyed by the path git last knew them at
+	moved, unregistered := scanUnregistered(m.settings.WorktreeBasePath(repo.Path), registered)
+
+	var problems []Problem
+
+	for _, ticketID := range meta.Tickets() {
+		entry := meta.Worktrees[ticketID]
+		if entry.IsArchived() {
+			problems = append(problems, checkArchived(repo, ticketID, entry)...)
+			continue
+		}
+
+		absPath, err := filepath.Abs(entry.Path)
+		if err != nil {
+			continue
+		}
+
+		_, statErr := os.Stat(absPath)
+		exists := statErr == nil
+
+		wt, isRegistered := registered[absPath]
+		if isRegistered && !wt.Prunable {
+			problems = append(problems, checkRegistered(repo, meta, ticketID, wt)...)
+			continue
+		}
+
+		if newPath, ok := moved[absPath]; ok && !exists {
+			problems = append(problems, Problem{
+				Category: ProblemMoved,
+				Ticket:   ticketID,
+				Message:  fmt.Sprintf("worktree moved from %s to %s", entry.Path, newPath),
+				fix: func() error {
+					if err := repo.RepairWorktrees(newPath); err != nil {
+						return err
+					}
+					entry.Path = newPath
+					meta.Worktrees[ticketID] = entry
+					return nil
+				},
+			})
+			delete(unregistered, newPath)
+			continue
+		}
+
+		if exists {
+			p := Problem{
+				Category: ProblemUnregistered,
+				Ticket:   ticketID,
+				Message:  fmt.Sprintf("%s exists but is not a registered worktree", entry.Path),
+			}
+			if _, err := util.ReadGitLink(absPath); err == nil {
+				p.fix = func() error { return repo.RepairWorktrees(absPath) }
+			}
+			problems = append(problems, p)
+			delete(unregistered, absPath)
+			continue
+		}
+
+		problems = append(problems, Problem{
+			Category: ProblemMissing,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf("%s no longer exists", entry.Path),
+			fix: func() error {
+				meta.RemoveWorktree(ticketID)
+				return repo.PruneWorktrees()
+			},
+		})
+	}
+
+	// Directories in the worktree root that git doesn't know about
+	for _, path := range sortedKeys(unregistered) {
+		problems = append(problems, Problem{
+			Category: ProblemUnregistered,
+			Message:  fmt.Sprintf("%s looks like a worktree but is not registered", path),
+			fix:      func() error { return repo.RepairWorktrees(path) },
+		})
+	}
+
+	// Registered worktrees without metadata. The mainline checkout of a bare hub is expected.
+	for _, wt := range worktrees {
+		absPath, err := filepath.Abs(wt.Path)
+		if err != nil || wt.IsPrimary || wt.Bare || wt.Prunable || tracked[absPath] {
+			continue
+		}
+		if meta.Mainline != "" && wt.Branch == meta.Mainline {
+			continue
+		}
+		problems = append(problems, Problem{
+			Category: ProblemUntracked,
+			Message:  fmt.Sprintf("worktree %s (%s) has no metadata entry", wt.Path, wt.Branch),
+		})
+	}
+
+	if p, ok := checkMainline(repo, meta); ok {
+		problems = append(problems, p)
+	}
+
+	return problems, nil
+}
+
+// checkArchived checks that an archived worktree's branch and saved changes still exist.
+// Neither can be repaired automatically.
+func checkArchived(repo *git.Repo, ticketID string, entry config.WorktreeEntry) []Problem {
+	var problems []Problem
+	if !repo.BranchExists(entry.Branch) {
+		problems = append(problems, Problem{
+			Category: ProblemArchived,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf("branch %s of the archived worktree no longer exists", entry.Branch),
+		})
+	}
+	if entry.Stash != "" {
+		if commit, err := repo.ResolveCommit(config.ArchiveRef(ticketID)); err != nil || commit != entry.Stash {
+			problems = append(problems, Problem{
+				Category: ProblemArchived,
+				Ticket:   ticketID,
+				Message:  fmt.Sprintf("saved changes in %s are missing", config.ArchiveRef(ticketID)),
+			})
+		}
+	}
+	if _, err := os.Stat(entry.Path); err == nil {
+		problems = append(problems, Problem{
+			Category: ProblemArchived,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf("worktree is archived but %s exists", entry.Path),
+		})
+	}
+	return problems
+}
+
+// checkRegistered checks a metadata entry whose path is a registered worktree.
+func checkRegistered(repo *git.Repo, meta *config.Metadata, ticketID string, wt git.WorktreeInfo) []Problem {
+	entry := meta.Worktrees[ticketID]
+	var problems []Problem
+
+	if wt.Locked {
+		message := "worktree is locked"
+		if wt.LockReason != "" {
+			message = fmt.Sprintf("worktree is locked: %s", wt.LockReason)
+		}
+		problems = append(problems, Problem{Category: ProblemLocked, Ticket: ticketID, Message: message})
+	}
+
+	if gitdir, err := util.ReadGitLink(wt.Path); err != nil {
+		problems = append(problems, Problem{
+			Category: ProblemBrokenLink,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf(".git link is unreadable: %v", err),
+			fix:      func() error { return repo.RepairWorktrees(wt.Path) },
+		})
+	} else if _, err := os.Stat(gitdir); err != nil {
+		problems = append(problems, Problem{
+			Category: ProblemBrokenLink,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf(".git link points to missing %s", gitdir),
+			fix:      func() error { return repo.RepairWorktrees(wt.Path) },
+		})
+	}
+
+	if wt.Detached {
+		problems = append(problems, Problem{
+			Category: ProblemDetached,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf("HEAD is detached (expected branch %s)", entry.Branch),
+		})
+	} else if wt.Branch != entry.Branch {
+		p := Problem{
+			Category: ProblemBranchMismatch,
+			Ticket:   ticketID,
+			Message:  fmt.Sprintf("metadata says %s but %s is checked out", entry.Branch, wt.Branch),
+		}
+		// The recorded branch is the ticket's, so it is checked out again rather than the
+		// metadata changed to match whatever branch is checked out, which delete would
+		// then remove. Without the recorded branch, the user has to sort it out.
+		if repo.BranchExists(entry.Branch) {
+			p.fix = func() error {
+				worktree := repo.WithPath(wt.Path)
+				if clean, err := worktree.IsClean(); err != nil || !clean {
+					return fmt.Errorf("worktree has uncommitted changes")
+				}
+				return worktree.Checkout(entry.Branch)
+			}
+		}
+		problems = append(problems, p)
+	}
+
+	return problems
+}
+
+// checkMainline verifies the recorded mainline still exists on the remote.
+func checkMainline(repo *git.Repo, meta *config.Metadata) (Problem, bool) {
+	if meta.Mainline == "" {
+		return Problem{}, false
+	}
+
+	exists, err := repo.RemoteBranchExists(meta.Mainline)
+	if err != nil {
+		// Remote unreachable, fall back to the remote-tracking branch
+		_, err := repo.ResolveCommit(repo.Remote + "/" + meta.Mainline)
+		exists = err == nil
+	}
+	if exists {
+		return Problem{}, false
+	}
+
+	return Problem{
+		Category: ProblemMainline,
+		Message:  fmt.Sprintf("mainline %s no longer exists on %s", meta.Mainline, repo.Remote),
+		fix: func() error {
+			if err := repo.Fetch(); err != nil {
+				return err
+			}
+			mainline, err := repo.DetectMainline()
+			if err != nil {
+				return err
+			}
+			if mainline == meta.Mainline {
+				return fmt.Errorf("detected mainline is still %s", mainline)
+			}
+			meta.Mainline = mainline
+			return nil
+		},
+	}, true
+}
+
+// scanUnregistered looks for worktree directories under basePath that are not registered.
+// Directories whose gitdir link is still valid were moved by hand and are returned keyed
+// by the path git last recorded for them; the rest are returned as unregistered.
+func scanUnregistered(basePath string, registered map[string]git.WorktreeInfo) (map[string]string, map[string]bool) {
+	moved := make(map[string]string)
+	unregistered := make(map[string]bool)
+
+	dirEntries, err := os.ReadDir(basePath)
+	if err != nil {
+		return moved, unregistered
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		path := filepath.Join(basePath, dirEntry.Name())
+		if wt, ok := registered[path]; ok && !wt.Prunable {
+			continue
+		}
+
+		gitdir, err := util.ReadGitLink(path)
+		if err != nil {
+			continue
+		}
+
+		// The worktree's admin directory records where git thinks the worktree lives
+		content, err := os.ReadFile(filepath.Join(gitdir, "gitdir"))
+		if err != nil {
+			unregistered[path] = true
+			continue
+		}
+		oldPath := filepath.Dir(strings.TrimSpace(string(content)))
+		if oldPath != path {
+			moved[oldPath] = path
+		}
+		unregistered[path] = true
+	}
+
+	return moved, unregistered
+}
+
+// metadataChanges returns the journal changes between two sets of metadata entries.
+func metadataChanges(before, after map[string]config.WorktreeEntry) []config.Change {
+	var changes []config.Change
+	for ticketID, old := range before {
+		current, ok := after[ticketID]
+		if !ok {
+			changes = append(changes, config.Change{Ticket: ticketID, Before: entryRef(old)})
+		} else if !reflect.DeepEqual(current, old) {
+			changes = append(changes, config.Change{Ticket: ticketID, Before: entryRef(old), After: entryRef(current)})
+		}
+	}
+	for ticketID, current := range after {
+		if _, ok := before[ticketID]; !ok {
+			changes = append(changes, config.Change{Ticket: ticketID, After: entryRef(current)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Ticket < changes[j].Ticket })
+	return changes
+}
+
+// sortedKeys returns the keys of a set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package gittree
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Errors returned by a Manager, usually wrapped in an *Error. Check for them with errors.Is.
+var (
+	// ErrNotFound means a ticket has no worktree.
+	ErrNotFound = config.ErrNotFound
+
+	// ErrExists means a ticket already has a worktree, or its directory already exists.
+	ErrExists = config.ErrExists
+
+	// ErrArchived means a worktree is archived, so it has no directory to work in.
+	ErrArchived = config.ErrArchived
+
+	// ErrNoMainline means the mainline branch hasn't been detected yet.
+	ErrNoMainline = config.ErrNoMainline
+
+	// ErrInvalidTicket means a ticket ID can't be used, or doesn't match the configured patterns.
+	ErrInvalidTicket = config.ErrInvalidTicket
+
+	// ErrInvalidSettings means the settings in git config, such as a workspace, are invalid.
+	ErrInvalidSettings = config.ErrInvalidSettings
+
+	// ErrNotRepository means the directory a Manager was opened in isn't in a git repository.
+	ErrNotRepository = git.ErrNotRepository
+
+	// ErrDirty means a worktree has uncommitted changes.
+	ErrDirty = git.ErrDirty
+
+	// ErrConflict means a rebase stopped on conflicts.
+	ErrConflict = git.ErrConflict
+
+	// ErrRebase means rebasing a worktree onto the mainline failed, usually because of
+	// conflicts, when it also matches ErrConflict. The worktree is left mid-rebase by
+	// Update and restored by UpdateAll.
+	ErrRebase = errors.New("rebase failed")
+)
+
+// Error describes a failure that callers may want to handle, such as a ticket without a
+// worktree. Its Kind is one of the Err variables above, and its Ticket the ticket concerned.
+type Error struct {
+	// Kind is the sentinel error the failure is an instance of.
+	Kind error
+
+	// Ticket is the ticket the failure concerns, if any.
+	Ticket string
+
+	// Err is the underlying error, if any.
+	Err error
+
+	// Message describes the failure. If it is empty, the underlying error's message is used.
+	Message string
+}
+
+// Error returns the message.
+func (e *Error) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil:
+		return e.Err.Error()
+	default:
+		return e.Kind.Error()
+	}
+}
+
+// Unwrap returns the kind of error and the underlying error, so errors.Is and errors.As
+// match either.
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// newError returns an *Error of a kind with a formatted message.
+func newError(kind error, ticketID string, format string, args ...any) *Error {
+	return &Error{Kind: kind, Ticket: ticketID, Message: fmt.Sprintf(format, args...)}
+}
+
+// wrapError returns err as an *Error if it is an error of the internal packages that
+// callers may want to handle, so errors.As finds an *Error whichever package failed.
+func wrapError(err error) error {
+	var e *Error
+	var configErr *config.Error
+	if err == nil || errors.As(err, &e) || !errors.As(err, &configErr) {
+		return err
+	}
+	return &Error{Kind: configErr.Kind, Ticket: configErr.Ticket, Err: err}
+}
@@ -0,0 +1,316 @@
+// Package gittree manages one git worktree per ticket. It is the library behind the
+// git-tree command, for tools that create and inspect ticket worktrees without running it.
+//
+// A Manager is opened on a repository and works on its worktree metadata and git
+// repository the same way the command does, so worktrees created through either can be
+// managed by both, and changes are recorded in the journal so git tree undo can revert them.
+package gittree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+	"github.com/sduncan/git-tree/internal/ticket"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// Worktree is the metadata of a ticket's worktree.
+type Worktree struct {
+	// Path is the absolute path to the worktree directory.
+	Path string `json:"path"`
+
+	// Branch is the git branch name for this worktree.
+	Branch string `json:"branch"`
+
+	// Created is the timestamp when the worktree was created.
+	Created time.Time `json:"created"`
+
+	// Ticket is the ticket/task identifier (e.g., "PROJ-123").
+	Ticket string `json:"ticket"`
+
+	// LastAccessed is when the worktree was last switched to or entered, if ever.
+	LastAccessed time.Time `json:"last_accessed,omitzero"`
+
+	// TicketInfo caches details fetched from the issue tracker, if one is configured.
+	TicketInfo *TicketInfo `json:"ticket_info,omitempty"`
+
+	// Description is a free-form summary of the work in the worktree.
+	Description string `json:"description,omitempty"`
+
+	// Labels tag the worktree (e.g., "blocked", "review"), sorted and without duplicates.
+	Labels []string `json:"labels,omitempty"`
+
+	// Notes are timestamped free-form notes, oldest first.
+	Notes []Note `json:"notes,omitempty"`
+
+	// Workspace is the name of the workspace the worktree was created in with the
+	// worktrees of its other repositories, if any.
+	Workspace string `json:"workspace,omitempty"`
+
+	// Archived is when the worktree directory was removed by an archive, or zero if the
+	// worktree is checked out.
+	Archived time.Time `json:"archived,omitzero"`
+
+	// Stash is the commit holding the uncommitted changes saved when the worktree was
+	// archived, if there were any. It is kept alive by the ref returned by ArchiveRef.
+	Stash string `json:"stash,omitempty"`
+}
+
+// IsArchived reports whether the worktree is archived.
+func (w Worktree) IsArchived() bool {
+	return !w.Archived.IsZero()
+}
+
+// HasLabel reports whether the worktree has the given label.
+func (w Worktree) HasLabel(label string) bool {
+	return slices.Contains(w.Labels, label)
+}
+
+// ArchiveRef returns the ref that keeps a ticket's archived changes alive.
+func ArchiveRef(ticketID string) string {
+	return config.ArchiveRef(ticketID)
+}
+
+// TicketInfo is ticket details fetched from an issue tracker.
+type TicketInfo struct {
+	// Title is the ticket's summary line.
+	Title string `json:"title,omitempty"`
+
+	// Status is the ticket's workflow state when it was fetched.
+	Status string `json:"status,omitempty"`
+
+	// Assignee is the ticket's assignee when it was fetched.
+	Assignee string `json:"assignee,omitempty"`
+
+	// URL links to the ticket in the tracker.
+	URL string `json:"url,omitempty"`
+
+	// Fetched is the timestamp when the details were fetched.
+	Fetched time.Time `json:"fetched"`
+}
+
+// Note is a timestamped note attached to a worktree.
+type Note struct {
+	// Time is when the note was added.
+	Time time.Time `json:"time"`
+
+	// Text is the note itself.
+	Text string `json:"text"`
+}
+
+// newWorktree returns the Worktree of a metadata entry.
+func newWorktree(entry config.WorktreeEntry) Worktree {
+	w := Worktree{
+		Path:         entry.Path,
+		Branch:       entry.Branch,
+		Created:      entry.Created,
+		Ticket:       entry.Ticket,
+		LastAccessed: entry.LastAccessed,
+		Description:  entry.Description,
+		Labels:       slices.Clone(entry.Labels),
+		Workspace:    entry.Workspace,
+		Archived:     entry.Archived,
+		Stash:        entry.Stash,
+	}
+	if info := entry.TicketInfo; info != nil {
+		w.TicketInfo = &TicketInfo{Title: info.Title, Status: info.Status, Assignee: info.Assignee, URL: info.URL, Fetched: info.Fetched}
+	}
+	for _, note := range entry.Notes {
+		w.Notes = append(w.Notes, Note{Time: note.Time, Text: note.Text})
+	}
+	return w
+}
+
+// newWorktrees returns the Worktrees of metadata entries.
+func newWorktrees(entries []config.WorktreeEntry) []Worktree {
+	var worktrees []Worktree
+	for _, entry := range entries {
+		worktrees = append(worktrees, newWorktree(entry))
+	}
+	return worktrees
+}
+
+// ticketInfo returns the metadata form of ticket details, or nil if there are none.
+func (t *TicketInfo) ticketInfo() *config.TicketInfo {
+	if t == nil {
+		return nil
+	}
+	return &config.TicketInfo{Title: t.Title, Status: t.Status, Assignee: t.Assignee, URL: t.URL, Fetched: t.Fetched}
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Log receives progress messages and warnings, one per line, such as
+	// "Fetching latest from origin...". They are discarded if Log is nil.
+	Log io.Writer
+
+	// Getenv returns the value of an environment variable, such as where the user's
+	// registry is. It defaults to os.Getenv.
+	Getenv func(string) string
+
+	// Now returns the current time, for creation times and the journal. It defaults to
+	// time.Now.
+	Now func() time.Time
+
+	// Trace, if set, receives a line for every git command run on the repository and its
+	// worktrees, with its duration and exit status.
+	Trace io.Writer
+}
+
+// Manager manages the ticket worktrees of a repository. It reads the metadata when it is
+// opened; Reload picks up changes made since by others. A Manager isn't safe for
+// concurrent use.
+type Manager struct {
+	repoPath string
+	meta     *config.Metadata
+	settings *config.Settings
+	repo     *git.Repo
+	log      io.Writer
+	getenv   func(string) string
+	now      func() time.Time
+	runner   git.Runner
+}
+
+// Open returns a Manager for the repository containing dir. From a worktree, it manages
+// the worktrees of the primary repository the worktree belongs to.
+func Open(dir string, opts Options) (*Manager, error) {
+	repoPath, err := util.FindPrimaryRepoPath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find primary repository: %w", err)
+	}
+
+	m := &Manager{repoPath: repoPath, log: opts.Log, getenv: opts.Getenv, now: opts.Now}
+	if m.log == nil {
+		m.log = io.Discard
+	}
+	if m.getenv == nil {
+		m.getenv = os.Getenv
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	if opts.Trace != nil {
+		m.runner = &git.ExecRunner{Trace: opts.Trace}
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload reads the worktree metadata and settings again.
+func (m *Manager) Reload() error {
+	meta, err := config.Load(m.repoPath)
+	if err != nil {
+		return wrapError(fmt.Errorf("failed to load metadata: %w", err))
+	}
+	settings, err := config.LoadSettings(m.repoPath)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	m.meta, m.settings = meta, settings
+	m.repo = git.NewRepo(m.repoPath)
+	m.repo.Remote = settings.Remote
+	if m.runner != nil {
+		m.repo.Runner = m.runner
+	}
+	return nil
+}
+
+// RepoPath returns the path of the primary repository.
+func (m *Manager) RepoPath() string {
+	return m.repoPath
+}
+
+// Mainline returns the name of the mainline branch, or "" if it hasn't been detected yet.
+func (m *Manager) Mainline() string {
+	return m.meta.Mainline
+}
+
+// MainlineRef returns the ref worktrees are created from and rebased onto, such as
+// origin/main, or "" if the mainline hasn't been detected yet.
+func (m *Manager) MainlineRef() string {
+	if m.meta.Mainline == "" {
+		return ""
+	}
+	return m.settings.MainlineRef(m.meta.Mainline)
+}
+
+// Remote returns the name of the remote the mainline is fetched from.
+func (m *Manager) Remote() string {
+	return m.settings.Remote
+}
+
+// Tickets returns the IDs of the tickets with worktrees, sorted.
+func (m *Manager) Tickets() []string {
+	return m.meta.Tickets()
+}
+
+// Get returns the worktree of a ticket. The configured case normalization is applied
+// and case is ignored, so "proj-123" finds the worktree created for "PROJ-123".
+func (m *Manager) Get(ticketID string) (Worktree, error) {
+	_, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+	return newWorktree(entry), nil
+}
+
+// find returns the ID a ticket's worktree is stored under, and its metadata.
+func (m *Manager) find(ticketID string) (string, config.WorktreeEntry, error) {
+	id, ok := m.meta.FindWorktree(ticket.Normalize(ticketID, m.settings.TicketCase))
+	if !ok {
+		return "", config.WorktreeEntry{}, newError(ErrNotFound, ticketID, "worktree for %s not found", ticketID)
+	}
+	return id, m.meta.Worktrees[id], nil
+}
+
+// Fetch fetches the latest mainline from the remote.
+func (m *Manager) Fetch() error {
+	m.logf("Fetching latest from %s...\n", m.settings.Remote)
+	if err := m.repo.Fetch(); err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	return nil
+}
+
+// save writes the metadata and records it in the user's registry.
+func (m *Manager) save() error {
+	if err := config.Save(m.repoPath, m.meta); err != nil {
+		return wrapError(fmt.Errorf("failed to save metadata: %w", err))
+	}
+
+	// The registry is only an index, repaired by git tree index rebuild, so failing to
+	// update it doesn't fail the save
+	if path := config.RegistryPath(m.getenv); path != "" {
+		if err := config.UpdateRegistry(path, m.repoPath, m.meta, m.now()); err != nil {
+			m.logf("Warning: failed to update the registry: %v (run: git tree index rebuild)\n", err)
+		}
+	}
+	return nil
+}
+
+// record appends an operation to the journal. Failures are only logged as warnings since
+// the operation itself has already succeeded.
+func (m *Manager) record(op *config.Operation) {
+	op.Timestamp = m.now()
+	if err := config.AppendOperation(m.repoPath, op); err != nil {
+		m.logf("Warning: failed to record operation in journal: %v\n", err)
+	}
+}
+
+// logf writes a progress message or warning to the log.
+func (m *Manager) logf(format string, args ...any) {
+	fmt.Fprintf(m.log, format, args...)
+}
+
+// entryRef returns a pointer to a copy of entry, for use in journal changes.
+func entryRef(entry config.WorktreeEntry) *config.WorktreeEntry {
+	return &entry
+}
@@ -0,0 +1,522 @@
+package gittree_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/harness"
+	"github.com/sduncan/git-tree/pkg/gittree"
+)
+
+// open returns a Manager for the harness repository, logging to the test log.
+func open(t *testing.T, h *harness.Harness) *gittree.Manager {
+	t.Helper()
+	m, err := gittree.Open(h.Repo, gittree.Options{Log: testLog{t}, Now: h.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// testLog writes a Manager's log to the test log.
+type testLog struct {
+	t *testing.T
+}
+
+func (l testLog) Write(p []byte) (int, error) {
+	l.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func TestCreateListDelete(t *testing.T) {
+	h := harness.New(t)
+	m := open(t, h)
+
+	if _, err := gittree.Open(h.Root, gittree.Options{}); !errors.Is(err, gittree.ErrNotRepository) {
+		t.Errorf("opening outside a repository: %v", err)
+	}
+
+	var looked string
+	wt, err := m.Create("PROJ-1", gittree.CreateOptions{
+		Lookup: func(ticketID string) (*gittree.TicketInfo, error) {
+			looked = ticketID
+			return &gittree.TicketInfo{Title: "Fix the thing"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(h.Root, "worktrees", "repo", "PROJ-1")
+	if wt.Ticket != "PROJ-1" || wt.Branch != "PROJ-1" || wt.Path != want || looked != "PROJ-1" {
+		t.Errorf("Create = %+v after looking up %q", wt, looked)
+	}
+	if wt.TicketInfo == nil || wt.TicketInfo.Title != "Fix the thing" {
+		t.Errorf("ticket info not stored: %+v", wt.TicketInfo)
+	}
+	if m.Mainline() != "main" || m.MainlineRef() != "origin/main" {
+		t.Errorf("mainline = %q, %q", m.Mainline(), m.MainlineRef())
+	}
+
+	if _, err := m.Create("proj-1", gittree.CreateOptions{}); !errors.Is(err, gittree.ErrExists) {
+		t.Errorf("creating again: %v", err)
+	}
+	if _, err := m.Create("PROJ-2", gittree.CreateOptions{Branch: "feature/two"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another manager sees the changes
+	list, err := open(t, h).List(gittree.ListOptions{Sort: gittree.SortTicket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Ticket != "PROJ-1" || list[1].Branch != "feature/two" {
+		t.Fatalf("List = %+v", list)
+	}
+	for _, s := range list {
+		if s.State != gittree.StateClean || !s.Compared || s.Ahead != 0 || s.Behind != 0 {
+			t.Errorf("%s: state %s, compared %v, ahead %d, behind %d", s.Ticket, s.State, s.Compared, s.Ahead, s.Behind)
+		}
+	}
+	if _, err := m.List(gittree.ListOptions{Sort: "size"}); err == nil {
+		t.Error("List accepted an unknown sort order")
+	}
+
+	// Uncommitted changes are only discarded when forced
+	h.WriteFile(filepath.Join(wt.Path, "wip.txt"), "wip\n")
+	if s, err := m.Status("PROJ-1"); err != nil || s.State != gittree.StateDirty || s.Untracked != 1 {
+		t.Errorf("Status = %+v, %v", s, err)
+	}
+	if _, err := m.Delete("PROJ-1", gittree.DeleteOptions{}); !errors.Is(err, gittree.ErrDirty) {
+		t.Errorf("deleting a dirty worktree: %v", err)
+	}
+	if _, err := m.Delete("PROJ-1", gittree.DeleteOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Get("PROJ-1")
+	var e *gittree.Error
+	if !errors.Is(err, gittree.ErrNotFound) || !errors.As(err, &e) || e.Ticket != "PROJ-1" {
+		t.Errorf("Get after Delete: %v", err)
+	}
+	if got := m.Tickets(); len(got) != 1 || got[0] != "PROJ-2" {
+		t.Errorf("Tickets = %v", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	h := harness.New(t)
+	m := open(t, h)
+
+	if _, err := m.Update("PROJ-1"); !errors.Is(err, gittree.ErrNotFound) {
+		t.Errorf("updating a missing worktree: %v", err)
+	}
+	for _, ticketID := range []string{"PROJ-1", "PROJ-2", "PROJ-3"} {
+		if _, err := m.Create(ticketID, gittree.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wt, _ := m.Get("PROJ-1")
+	h.Commit(wt.Path, "feature.txt", "feature\n", "Add feature")
+	wt, _ = m.Get("PROJ-2")
+	h.Commit(wt.Path, "README.md", "# mine\n", "Change the README")
+	wt, _ = m.Get("PROJ-3")
+	h.WriteFile(filepath.Join(wt.Path, "wip.txt"), "wip\n")
+
+	h.AdvanceMainline("README.md", "# theirs\n", "Change the README upstream")
+	h.AdvanceMainline("other.txt", "other\n", "Add another file")
+	if err := m.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := m.CheckConflicts("PROJ-2")
+	if err != nil || conflicts.Behind != 2 || len(conflicts.Files) != 1 || conflicts.Files[0] != "README.md" {
+		t.Errorf("CheckConflicts = %+v, %v", conflicts, err)
+	}
+
+	if _, err := m.Update("PROJ-3"); !errors.Is(err, gittree.ErrDirty) {
+		t.Errorf("updating a dirty worktree: %v", err)
+	}
+	// Rebasing doesn't make the branch count as recently active
+	later := time.Now().Add(240 * time.Hour)
+	t.Setenv("GIT_COMMITTER_DATE", later.Format(time.RFC3339))
+	if _, err := m.Update("PROJ-1"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := m.Status("PROJ-1"); s.Behind != 0 || s.Ahead != 1 || !s.LastCommit.Before(later.Add(-time.Hour)) {
+		t.Errorf("PROJ-1 after Update: %d behind, %d ahead, last commit %v", s.Behind, s.Ahead, s.LastCommit)
+	}
+	// A branch without commits of its own isn't dated by the mainline commit it was cut from
+	if s, _ := m.Status("PROJ-3"); !s.LastCommit.IsZero() {
+		t.Errorf("PROJ-3 last commit = %v, want none", s.LastCommit)
+	}
+
+	// PROJ-1 is up to date, PROJ-2 would conflict and PROJ-3 is dirty
+	result, err := m.UpdateAll(gittree.UpdateAllOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Updated) != 0 || strings.Join(result.Skipped, ",") != "PROJ-2,PROJ-3" {
+		t.Errorf("UpdateAll = %+v", result)
+	}
+
+	// A conflicting rebase is aborted
+	result, err = m.UpdateAll(gittree.UpdateAllOptions{IncludeConflicts: true})
+	if !errors.Is(err, gittree.ErrRebase) || strings.Join(result.Failed, ",") != "PROJ-2" {
+		t.Errorf("UpdateAll = %+v, %v", result, err)
+	}
+	if s, _ := m.Status("PROJ-2"); s.State != gittree.StateClean || s.Behind != 2 {
+		t.Errorf("PROJ-2 after an aborted rebase: %+v", s)
+	}
+
+	// Update leaves a conflicting rebase to be resolved
+	if _, err := m.Update("PROJ-2"); !errors.Is(err, gittree.ErrRebase) || !errors.Is(err, gittree.ErrConflict) {
+		t.Errorf("updating a conflicting worktree: %v", err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	h := harness.New(t)
+	m := open(t, h)
+
+	for _, ticketID := range []string{"PROJ-1", "PROJ-2"} {
+		if _, err := m.Create(ticketID, gittree.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wt, _ := m.Get("PROJ-2")
+	h.WriteFile(filepath.Join(wt.Path, "wip.txt"), "wip\n")
+
+	// Nothing is inactive yet
+	removed, err := m.Clean(gittree.CleanOptions{Inactive: time.Hour})
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("Clean = %v, %v", removed, err)
+	}
+
+	// Two days later, only the clean worktree is removed, and only when confirmed
+	later, err := gittree.Open(h.Repo, gittree.Options{Log: testLog{t}, Now: func() time.Time { return time.Now().Add(48 * time.Hour) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offered []string
+	decline := func(candidates []gittree.Status) (bool, error) {
+		for _, c := range candidates {
+			offered = append(offered, c.Ticket)
+		}
+		return false, nil
+	}
+	if removed, err := later.Clean(gittree.CleanOptions{Inactive: 24 * time.Hour, Confirm: decline}); err != nil || len(removed) != 0 {
+		t.Fatalf("Clean = %v, %v", removed, err)
+	}
+	if strings.Join(offered, ",") != "PROJ-1" {
+		t.Errorf("offered %v for removal", offered)
+	}
+
+	removed, err = later.Clean(gittree.CleanOptions{Inactive: 24 * time.Hour})
+	if err != nil || len(removed) != 1 || removed[0].Ticket != "PROJ-1" {
+		t.Fatalf("Clean = %v, %v", removed, err)
+	}
+	if got := later.Tickets(); len(got) != 1 || got[0] != "PROJ-2" {
+		t.Errorf("Tickets = %v", got)
+	}
+}
+
+func TestMetadataUndo(t *testing.T) {
+	h := harness.New(t)
+	m := open(t, h)
+
+	if _, err := m.Create("PROJ-1", gittree.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := m.Label("proj-1", []string{"review", "blocked"}, nil)
+	if err != nil || strings.Join(wt.Labels, ",") != "blocked,review" {
+		t.Fatalf("Label = %+v, %v", wt.Labels, err)
+	}
+	if _, err := m.Label("PROJ-1", []string{"two words"}, nil); err == nil {
+		t.Error("label with a space was accepted")
+	}
+	if wt, err = m.AddNote("PROJ-1", " waiting on review "); err != nil || len(wt.Notes) != 1 || wt.Notes[0].Text != "waiting on review" {
+		t.Fatalf("AddNote = %+v, %v", wt.Notes, err)
+	}
+
+	h.WriteFile(filepath.Join(wt.Path, "wip.txt"), "wip\n")
+	if wt, err = m.Archive("PROJ-1", gittree.ArchiveOptions{}); err != nil || !wt.IsArchived() {
+		t.Fatalf("Archive = %+v, %v", wt, err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Errorf("archived worktree still exists: %v", err)
+	}
+
+	// Undo restores the archived worktree with its changes, then reverts the note
+	for _, command := range []string{"archive", "note"} {
+		op, err := m.Undo()
+		if err != nil || op.Command != command {
+			t.Fatalf("Undo = %+v, %v, want %s undone", op, err, command)
+		}
+	}
+	wt, _ = m.Get("PROJ-1")
+	if wt.IsArchived() || len(wt.Notes) != 0 || !wt.HasLabel("review") {
+		t.Errorf("after undo: %+v", wt)
+	}
+	if data, err := os.ReadFile(filepath.Join(wt.Path, "wip.txt")); err != nil || string(data) != "wip\n" {
+		t.Errorf("changes not restored: %q, %v", data, err)
+	}
+
+	ops, err := m.Journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var undone []string
+	for _, op := range ops {
+		if op.Undone {
+			undone = append(undone, op.Command)
+		}
+	}
+	if strings.Join(undone, ",") != "note,archive" {
+		t.Errorf("undone operations = %v", undone)
+	}
+
+	// An operation is only undone if every change can be
+	if err := os.Remove(filepath.Join(wt.Path, "wip.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("PROJ-2", gittree.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	h.AdvanceMainline("other.txt", "other\n", "Add another file")
+	if err := m.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := m.UpdateAll(gittree.UpdateAllOptions{}); err != nil || len(result.Updated) != 2 {
+		t.Fatalf("UpdateAll = %+v, %v", result, err)
+	}
+	wt, _ = m.Get("PROJ-2")
+	h.WriteFile(filepath.Join(wt.Path, "wip.txt"), "wip\n")
+	if _, err := m.Undo(); err == nil {
+		t.Error("undid an update of a dirty worktree")
+	}
+	if s, _ := m.Status("PROJ-1"); s.Behind != 0 {
+		t.Errorf("PROJ-1 was reset by a refused undo: %d behind", s.Behind)
+	}
+
+	// Operations that can't be undone aren't reached past
+	if _, err := m.Push("PROJ-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Undo(); err == nil || !strings.Contains(err.Error(), "cannot be undone") {
+		t.Errorf("undoing a push: %v", err)
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	h := harness.New(t)
+	backend := h.AddRepo("backend")
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", backend)
+	h.Git(h.Root, "config", "--global", "--add", "tree.workspace.platform.repo", h.Repo)
+	h.Git(h.Root, "config", "--global", "tree.workspace.platform.root", filepath.Join(h.Root, "tickets"))
+
+	if _, err := gittree.OpenWorkspace(h.Root, "mobile", gittree.Options{}); !errors.Is(err, gittree.ErrInvalidSettings) {
+		t.Errorf("opening an unknown workspace: %v", err)
+	}
+	w, err := gittree.OpenWorkspace(h.Root, "platform", gittree.Options{Log: testLog{t}, Now: h.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed create removes the worktrees already created
+	h.WriteFile(filepath.Join(h.Root, "tickets", "PROJ-1", "repo", "file.txt"), "in the way\n")
+	if _, err := w.Create("PROJ-1", gittree.CreateOptions{}); !errors.Is(err, gittree.ErrExists) {
+		t.Errorf("creating over an existing directory: %v", err)
+	}
+	if got := w.Members()[0].Manager.Tickets(); len(got) != 0 {
+		t.Errorf("backend tickets after a failed create: %v", got)
+	}
+
+	lookups := 0
+	created, err := w.Create("PROJ-2", gittree.CreateOptions{
+		Lookup: func(ticketID string) (*gittree.TicketInfo, error) {
+			lookups++
+			return &gittree.TicketInfo{Title: "Span repos"}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || lookups != 1 {
+		t.Fatalf("Create = %+v after %d lookups", created, lookups)
+	}
+	want := filepath.Join(h.Root, "tickets", "PROJ-2", "backend")
+	if created[0].Path != want || created[0].Workspace != "platform" || created[1].TicketInfo.Title != "Span repos" {
+		t.Errorf("Create = %+v", created)
+	}
+
+	// Removing one worktree leaves the ticket missing from its repository
+	if _, err := w.Members()[1].Manager.Delete("PROJ-2", gittree.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := w.List(gittree.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].State != gittree.StateMissing || list[0].Repos[0].State != gittree.StateClean || list[0].Repos[1].State != gittree.StateMissing {
+		t.Errorf("List = %+v", list)
+	}
+
+	deleted, err := w.Delete("proj-2", gittree.DeleteOptions{})
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("Delete = %+v, %v", deleted, err)
+	}
+	if _, err := w.Status("PROJ-2"); !errors.Is(err, gittree.ErrNotFound) {
+		t.Errorf("Status after Delete: %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	h := harness.New(t)
+
+	// The registry is found through the embedder's environment and stamped with its clock
+	path := filepath.Join(h.Root, "registry.json")
+	m, err := gittree.Open(h.Repo, gittree.Options{
+		Log:    testLog{t},
+		Getenv: func(key string) string { return map[string]string{"GIT_TREE_REGISTRY": path}[key] },
+		Now:    func() time.Time { return time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("PROJ-1", gittree.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := config.LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo := reg.Repos[h.Repo]; len(repo.Worktrees) != 1 || !repo.Updated.Equal(time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("registry entry = %+v", repo)
+	}
+	if _, err := os.Stat(filepath.Join(h.Root, "home", ".local", "share", "git-tree", "registry.json")); !os.IsNotExist(err) {
+		t.Errorf("registry written outside the embedder's environment: %v", err)
+	}
+
+	// A registry that can't be written only produces a warning
+	var log strings.Builder
+	blocked := filepath.Join(h.Root, "registry.json", "registry.json")
+	m, err = gittree.Open(h.Repo, gittree.Options{
+		Log:    &log,
+		Getenv: func(key string) string { return map[string]string{"GIT_TREE_REGISTRY": blocked}[key] },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Label("PROJ-1", []string{"review"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log.String(), "Warning: failed to update the registry") {
+		t.Errorf("no warning about the registry in %q", log.String())
+	}
+}
+
+func TestSync(t *testing.T) {
+	h := harness.New(t)
+	desk := open(t, h)
+	laptop, err := gittree.Open(h.Clone("laptop"), gittree.Options{Log: testLog{t}, Now: h.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// run runs git-tree in dir and reloads the managers
+	run := func(dir string, args ...string) {
+		t.Helper()
+		if result := h.RunIn(dir, "", args...); result.Code != 0 {
+			t.Fatalf("git tree %s exited with %d\n%s%s", strings.Join(args, " "), result.Code, result.Stdout, result.Stderr)
+		}
+		for _, m := range []*gittree.Manager{desk, laptop} {
+			if err := m.Reload(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	sync := func(m *gittree.Manager) gittree.SyncResult {
+		t.Helper()
+		result, err := m.Sync(gittree.SyncOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// Tickets created on one machine get worktrees on the other, on their pushed branch
+	wt, err := desk.Create("PROJ-1", gittree.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Commit(wt.Path, "feature.txt", "feature\n", "Add feature")
+	if _, err := desk.Push("PROJ-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := desk.Create("PROJ-2", gittree.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	run(h.Repo, "label", "PROJ-1", "blocked")
+	if result := sync(desk); !result.Pushed || len(result.Created) != 0 {
+		t.Errorf("first sync = %+v", result)
+	}
+
+	result := sync(laptop)
+	if len(result.Created) != 2 || result.Pushed {
+		t.Fatalf("sync on another machine = %+v", result)
+	}
+	synced, err := laptop.Get("PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(h.Root, "worktrees", "laptop", "PROJ-1"); synced.Path != want || strings.Join(synced.Labels, ",") != "blocked" {
+		t.Errorf("synced worktree = %+v", synced)
+	}
+	if _, err := os.Stat(filepath.Join(synced.Path, "feature.txt")); err != nil {
+		t.Errorf("synced worktree isn't on the pushed branch: %v", err)
+	}
+
+	// Concurrent changes to labels and notes are merged, and deletions are propagated
+	run(h.Repo, "label", "PROJ-1", "review")
+	run(h.Repo, "note", "PROJ-1", "from the desk")
+	if _, err := desk.Delete("PROJ-2", gittree.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	run(laptop.RepoPath(), "label", "PROJ-1", "--remove", "blocked")
+	run(laptop.RepoPath(), "note", "PROJ-1", "from the laptop")
+	sync(laptop)
+	if result := sync(desk); len(result.Updated) != 1 || !result.Pushed {
+		t.Errorf("merging sync = %+v", result)
+	}
+	if result := sync(laptop); len(result.Removed) != 1 || result.Removed[0].Ticket != "PROJ-2" {
+		t.Errorf("deletion not propagated: %+v", result)
+	}
+	for _, m := range []*gittree.Manager{desk, laptop} {
+		wt, err := m.Get("PROJ-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(wt.Labels, ",") != "review" || len(wt.Notes) != 2 {
+			t.Errorf("%s: merged labels %v, notes %+v", m.RepoPath(), wt.Labels, wt.Notes)
+		}
+	}
+
+	// A worktree with uncommitted changes is kept, and shared again, when deleted elsewhere
+	synced, _ = laptop.Get("PROJ-1")
+	h.WriteFile(filepath.Join(synced.Path, "wip.txt"), "wip\n")
+	if _, err := desk.Delete("PROJ-1", gittree.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sync(desk)
+	if result := sync(laptop); len(result.Kept) != 1 || len(result.Removed) != 0 {
+		t.Errorf("dirty worktree not kept: %+v", result)
+	}
+	if result := sync(desk); len(result.Created) != 1 {
+		t.Errorf("kept ticket not shared again: %+v", result)
+	}
+}
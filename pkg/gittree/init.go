@@ -0,0 +1,111 @@
+package gittree
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// InitOptions configures Init.
+type InitOptions struct {
+	// Mainline is the name of the mainline branch.
+	Mainline string
+
+	// Adopt is called with the path and branch of every worktree that isn't tracked yet,
+	// and returns the ticket ID to adopt it as, or false to leave it alone. If Adopt is
+	// nil, no worktrees are adopted.
+	Adopt func(path, branch string) (string, bool)
+
+	// Command and Args are what the initialization is recorded as in the journal. Command
+	// defaults to "init".
+	Command string
+	Args    []string
+}
+
+// Init records the mainline branch of the repository and adopts existing worktrees that
+// aren't tracked yet, returning the adopted worktrees.
+func (m *Manager) Init(opts InitOptions) ([]Worktree, error) {
+	if opts.Mainline == "" {
+		return nil, fmt.Errorf("a mainline branch is required")
+	}
+	mainlineBefore := m.meta.Mainline
+	m.meta.Mainline = opts.Mainline
+
+	var adopted []config.WorktreeEntry
+	if opts.Adopt != nil {
+		var err error
+		adopted, err = m.adoptWorktrees(opts.Adopt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.save(); err != nil {
+		return nil, err
+	}
+
+	var changes []config.Change
+	for _, entry := range adopted {
+		changes = append(changes, config.Change{Ticket: entry.Ticket, After: entryRef(entry)})
+	}
+	command := opts.Command
+	if command == "" {
+		command = "init"
+	}
+	m.record(&config.Operation{
+		Command:        command,
+		Args:           opts.Args,
+		MainlineBefore: mainlineBefore,
+		MainlineAfter:  m.meta.Mainline,
+		Changes:        changes,
+	})
+	return newWorktrees(adopted), nil
+}
+
+// adoptWorktrees adds metadata entries for registered worktrees that aren't tracked yet.
+// choose is called for each candidate and returns the ticket ID to adopt it as, or false
+// to skip it.
+func (m *Manager) adoptWorktrees(choose func(path, branch string) (string, bool)) ([]config.WorktreeEntry, error) {
+	worktrees, err := m.repo.ListWorktrees()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	tracked := make(map[string]bool)
+	for _, entry := range m.meta.Worktrees {
+		if absPath, err := filepath.Abs(entry.Path); err == nil {
+			tracked[absPath] = true
+		}
+	}
+
+	var adopted []config.WorktreeEntry
+	for _, wt := range worktrees {
+		absPath, err := filepath.Abs(wt.Path)
+		if err != nil || wt.IsPrimary || wt.Bare || wt.Prunable || wt.Detached || tracked[absPath] {
+			continue
+		}
+		if wt.Branch == m.meta.Mainline {
+			continue
+		}
+
+		ticketID, ok := choose(wt.Path, wt.Branch)
+		if !ok {
+			continue
+		}
+		ticketID, err = m.settings.NormalizeTicket(ticketID)
+		if err != nil {
+			m.logf("Skipping %s: %v\n", wt.Path, err)
+			continue
+		}
+		if _, ok := m.meta.FindWorktree(ticketID); ok {
+			m.logf("Skipping %s: a worktree for %s already exists\n", wt.Path, ticketID)
+			continue
+		}
+
+		m.meta.AddWorktree(ticketID, absPath, wt.Branch, m.now())
+		adopted = append(adopted, m.meta.Worktrees[ticketID])
+	}
+
+	return adopted, nil
+}
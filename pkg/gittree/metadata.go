@@ -0,0 +1,176 @@
+package gittree
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// Label adds labels to a ticket's worktree and removes others, returning the updated
+// worktree. Labels are single words, kept sorted and without duplicates.
+func (m *Manager) Label(ticketID string, add, remove []string) (Worktree, error) {
+	for _, label := range slices.Concat(add, remove) {
+		if err := checkLabel(label); err != nil {
+			return Worktree{}, err
+		}
+	}
+
+	args := append([]string{ticketID}, add...)
+	if len(remove) > 0 {
+		args = append(append(args, "--remove"), remove...)
+	}
+	return m.edit("label", args, ticketID, func(entry *config.WorktreeEntry) error {
+		entry.Labels = updateLabels(entry.Labels, add, remove)
+		return nil
+	})
+}
+
+// Describe sets the one-line description of a ticket's worktree, or clears it if
+// description is empty, returning the updated worktree.
+func (m *Manager) Describe(ticketID, description string) (Worktree, error) {
+	description = strings.TrimSpace(description)
+	return m.edit("note", []string{ticketID, "--description", description}, ticketID, func(entry *config.WorktreeEntry) error {
+		entry.Description = description
+		return nil
+	})
+}
+
+// AddNote adds a note to a ticket's worktree, timestamped now, returning the updated
+// worktree.
+func (m *Manager) AddNote(ticketID, text string) (Worktree, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Worktree{}, fmt.Errorf("note is empty")
+	}
+	return m.edit("note", []string{ticketID, text}, ticketID, func(entry *config.WorktreeEntry) error {
+		// Build a new slice so the journal's copy of the entry is left untouched
+		notes := make([]config.Note, 0, len(entry.Notes)+1)
+		notes = append(notes, entry.Notes...)
+		entry.Notes = append(notes, config.Note{Time: m.now(), Text: text})
+		return nil
+	})
+}
+
+// DeleteNote deletes note n of a ticket's worktree, counting from 1, returning the
+// updated worktree.
+func (m *Manager) DeleteNote(ticketID string, n int) (Worktree, error) {
+	return m.edit("note", []string{ticketID, "--delete", strconv.Itoa(n)}, ticketID, func(entry *config.WorktreeEntry) error {
+		if n < 1 || n > len(entry.Notes) {
+			return fmt.Errorf("%s has no note %d", entry.Ticket, n)
+		}
+		notes := make([]config.Note, 0, len(entry.Notes)-1)
+		notes = append(notes, entry.Notes[:n-1]...)
+		entry.Notes = append(notes, entry.Notes[n:]...)
+		return nil
+	})
+}
+
+// edit changes the metadata entry of a ticket's worktree with fn, saves it and records
+// the change in the journal as command, returning the updated worktree.
+func (m *Manager) edit(command string, args []string, ticketID string, fn func(entry *config.WorktreeEntry) error) (Worktree, error) {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	before := entry
+	if err := fn(&entry); err != nil {
+		return Worktree{}, err
+	}
+	m.meta.Worktrees[id] = entry
+	if err := m.save(); err != nil {
+		return Worktree{}, err
+	}
+
+	m.record(&config.Operation{
+		Command:        command,
+		Args:           args,
+		MainlineBefore: m.meta.Mainline,
+		MainlineAfter:  m.meta.Mainline,
+		Changes: []config.Change{{
+			Ticket: id,
+			Before: entryRef(before),
+			After:  entryRef(entry),
+		}},
+	})
+	return newWorktree(entry), nil
+}
+
+// Touch records that a ticket's worktree was accessed now. Accesses aren't operations,
+// so they aren't recorded in the journal.
+func (m *Manager) Touch(ticketID string) error {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return err
+	}
+	entry.LastAccessed = m.now()
+	m.meta.Worktrees[id] = entry
+	return m.save()
+}
+
+// WorktreeAt returns the worktree whose directory is path, and whether there is one.
+// Symlinks are resolved, so any path to the directory finds it.
+func (m *Manager) WorktreeAt(path string) (Worktree, bool) {
+	path = canonicalPath(path)
+	for _, ticketID := range m.meta.Tickets() {
+		entry := m.meta.Worktrees[ticketID]
+		if canonicalPath(entry.Path) == path {
+			return newWorktree(entry), true
+		}
+	}
+	return Worktree{}, false
+}
+
+// canonicalPath returns an absolute path with symlinks resolved where possible.
+func canonicalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
+
+// checkLabel returns an error if a label can't be shown in a list or used as a filter.
+func checkLabel(label string) error {
+	if label == "" || strings.HasPrefix(label, "-") {
+		return fmt.Errorf("invalid label %q", label)
+	}
+	for _, r := range label {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == ',' {
+			return fmt.Errorf("label %q must not contain whitespace or commas", label)
+		}
+	}
+	return nil
+}
+
+// updateLabels returns a new sorted, de-duplicated label list with add added and remove removed.
+func updateLabels(labels, add, remove []string) []string {
+	set := make(map[string]bool)
+	for _, l := range labels {
+		set[l] = true
+	}
+	for _, l := range add {
+		set[l] = true
+	}
+	for _, l := range remove {
+		delete(set, l)
+	}
+
+	if len(set) == 0 {
+		return nil
+	}
+	updated := make([]string, 0, len(set))
+	for l := range set {
+		updated = append(updated, l)
+	}
+	sort.Strings(updated)
+	return updated
+}
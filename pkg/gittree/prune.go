@@ -0,0 +1,68 @@
+package gittree
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// Prune removes the metadata of worktrees whose directory is gone, returning the
+// worktrees whose metadata was removed. Archived worktrees have no directory on purpose,
+// so they are kept.
+func (m *Manager) Prune() ([]Worktree, error) {
+	worktrees, err := m.repo.ListWorktrees()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	// Worktrees whose directory is gone are still listed until git prunes them, so they
+	// don't count
+	existingPaths := make(map[string]bool)
+	for _, wt := range worktrees {
+		if wt.Prunable {
+			continue
+		}
+		absPath, err := filepath.Abs(wt.Path)
+		if err == nil {
+			existingPaths[absPath] = true
+		}
+	}
+
+	var stale []config.WorktreeEntry
+	var changes []config.Change
+	for _, ticketID := range m.meta.Tickets() {
+		entry := m.meta.Worktrees[ticketID]
+		if entry.IsArchived() {
+			continue
+		}
+		if absPath, err := filepath.Abs(entry.Path); err == nil && existingPaths[absPath] {
+			continue
+		}
+		m.meta.RemoveWorktree(ticketID)
+		stale = append(stale, entry)
+		changes = append(changes, config.Change{Ticket: ticketID, Before: entryRef(entry)})
+	}
+
+	if len(stale) > 0 {
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+		m.record(&config.Operation{
+			Command:        "prune",
+			MainlineBefore: m.meta.Mainline,
+			MainlineAfter:  m.meta.Mainline,
+			Changes:        changes,
+		})
+	}
+
+	return newWorktrees(stale), nil
+}
+
+// PruneWorktrees prunes git's records of worktrees whose directory is gone.
+func (m *Manager) PruneWorktrees() error {
+	if err := m.repo.PruneWorktrees(); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package gittree
+
+import (
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// Push pushes a ticket's branch to the remote and sets it as the branch's upstream.
+// Branches are shared by all worktrees, so archived worktrees can be pushed too.
+func (m *Manager) Push(ticketID string) (Worktree, error) {
+	_, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	m.logf("Pushing %s to %s...\n", entry.Branch, m.settings.Remote)
+	if err := m.repo.Push(entry.Branch); err != nil {
+		return Worktree{}, err
+	}
+
+	m.record(&config.Operation{
+		Command:        "push",
+		Args:           []string{ticketID},
+		MainlineBefore: m.meta.Mainline,
+		MainlineAfter:  m.meta.Mainline,
+	})
+	return newWorktree(entry), nil
+}
@@ -0,0 +1,201 @@
+package gittree
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Worktree states.
+const (
+	StateClean    = "clean"
+	StateDirty    = "dirty"
+	StateStale    = "stale" // the directory is gone
+	StateArchived = "archived"
+	StateUnknown  = "unknown" // the working tree status can't be read
+)
+
+// Status is a worktree with its working tree state and activity.
+type Status struct {
+	Worktree
+
+	// State is one of the State constants.
+	State   string `json:"state"`
+	Changes int    `json:"changes"`
+	Ahead   int    `json:"ahead"`
+	Behind  int    `json:"behind"`
+
+	// Staged, Unstaged, Untracked and Conflicted count files by category. A file can be
+	// both staged and unstaged.
+	Staged     int `json:"staged"`
+	Unstaged   int `json:"unstaged"`
+	Untracked  int `json:"untracked"`
+	Conflicted int `json:"conflicted"`
+
+	LastCommit   time.Time `json:"last_commit,omitzero"`
+	LastModified time.Time `json:"last_modified,omitzero"`
+	LastActive   time.Time `json:"last_active"`
+
+	// Compared is set when Ahead and Behind were computed against the mainline.
+	Compared bool `json:"-"`
+}
+
+// Sort orders for List.
+const (
+	SortTicket  = "ticket"
+	SortActive  = "active"  // most recently active first
+	SortCreated = "created" // most recently created first
+)
+
+// ListOptions selects and orders the worktrees returned by List.
+type ListOptions struct {
+	// Labels limits the list to worktrees with every one of the labels.
+	Labels []string
+
+	// Sort is one of the Sort constants. It defaults to SortTicket.
+	Sort string
+}
+
+// List returns the status of the worktrees selected by opts. Worktrees whose directory is
+// no longer a git worktree are StateStale.
+func (m *Manager) List(opts ListOptions) ([]Status, error) {
+	if err := checkSort(opts.Sort); err != nil {
+		return nil, err
+	}
+
+	worktrees, err := m.repo.ListWorktrees()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	// Build map of existing worktree paths
+	existingPaths := make(map[string]git.WorktreeInfo)
+	for _, wt := range worktrees {
+		absPath, err := filepath.Abs(wt.Path)
+		if err == nil {
+			existingPaths[absPath] = wt
+		}
+	}
+
+	mainlineRef := m.MainlineRef()
+	results := []Status{}
+	for _, ticketID := range m.meta.Tickets() {
+		entry := m.meta.Worktrees[ticketID]
+		if !hasLabels(entry, opts.Labels) {
+			continue
+		}
+
+		result := Status{Worktree: newWorktree(entry), State: StateUnknown}
+		absPath, err := filepath.Abs(entry.Path)
+		if err == nil {
+			if _, exists := existingPaths[absPath]; exists || entry.IsArchived() {
+				result = m.inspect(entry, mainlineRef)
+			} else {
+				result.State = StateStale
+				m.inspectActivity(&result)
+			}
+		}
+		results = append(results, result)
+	}
+
+	// Tickets are already in order
+	switch opts.Sort {
+	case SortActive:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].LastActive.After(results[j].LastActive) })
+	case SortCreated:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Created.After(results[j].Created) })
+	}
+	return results, nil
+}
+
+// checkSort checks that a sort order is one of the Sort constants, or "".
+func checkSort(order string) error {
+	switch order {
+	case "", SortTicket, SortActive, SortCreated:
+		return nil
+	}
+	return fmt.Errorf("unknown sort order %q: use ticket, active or created", order)
+}
+
+// Status returns the status of a ticket's worktree. A worktree whose directory is gone is
+// StateUnknown.
+func (m *Manager) Status(ticketID string) (Status, error) {
+	_, entry, err := m.find(ticketID)
+	if err != nil {
+		return Status{}, err
+	}
+	return m.inspect(entry, m.MainlineRef()), nil
+}
+
+// inspect gathers the working tree state and activity of a worktree and, if mainlineRef
+// is set, how far its branch is ahead of and behind the mainline.
+func (m *Manager) inspect(entry config.WorktreeEntry, mainlineRef string) Status {
+	result := Status{Worktree: newWorktree(entry), State: StateUnknown}
+	m.inspectActivity(&result)
+	if entry.IsArchived() {
+		result.State = StateArchived
+		return result
+	}
+	wtRepo := m.repo.WithPath(entry.Path)
+
+	status, err := wtRepo.Status()
+	if err != nil {
+		return result
+	}
+	if status.Clean() {
+		result.State = StateClean
+	} else {
+		result.State = StateDirty
+	}
+	result.Changes = status.Changes()
+	result.Staged = len(status.Staged())
+	result.Unstaged = len(status.Unstaged())
+	result.Untracked = len(status.Untracked())
+	result.Conflicted = len(status.Conflicted())
+
+	if mainlineRef != "" && status.Branch.Head != "" {
+		ahead, behind, err := wtRepo.GetCommitCount(status.Branch.Head, mainlineRef)
+		if err == nil {
+			result.Ahead, result.Behind, result.Compared = ahead, behind, true
+		}
+	}
+
+	return result
+}
+
+// inspectActivity fills in the last commit and last modification times of a worktree
+// and its overall last activity. Only commits on the branch that aren't on the mainline
+// count, so a branch without commits of its own is active from its creation or last
+// access. Archiving counts as activity.
+func (m *Manager) inspectActivity(result *Status) {
+	wtRepo := m.repo.WithPath(result.Path)
+	if mainline := m.MainlineRef(); mainline != "" {
+		if t, err := wtRepo.LastCommitTime(result.Branch, mainline); err == nil {
+			result.LastCommit = t
+		}
+	}
+	if t, err := wtRepo.LastModified(); err == nil {
+		result.LastModified = t
+	}
+
+	result.LastActive = result.Created
+	for _, t := range []time.Time{result.LastAccessed, result.LastCommit, result.LastModified, result.Archived} {
+		if t.After(result.LastActive) {
+			result.LastActive = t
+		}
+	}
+}
+
+// hasLabels reports whether an entry has every one of the given labels.
+func hasLabels(entry config.WorktreeEntry, labels []string) bool {
+	for _, label := range labels {
+		if !entry.HasLabel(label) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package gittree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// StorageBackends are the names of the backends the metadata can be stored in: json
+// (worktree-metadata.json, the default), gitconfig (tree-ticket sections of the
+// repository's git config) and kv (worktree-metadata.kv, one line per ticket).
+var StorageBackends = config.StorageBackends
+
+// MigrateOptions configures MigrateStorage.
+type MigrateOptions struct {
+	// From is the backend to move the metadata from. It defaults to the configured one.
+	From string
+
+	// Keep keeps the metadata in the old backend instead of removing it.
+	Keep bool
+
+	// Replace is called with the path of the new backend and the number of worktrees it
+	// holds if it already holds some, and returns an error to stop the migration. If
+	// Replace is nil, metadata already in the new backend is never replaced.
+	Replace func(path string, worktrees int) error
+}
+
+// Storage returns the name of the backend the metadata is stored in.
+func (m *Manager) Storage() (string, error) {
+	storage, err := config.OpenStorage(m.repoPath)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	return storage.Name(), nil
+}
+
+// MigrateStorage copies the metadata from one storage backend to another, checks the
+// copy, selects the new backend and removes the old copy. It returns the name of the
+// backend the metadata was moved from. The migration is journaled but can't be undone;
+// migrating back reverses it.
+func (m *Manager) MigrateStorage(to string, opts MigrateOptions) (string, error) {
+	var source config.Storage
+	var err error
+	if opts.From == "" {
+		source, err = config.OpenStorage(m.repoPath)
+	} else {
+		source, err = config.NewStorage(m.repoPath, opts.From)
+	}
+	if err != nil {
+		return "", wrapError(err)
+	}
+	target, err := config.NewStorage(m.repoPath, to)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	if target.Name() == source.Name() {
+		return "", fmt.Errorf("metadata is already stored in %s", source.Name())
+	}
+
+	meta, err := source.Load()
+	if err != nil {
+		return "", wrapError(fmt.Errorf("failed to load metadata: %w", err))
+	}
+	existing, err := target.Load()
+	if err != nil {
+		return "", wrapError(fmt.Errorf("failed to load metadata: %w", err))
+	}
+	if len(existing.Worktrees) > 0 {
+		if opts.Replace == nil {
+			return "", newError(ErrExists, "", "%s already holds %d worktree(s)", target.Path(), len(existing.Worktrees))
+		}
+		if err := opts.Replace(target.Path(), len(existing.Worktrees)); err != nil {
+			return "", err
+		}
+	}
+
+	m.logf("Copying %d worktree(s) from %s to %s...\n", len(meta.Worktrees), source.Path(), target.Path())
+	if err := target.Save(meta); err != nil {
+		return "", fmt.Errorf("failed to save metadata: %w", err)
+	}
+	copied, err := target.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to check copied metadata: %w", err)
+	}
+	if !sameJSON(meta, copied) {
+		return "", fmt.Errorf("copied metadata in %s doesn't match the original; %s was left selected", target.Path(), source.Name())
+	}
+
+	if err := config.SetStorage(m.repoPath, target.Name()); err != nil {
+		return "", err
+	}
+	if !opts.Keep {
+		m.logf("Removing metadata from %s...\n", source.Path())
+		if err := source.Clear(); err != nil {
+			m.logf("Warning: %v\n", err)
+		}
+	}
+
+	m.record(&config.Operation{
+		Command:        "migrate-storage",
+		Args:           []string{source.Name(), target.Name()},
+		MainlineBefore: meta.Mainline,
+		MainlineAfter:  meta.Mainline,
+	})
+	return source.Name(), m.Reload()
+}
+
+// RebuildRegistry replaces the user's registry of repositories with worktrees with the
+// repositories in repoPaths, returning how many repositories and worktrees it registered.
+// Repositories that can't be opened are skipped with a warning to opts.Log.
+func RebuildRegistry(repoPaths []string, opts Options) (int, int, error) {
+	getenv, log := opts.Getenv, opts.Log
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	if log == nil {
+		log = io.Discard
+	}
+	path := config.RegistryPath(getenv)
+	if path == "" {
+		return 0, 0, fmt.Errorf("no registry: neither XDG_DATA_HOME nor HOME is set")
+	}
+
+	paths := slices.Clone(repoPaths)
+	sort.Strings(paths)
+
+	reg := &config.Registry{Repos: make(map[string]config.RegistryRepo)}
+	worktrees := 0
+	for _, repoPath := range paths {
+		m, err := Open(repoPath, opts)
+		if err != nil {
+			fmt.Fprintf(log, "Warning: skipping %s: %v\n", repoPath, err)
+			continue
+		}
+		reg.Set(m.repoPath, m.meta, m.now())
+		worktrees += len(m.meta.Worktrees)
+	}
+
+	if err := config.SaveRegistry(path, reg); err != nil {
+		return 0, 0, err
+	}
+	return len(reg.Repos), worktrees, nil
+}
@@ -0,0 +1,433 @@
+package gittree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// remoteSharedRef is where the remote's shared metadata is fetched to before it is merged.
+const remoteSharedRef = "refs/git-tree/remote-metadata"
+
+// SharedRef is the ref Sync stores the shared metadata in.
+const SharedRef = config.SharedRef
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// NoPush merges the remote's shared metadata into the local worktrees without pushing
+	// the result back.
+	NoPush bool
+}
+
+// SyncResult is what Sync changed.
+type SyncResult struct {
+	// Created are the worktrees created for tickets added elsewhere.
+	Created []Worktree
+
+	// Updated are the worktrees whose shared metadata was changed elsewhere.
+	Updated []Worktree
+
+	// Removed are the worktrees removed because their tickets were deleted elsewhere.
+	Removed []Worktree
+
+	// Kept are the tickets deleted elsewhere whose worktrees were kept because they have
+	// uncommitted changes or unpushed commits, or otherwise couldn't be removed or created.
+	Kept []string
+
+	// Pushed reports whether the shared metadata was pushed to the remote.
+	Pushed bool
+}
+
+// Sync merges the worktrees' shared metadata with the copy in SharedRef on the remote, so
+// machines working on the same repository end up with the same tickets. Only what is
+// the same on every machine is shared: tickets, branches, descriptions, labels, notes
+// and ticket details, not paths or access times.
+//
+// Tickets added elsewhere get worktrees, on their existing branch if there is one;
+// tickets deleted elsewhere have their worktrees removed, unless they have uncommitted
+// changes or commits that are neither pushed nor on the mainline. When both sides
+// changed the same ticket since the last sync, labels and notes added or removed on
+// either side are all kept, and the local side wins other fields. The merged metadata
+// is committed to SharedRef and pushed back.
+func (m *Manager) Sync(opts SyncOptions) (SyncResult, error) {
+	var result SyncResult
+	if err := m.Fetch(); err != nil {
+		return result, err
+	}
+
+	local, localCommit, err := config.LoadShared(m.repo, SharedRef)
+	if err != nil {
+		return result, wrapError(err)
+	}
+
+	m.logf("Fetching shared metadata from %s...\n", m.settings.Remote)
+	found, err := m.repo.FetchRef(SharedRef, remoteSharedRef)
+	if err != nil {
+		return result, fmt.Errorf("failed to fetch shared metadata: %w", err)
+	}
+	theirs, theirCommit := local, localCommit
+	base, baseCommit := local, localCommit
+	if found {
+		if theirs, theirCommit, err = config.LoadShared(m.repo, remoteSharedRef); err != nil {
+			return result, wrapError(err)
+		}
+		if base, baseCommit, err = m.sharedBase(localCommit, theirCommit); err != nil {
+			return result, wrapError(err)
+		}
+	}
+
+	ours := config.Share(m.meta)
+	merged := mergeShared(base, ours, theirs)
+
+	mainlineBefore := m.meta.Mainline
+	if m.meta.Mainline == "" {
+		m.meta.Mainline = merged.Mainline
+	}
+
+	var changes []config.Change
+	for _, ticketID := range sortedTickets(merged.Worktrees) {
+		shared := merged.Worktrees[ticketID]
+		entry, ok := m.meta.Worktrees[ticketID]
+		if !ok {
+			created, tip, err := m.createShared(ticketID, shared)
+			if err != nil {
+				m.logf("Warning: skipping %s: %v\n", ticketID, err)
+				result.Kept = append(result.Kept, ticketID)
+				continue
+			}
+			result.Created = append(result.Created, newWorktree(created))
+			changes = append(changes, config.Change{Ticket: ticketID, After: entryRef(created), TipAfter: tip})
+			continue
+		}
+
+		// The branch is whatever the worktree has checked out, whatever it is called elsewhere
+		shared.Branch = entry.Branch
+		if sameShared(ours.Worktrees[ticketID], shared) {
+			continue
+		}
+		before := entry
+		shared.Apply(&entry)
+		m.meta.Worktrees[ticketID] = entry
+		result.Updated = append(result.Updated, newWorktree(entry))
+		changes = append(changes, config.Change{Ticket: ticketID, Before: entryRef(before), After: entryRef(entry)})
+	}
+
+	for _, ticketID := range m.meta.Tickets() {
+		if _, ok := merged.Worktrees[ticketID]; ok {
+			continue
+		}
+		entry := m.meta.Worktrees[ticketID]
+		tip, err := m.removeShared(ticketID, entry)
+		if err != nil {
+			// The ticket stays shared, so it isn't deleted elsewhere either
+			m.logf("Keeping %s: %v\n", ticketID, err)
+			merged.Worktrees[ticketID] = config.ShareEntry(entry)
+			result.Kept = append(result.Kept, ticketID)
+			continue
+		}
+		m.meta.RemoveWorktree(ticketID)
+		result.Removed = append(result.Removed, newWorktree(entry))
+		changes = append(changes, config.Change{Ticket: ticketID, Before: entryRef(entry), TipBefore: tip})
+	}
+
+	if err := m.save(); err != nil {
+		return result, err
+	}
+
+	commit, err := m.commitShared(merged, local, theirs, localCommit, theirCommit, baseCommit)
+	if err != nil {
+		return result, err
+	}
+	if !opts.NoPush && commit != "" && commit != theirCommit {
+		m.logf("Pushing shared metadata to %s...\n", m.settings.Remote)
+		if err := m.repo.PushRef(SharedRef); err != nil {
+			return result, fmt.Errorf("failed to push shared metadata (run sync again to merge the latest): %w", err)
+		}
+		result.Pushed = true
+	}
+
+	m.record(&config.Operation{
+		Command:        "sync",
+		MainlineBefore: mainlineBefore,
+		MainlineAfter:  m.meta.Mainline,
+		Changes:        changes,
+	})
+	return result, nil
+}
+
+// createShared creates the worktree of a ticket added elsewhere, at the configured worktree
+// location, returning it and its branch tip. The branch is checked out if it exists
+// locally, created from the remote's if it was pushed, and otherwise from the mainline.
+// A ticket in a workspace that can't be loaded is an error rather than a worktree outside
+// the workspace root.
+func (m *Manager) createShared(ticketID string, shared config.SharedEntry) (config.WorktreeEntry, string, error) {
+	path := m.settings.WorktreePath(m.repoPath, ticketID)
+	if shared.Workspace != "" {
+		ws, err := config.LoadWorkspace(m.repoPath, shared.Workspace)
+		if err != nil {
+			return config.WorktreeEntry{}, "", fmt.Errorf("failed to load workspace %s: %w", shared.Workspace, err)
+		}
+		path = ws.TicketPath(ticketID)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return config.WorktreeEntry{}, "", newError(ErrExists, ticketID, "path already exists: %s", path)
+	}
+
+	m.logf("Creating worktree at %s...\n", path)
+	remoteBranch := m.settings.Remote + "/" + shared.Branch
+	switch {
+	case m.repo.BranchExists(shared.Branch):
+		if err := m.repo.AttachWorktree(path, shared.Branch); err != nil {
+			return config.WorktreeEntry{}, "", err
+		}
+	case m.refExists(remoteBranch):
+		if err := m.repo.AddWorktree(path, shared.Branch, remoteBranch); err != nil {
+			return config.WorktreeEntry{}, "", err
+		}
+		if err := m.repo.SetUpstream(shared.Branch, remoteBranch); err != nil {
+			m.logf("Warning: %v\n", err)
+		}
+	default:
+		if m.meta.Mainline == "" {
+			mainline, err := m.repo.DetectMainline()
+			if err != nil {
+				return config.WorktreeEntry{}, "", fmt.Errorf("failed to detect mainline branch: %w", err)
+			}
+			m.meta.Mainline = mainline
+		}
+		if err := m.repo.AddWorktree(path, shared.Branch, m.MainlineRef()); err != nil {
+			return config.WorktreeEntry{}, "", err
+		}
+	}
+
+	entry := config.WorktreeEntry{Path: path}
+	shared.Apply(&entry)
+	m.meta.Worktrees[ticketID] = entry
+	tip, _ := m.repo.ResolveCommit(shared.Branch)
+	return entry, tip, nil
+}
+
+// removeShared removes the worktree of a ticket deleted elsewhere and deletes its branch,
+// returning the branch tip. A worktree with uncommitted changes, saved changes of an
+// archive, or commits that are neither pushed nor on the mainline is an ErrDirty, since
+// removing it would lose work that exists only on this machine.
+func (m *Manager) removeShared(ticketID string, entry config.WorktreeEntry) (string, error) {
+	if entry.IsArchived() {
+		if entry.Stash != "" {
+			return "", newError(ErrDirty, ticketID, "archive has saved uncommitted changes")
+		}
+	} else {
+		clean, err := m.repo.WithPath(entry.Path).IsClean()
+		if err != nil {
+			return "", fmt.Errorf("cannot read worktree status: %w", err)
+		}
+		if !clean {
+			return "", newError(ErrDirty, ticketID, "worktree has uncommitted changes")
+		}
+	}
+
+	if m.refExists(entry.Branch) {
+		unpublished, err := m.unpublishedCommits(entry.Branch)
+		if err != nil {
+			return "", err
+		}
+		if unpublished > 0 {
+			return "", newError(ErrDirty, ticketID, "branch %s has %d commit(s) not pushed or on the mainline", entry.Branch, unpublished)
+		}
+	}
+
+	if entry.IsArchived() {
+		return m.removeArchived(ticketID, entry), nil
+	}
+	return m.removeWorktree(entry, false)
+}
+
+// unpublishedCommits returns the number of commits on a branch that are on neither its
+// upstream, the remote's branch of the same name, nor the mainline.
+func (m *Manager) unpublishedCommits(branch string) (int, error) {
+	var published []string
+	for _, ref := range []string{branch + "@{upstream}", m.settings.Remote + "/" + branch, m.MainlineRef()} {
+		if ref != "" && m.refExists(ref) {
+			published = append(published, ref)
+		}
+	}
+	return m.repo.CountCommitsNotIn(branch, published...)
+}
+
+// sharedBase returns the shared metadata of the last sync the local and remote copies both
+// include, and its commit. It is empty if there is none, as on a machine's first sync.
+func (m *Manager) sharedBase(localCommit, theirCommit string) (*config.SharedMetadata, string, error) {
+	empty := &config.SharedMetadata{Worktrees: make(map[string]config.SharedEntry)}
+	if localCommit == "" {
+		return empty, "", nil
+	}
+	commit, err := m.repo.MergeBase(localCommit, theirCommit)
+	if err != nil || commit == "" {
+		return empty, "", err
+	}
+	return config.LoadShared(m.repo, commit)
+}
+
+// commitShared points SharedRef at the merged metadata, returning the commit. It reuses the
+// remote's commit, or the local one if it already includes the remote's, when the metadata
+// is unchanged from it, and otherwise commits the metadata on top of both.
+func (m *Manager) commitShared(merged, local, theirs *config.SharedMetadata, localCommit, theirCommit, baseCommit string) (string, error) {
+	if theirCommit != "" && sameMetadata(merged, theirs) {
+		if theirCommit != localCommit {
+			if err := m.repo.UpdateRef(SharedRef, theirCommit); err != nil {
+				return "", err
+			}
+		}
+		return theirCommit, nil
+	}
+	if localCommit != "" && baseCommit == theirCommit && sameMetadata(merged, local) {
+		return localCommit, nil
+	}
+
+	var parents []string
+	for _, parent := range []string{localCommit, theirCommit} {
+		if parent != "" && (len(parents) == 0 || parents[0] != parent) {
+			parents = append(parents, parent)
+		}
+	}
+	commit, err := config.CommitShared(m.repo, SharedRef, merged, fmt.Sprintf("Sync %d ticket(s)", len(merged.Worktrees)), parents...)
+	if err != nil {
+		return "", fmt.Errorf("failed to save shared metadata: %w", err)
+	}
+	return commit, nil
+}
+
+// refExists reports whether ref resolves to a commit.
+func (m *Manager) refExists(ref string) bool {
+	_, err := m.repo.ResolveCommit(ref)
+	return err == nil
+}
+
+// mergeShared merges the local and remote shared metadata, given the metadata of the last
+// sync they both started from. A ticket changed on only one side takes that side's
+// version, including a deletion; a ticket changed on one side and deleted on the other is
+// kept; and a ticket changed on both is merged by mergeEntry.
+func mergeShared(base, ours, theirs *config.SharedMetadata) *config.SharedMetadata {
+	merged := &config.SharedMetadata{Worktrees: make(map[string]config.SharedEntry), Mainline: ours.Mainline}
+	if merged.Mainline == "" {
+		merged.Mainline = theirs.Mainline
+	}
+
+	tickets := make(map[string]bool)
+	for _, shared := range []*config.SharedMetadata{base, ours, theirs} {
+		for ticketID := range shared.Worktrees {
+			tickets[ticketID] = true
+		}
+	}
+
+	for ticketID := range tickets {
+		b, inBase := base.Worktrees[ticketID]
+		o, inOurs := ours.Worktrees[ticketID]
+		t, inTheirs := theirs.Worktrees[ticketID]
+
+		oursChanged := inOurs != inBase || (inOurs && !sameShared(o, b))
+		theirsChanged := inTheirs != inBase || (inTheirs && !sameShared(t, b))
+		switch {
+		case !oursChanged:
+			if inTheirs {
+				merged.Worktrees[ticketID] = t
+			}
+		case !theirsChanged || !inTheirs:
+			if inOurs {
+				merged.Worktrees[ticketID] = o
+			}
+		case !inOurs:
+			merged.Worktrees[ticketID] = t
+		default:
+			merged.Worktrees[ticketID] = mergeEntry(b, o, t)
+		}
+	}
+	return merged
+}
+
+// mergeEntry merges a ticket changed on both sides. Labels and notes are merged as sets,
+// keeping those added on either side and dropping those removed on either side; for the
+// other fields, a local change wins over a remote one.
+func mergeEntry(base, ours, theirs config.SharedEntry) config.SharedEntry {
+	merged := ours
+	if ours.Branch == base.Branch {
+		merged.Branch = theirs.Branch
+	}
+	if ours.Description == base.Description {
+		merged.Description = theirs.Description
+	}
+	if ours.Workspace == base.Workspace {
+		merged.Workspace = theirs.Workspace
+	}
+	if sameJSON(ours.TicketInfo, base.TicketInfo) {
+		merged.TicketInfo = theirs.TicketInfo
+	}
+
+	merged.Labels = mergeSet(base.Labels, ours.Labels, theirs.Labels, func(label string) string { return label })
+	sort.Strings(merged.Labels)
+	merged.Notes = mergeSet(base.Notes, ours.Notes, theirs.Notes, func(note config.Note) string {
+		return note.Time.UTC().Format("2006-01-02T15:04:05.999999999") + "\x00" + note.Text
+	})
+	sort.SliceStable(merged.Notes, func(i, j int) bool { return merged.Notes[i].Time.Before(merged.Notes[j].Time) })
+	return merged
+}
+
+// mergeSet returns the items on both sides, and those only one side added since base,
+// identifying items by key.
+func mergeSet[T any](base, ours, theirs []T, key func(T) string) []T {
+	keys := func(items []T) map[string]bool {
+		set := make(map[string]bool, len(items))
+		for _, item := range items {
+			set[key(item)] = true
+		}
+		return set
+	}
+	inBase, inOurs, inTheirs := keys(base), keys(ours), keys(theirs)
+
+	var merged []T
+	seen := make(map[string]bool)
+	for _, items := range [][]T{ours, theirs} {
+		for _, item := range items {
+			k := key(item)
+			if seen[k] {
+				continue
+			}
+			if (inOurs[k] && inTheirs[k]) || !inBase[k] {
+				merged = append(merged, item)
+				seen[k] = true
+			}
+		}
+	}
+	return merged
+}
+
+// sameShared reports whether two tickets have the same shared metadata.
+func sameShared(a, b config.SharedEntry) bool {
+	return sameJSON(a, b)
+}
+
+// sameMetadata reports whether two copies of the shared metadata are the same.
+func sameMetadata(a, b *config.SharedMetadata) bool {
+	return sameJSON(a, b)
+}
+
+// sameJSON reports whether a and b encode to the same JSON, which compares times by
+// instant and ignores their monotonic clock readings.
+func sameJSON(a, b any) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
+
+// sortedTickets returns the ticket IDs of the shared worktrees, sorted.
+func sortedTickets(worktrees map[string]config.SharedEntry) []string {
+	tickets := make([]string, 0, len(worktrees))
+	for ticketID := range worktrees {
+		tickets = append(tickets, ticketID)
+	}
+	sort.Strings(tickets)
+	return tickets
+}
@@ -0,0 +1,323 @@
+package gittree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+)
+
+// Operation is a command recorded in the journal.
+type Operation struct {
+	// ID is the sequence number of the operation within the journal.
+	ID int `json:"id"`
+
+	// Command is the name of the command that was run (e.g., "create", "delete").
+	Command string `json:"command"`
+
+	// Args are the arguments the command was invoked with.
+	Args []string `json:"args,omitempty"`
+
+	// Time is when the operation completed.
+	Time time.Time `json:"time"`
+
+	// Tickets are the tickets the operation changed.
+	Tickets []string `json:"tickets,omitempty"`
+
+	// Reverts is the ID of the operation this one undid, if any.
+	Reverts int `json:"reverts,omitempty"`
+
+	// Undone reports whether the operation has been undone.
+	Undone bool `json:"undone,omitempty"`
+}
+
+// newOperation returns the Operation of a journal entry.
+func newOperation(op config.Operation, undone bool) Operation {
+	o := Operation{ID: op.ID, Command: op.Command, Args: op.Args, Time: op.Timestamp, Reverts: op.Reverts, Undone: undone}
+	for _, change := range op.Changes {
+		o.Tickets = append(o.Tickets, change.Ticket)
+	}
+	return o
+}
+
+// Journal returns the operations recorded in the journal, oldest first.
+func (m *Manager) Journal() ([]Operation, error) {
+	ops, err := config.LoadJournal(m.repoPath)
+	if err != nil {
+		return nil, wrapError(fmt.Errorf("failed to load journal: %w", err))
+	}
+
+	reverted := config.Reverted(ops)
+	operations := make([]Operation, len(ops))
+	for i, op := range ops {
+		operations[i] = newOperation(op, reverted[op.ID])
+	}
+	return operations, nil
+}
+
+// Undo reverses the most recent operation in the journal that hasn't been undone, and
+// returns it: created worktrees are removed, removed ones are recreated at their old
+// branch tip, archives are restored, updates are reset to their pre-rebase commit, and
+// metadata changes are reverted. Every change is checked before any is undone, so a
+// refused undo changes nothing. Operations that changed no worktrees, such as push, can't
+// be undone.
+func (m *Manager) Undo() (Operation, error) {
+	ops, err := config.LoadJournal(m.repoPath)
+	if err != nil {
+		return Operation{}, wrapError(fmt.Errorf("failed to load journal: %w", err))
+	}
+
+	op := config.LastOperation(ops)
+	if op == nil {
+		return Operation{}, fmt.Errorf("nothing to undo")
+	}
+	if !op.Reversible() {
+		return Operation{}, fmt.Errorf("last operation cannot be undone: #%d %s", op.ID, op.Command)
+	}
+
+	var undoers []undoer
+	for _, change := range op.Changes {
+		undo, err := m.prepareUndo(op.Command, change)
+		if err != nil {
+			return Operation{}, fmt.Errorf("failed to undo %s for %s: %w", op.Command, change.Ticket, err)
+		}
+		undoers = append(undoers, undo)
+	}
+
+	m.logf("Undoing #%d: %s\n", op.ID, op.Command)
+
+	var changes []config.Change
+	for i, undo := range undoers {
+		undone, err := undo()
+		if err != nil {
+			err = fmt.Errorf("failed to undo %s for %s: %w", op.Command, op.Changes[i].Ticket, err)
+			if len(changes) == 0 {
+				return Operation{}, err
+			}
+			// Keep the metadata in step with the changes that were undone
+			if saveErr := m.finishUndo(op, changes); saveErr != nil {
+				return Operation{}, fmt.Errorf("%w; %w", err, saveErr)
+			}
+			var tickets []string
+			for _, change := range changes {
+				tickets = append(tickets, change.Ticket)
+			}
+			return Operation{}, fmt.Errorf("%w; undid %s, the rest of #%d has to be undone by hand", err, strings.Join(tickets, ", "), op.ID)
+		}
+		changes = append(changes, undone)
+	}
+
+	if op.MainlineBefore != op.MainlineAfter && m.meta.Mainline == op.MainlineAfter {
+		m.meta.Mainline = op.MainlineBefore
+	}
+
+	if err := m.finishUndo(op, changes); err != nil {
+		return Operation{}, err
+	}
+	return newOperation(*op, true), nil
+}
+
+// finishUndo saves the metadata after changes of op were undone, and records the undo in
+// the journal.
+func (m *Manager) finishUndo(op *config.Operation, changes []config.Change) error {
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.record(&config.Operation{
+		Command: "undo",
+		Changes: changes,
+		Reverts: op.ID,
+	})
+	return nil
+}
+
+// undoer reverses one change of an operation, returning the change it made in turn.
+type undoer func() (config.Change, error)
+
+// prepareUndo checks that a change made by command can still be undone, and returns the
+// undoer that reverses it.
+func (m *Manager) prepareUndo(command string, change config.Change) (undoer, error) {
+	switch {
+	case command == "update":
+		return m.undoRebase(change)
+	case command == "archive":
+		return m.undoArchive(change, m.restoreWorktree)
+	case command == "restore":
+		return m.undoArchive(change, func(ticketID string, entry config.WorktreeEntry) (config.WorktreeEntry, error) {
+			return m.archiveWorktree(ticketID, entry, false)
+		})
+	case command == "init":
+		// Adopted worktrees only gained metadata, so only the metadata is removed
+		return m.undoMetadata(change), nil
+	case change.Before == nil && change.After != nil:
+		return m.undoCreate(change)
+	case change.Before != nil && change.After == nil:
+		return m.undoRemove(change)
+	default:
+		return m.undoMetadata(change), nil
+	}
+}
+
+// undoCreate removes a worktree and branch that were created by an operation.
+// It refuses if the worktree has changes or the branch has moved since creation.
+func (m *Manager) undoCreate(change config.Change) (undoer, error) {
+	entry := *change.After
+
+	hasBranch := m.repo.BranchExists(entry.Branch)
+	if hasBranch {
+		tip, err := m.repo.ResolveCommit(entry.Branch)
+		if err != nil {
+			return nil, err
+		}
+		if change.TipAfter != "" && tip != change.TipAfter {
+			return nil, fmt.Errorf("branch %s has new commits since it was created", entry.Branch)
+		}
+	}
+
+	_, statErr := os.Stat(entry.Path)
+	exists := statErr == nil
+	if exists {
+		clean, err := m.repo.WithPath(entry.Path).IsClean()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check worktree status: %w", err)
+		}
+		if !clean {
+			return nil, fmt.Errorf("worktree at %s has uncommitted changes", entry.Path)
+		}
+	}
+
+	return func() (config.Change, error) {
+		undone := config.Change{Ticket: change.Ticket, Before: change.After, TipBefore: change.TipAfter}
+		if exists {
+			m.logf("Removing worktree at %s...\n", entry.Path)
+			if err := m.repo.RemoveWorktree(entry.Path); err != nil {
+				return undone, err
+			}
+		}
+		if hasBranch {
+			m.logf("Deleting branch %s...\n", entry.Branch)
+			if err := m.repo.DeleteBranch(entry.Branch); err != nil {
+				return undone, err
+			}
+		}
+		m.meta.RemoveWorktree(change.Ticket)
+		return undone, nil
+	}, nil
+}
+
+// undoRemove restores a worktree entry that was removed by an operation.
+// If the branch tip was recorded, the worktree is recreated from it.
+func (m *Manager) undoRemove(change config.Change) (undoer, error) {
+	entry := *change.Before
+
+	if m.meta.HasWorktree(change.Ticket) {
+		return nil, newError(ErrExists, change.Ticket, "a worktree for %s already exists", change.Ticket)
+	}
+	if !entry.IsArchived() && change.TipBefore != "" {
+		if _, err := os.Stat(entry.Path); err == nil {
+			return nil, newError(ErrExists, change.Ticket, "path already exists: %s", entry.Path)
+		}
+	}
+
+	return func() (config.Change, error) {
+		undone := config.Change{Ticket: change.Ticket, After: change.Before, TipAfter: change.TipBefore}
+		if entry.IsArchived() {
+			// Archived worktrees have no directory, only a branch and maybe saved changes
+			m.logf("Restoring branch %s...\n", entry.Branch)
+			if change.TipBefore != "" && !m.repo.BranchExists(entry.Branch) {
+				if err := m.repo.CreateBranch(entry.Branch, change.TipBefore); err != nil {
+					return undone, err
+				}
+			}
+			if entry.Stash != "" {
+				if err := m.repo.UpdateRef(config.ArchiveRef(change.Ticket), entry.Stash); err != nil {
+					return undone, err
+				}
+			}
+		} else if change.TipBefore != "" {
+			m.logf("Recreating worktree at %s...\n", entry.Path)
+			if m.repo.BranchExists(entry.Branch) {
+				if err := m.repo.AttachWorktree(entry.Path, entry.Branch); err != nil {
+					return undone, err
+				}
+			} else if err := m.repo.AddWorktree(entry.Path, entry.Branch, change.TipBefore); err != nil {
+				return undone, err
+			}
+		} else {
+			m.logf("Restoring metadata for %s...\n", change.Ticket)
+		}
+
+		m.meta.Worktrees[change.Ticket] = entry
+		return undone, nil
+	}, nil
+}
+
+// undoArchive reverses an archive or restore by applying the opposite transition to the
+// worktree's current entry.
+func (m *Manager) undoArchive(change config.Change, reverse func(string, config.WorktreeEntry) (config.WorktreeEntry, error)) (undoer, error) {
+	current, ok := m.meta.Worktrees[change.Ticket]
+	if !ok {
+		return nil, fmt.Errorf("worktree for %s no longer exists", change.Ticket)
+	}
+	if change.After == nil || current.IsArchived() != change.After.IsArchived() {
+		return nil, fmt.Errorf("worktree for %s has been archived or restored since", change.Ticket)
+	}
+
+	return func() (config.Change, error) {
+		undone := config.Change{Ticket: change.Ticket, Before: change.After}
+		entry, err := reverse(change.Ticket, current)
+		if err != nil {
+			return undone, err
+		}
+		m.meta.Worktrees[change.Ticket] = entry
+		undone.After = entryRef(entry)
+		return undone, nil
+	}, nil
+}
+
+// undoRebase resets a worktree branch to the tip it had before an update.
+func (m *Manager) undoRebase(change config.Change) (undoer, error) {
+	if change.After == nil || change.TipBefore == "" {
+		return nil, fmt.Errorf("no branch tip recorded")
+	}
+
+	wtRepo := m.repo.WithPath(change.After.Path)
+	clean, err := wtRepo.IsClean()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check worktree status: %w", err)
+	}
+	if !clean {
+		return nil, fmt.Errorf("worktree has uncommitted changes")
+	}
+
+	tip, err := wtRepo.ResolveCommit("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if tip != change.TipAfter {
+		return nil, fmt.Errorf("branch %s has moved since the update", change.After.Branch)
+	}
+
+	return func() (config.Change, error) {
+		undone := config.Change{Ticket: change.Ticket, Before: change.After, After: change.Before, TipBefore: change.TipAfter, TipAfter: change.TipBefore}
+		m.logf("Resetting %s to %s...\n", change.After.Branch, change.TipBefore)
+		if err := wtRepo.ResetHard(change.TipBefore); err != nil {
+			return undone, err
+		}
+		return undone, nil
+	}, nil
+}
+
+// undoMetadata restores the metadata entry recorded before an operation.
+func (m *Manager) undoMetadata(change config.Change) undoer {
+	return func() (config.Change, error) {
+		if change.Before == nil {
+			m.meta.RemoveWorktree(change.Ticket)
+		} else {
+			m.meta.Worktrees[change.Ticket] = *change.Before
+		}
+		return config.Change{Ticket: change.Ticket, Before: change.After, After: change.Before}, nil
+	}
+}
@@ -0,0 +1,222 @@
+package gittree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/git"
+)
+
+// Conflicts is the predicted outcome of updating a worktree's branch from the mainline.
+type Conflicts struct {
+	// Behind is the number of mainline commits missing from the branch.
+	Behind int
+
+	// Files lists the files predicted to conflict.
+	Files []string
+}
+
+// CheckConflicts predicts whether updating a ticket's worktree would conflict with the
+// mainline as last fetched, by merging the two in memory with git merge-tree. No worktree
+// or index is touched, so archived and dirty worktrees can be checked too. A rebase
+// replays commits one at a time, so it can occasionally conflict where the merge doesn't,
+// or the other way around.
+func (m *Manager) CheckConflicts(ticketID string) (Conflicts, error) {
+	_, entry, err := m.find(ticketID)
+	if err != nil {
+		return Conflicts{}, err
+	}
+	target, err := m.target()
+	if err != nil {
+		return Conflicts{}, err
+	}
+	return m.checkConflicts(entry.Branch, target)
+}
+
+// checkConflicts predicts whether a branch would conflict with target.
+func (m *Manager) checkConflicts(branch, target string) (Conflicts, error) {
+	_, behind, err := m.repo.GetCommitCount(branch, target)
+	if err != nil || behind == 0 {
+		return Conflicts{}, err
+	}
+
+	files, err := m.repo.MergeConflicts(branch, target)
+	return Conflicts{Behind: behind, Files: files}, err
+}
+
+// target returns the mainline ref to rebase onto.
+func (m *Manager) target() (string, error) {
+	if m.meta.Mainline == "" {
+		return "", newError(ErrNoMainline, "", "mainline branch not set in metadata")
+	}
+	return m.MainlineRef(), nil
+}
+
+// Update fetches the mainline and rebases a ticket's worktree onto it. The worktree must
+// have no uncommitted changes. If the rebase fails, the error is an ErrRebase and the
+// worktree is left mid-rebase for the conflicts to be resolved.
+func (m *Manager) Update(ticketID string) (Worktree, error) {
+	id, entry, err := m.find(ticketID)
+	if err != nil {
+		return Worktree{}, err
+	}
+	if entry.IsArchived() {
+		return Worktree{}, newError(ErrArchived, id, "worktree for %s is archived, run: git tree restore %s", id, id)
+	}
+	target, err := m.target()
+	if err != nil {
+		return Worktree{}, err
+	}
+
+	// Check if worktree is clean
+	wtRepo := m.repo.WithPath(entry.Path)
+	clean, err := wtRepo.IsClean()
+	if err != nil {
+		return Worktree{}, fmt.Errorf("failed to check worktree status: %w", err)
+	}
+	if !clean {
+		return Worktree{}, newError(ErrDirty, id, "worktree has uncommitted changes, please commit or stash them first")
+	}
+
+	if err := m.Fetch(); err != nil {
+		return Worktree{}, err
+	}
+
+	change, err := m.rebase(wtRepo, id, entry, target)
+	if err != nil {
+		return Worktree{}, &Error{Kind: ErrRebase, Ticket: id, Err: err}
+	}
+
+	if change.TipAfter != change.TipBefore {
+		m.record(&config.Operation{
+			Command:        "update",
+			Args:           []string{ticketID},
+			MainlineBefore: m.meta.Mainline,
+			MainlineAfter:  m.meta.Mainline,
+			Changes:        []config.Change{change},
+		})
+	}
+	return newWorktree(entry), nil
+}
+
+// rebase rebases a worktree onto target and returns the journal change recording the
+// branch tip before and after.
+func (m *Manager) rebase(wtRepo *git.Repo, ticketID string, entry config.WorktreeEntry, target string) (config.Change, error) {
+	change := config.Change{Ticket: ticketID, Before: entryRef(entry), After: entryRef(entry)}
+
+	// Record the branch tip so the update can be undone
+	tipBefore, err := wtRepo.ResolveCommit("HEAD")
+	if err != nil {
+		return change, err
+	}
+	change.TipBefore = tipBefore
+
+	m.logf("Rebasing onto %s...\n", target)
+	if err := wtRepo.Rebase(target); err != nil {
+		return change, err
+	}
+
+	change.TipAfter, _ = wtRepo.ResolveCommit("HEAD")
+	return change, nil
+}
+
+// UpdateAllOptions configures UpdateAll.
+type UpdateAllOptions struct {
+	// IncludeConflicts also updates worktrees predicted to conflict.
+	IncludeConflicts bool
+}
+
+// UpdateAllResult lists the tickets UpdateAll updated, skipped and failed to update.
+type UpdateAllResult struct {
+	Updated []string
+	Skipped []string
+	Failed  []string
+}
+
+// UpdateAll fetches the mainline and rebases every clean, checked out worktree that is
+// behind it. Worktrees predicted to conflict are skipped unless opts.IncludeConflicts is
+// set; a rebase that fails anyway is aborted so the worktree is left as it was, and the
+// error is an ErrRebase naming the failed tickets.
+func (m *Manager) UpdateAll(opts UpdateAllOptions) (UpdateAllResult, error) {
+	var result UpdateAllResult
+	target, err := m.target()
+	if err != nil {
+		return result, err
+	}
+	if err := m.Fetch(); err != nil {
+		return result, err
+	}
+
+	var changes []config.Change
+	for _, ticketID := range m.meta.Tickets() {
+		entry := m.meta.Worktrees[ticketID]
+		skip := func(reason string) {
+			m.logf("Skipping %s: %s\n", ticketID, reason)
+			result.Skipped = append(result.Skipped, ticketID)
+		}
+
+		if entry.IsArchived() {
+			continue
+		}
+
+		conflicts, err := m.checkConflicts(entry.Branch, target)
+		switch {
+		case err != nil:
+			skip(firstLine(err.Error()))
+			continue
+		case conflicts.Behind == 0:
+			continue
+		case len(conflicts.Files) > 0 && !opts.IncludeConflicts:
+			skip("predicted conflicts in " + strings.Join(conflicts.Files, ", "))
+			continue
+		}
+
+		wtRepo := m.repo.WithPath(entry.Path)
+		clean, err := wtRepo.IsClean()
+		if err != nil {
+			skip("cannot read worktree status")
+			continue
+		}
+		if !clean {
+			skip("worktree has uncommitted changes")
+			continue
+		}
+
+		m.logf("\nUpdating %s...\n", ticketID)
+		change, err := m.rebase(wtRepo, ticketID, entry, target)
+		if err != nil {
+			m.logf("Rebase of %s failed, aborting it.\n", ticketID)
+			if abortErr := wtRepo.RebaseAbort(); abortErr != nil {
+				m.logf("Warning: %v\n", abortErr)
+			}
+			result.Failed = append(result.Failed, ticketID)
+			continue
+		}
+		if change.TipAfter != change.TipBefore {
+			changes = append(changes, change)
+			result.Updated = append(result.Updated, ticketID)
+		}
+	}
+
+	if len(changes) > 0 {
+		m.record(&config.Operation{
+			Command:        "update",
+			Args:           []string{"--all"},
+			MainlineBefore: m.meta.Mainline,
+			MainlineAfter:  m.meta.Mainline,
+			Changes:        changes,
+		})
+	}
+
+	if len(result.Failed) > 0 {
+		return result, newError(ErrRebase, "", "rebase failed for %s", strings.Join(result.Failed, ", "))
+	}
+	return result, nil
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
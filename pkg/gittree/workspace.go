@@ -0,0 +1,382 @@
+package gittree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sduncan/git-tree/internal/config"
+	"github.com/sduncan/git-tree/internal/tracker"
+	"github.com/sduncan/git-tree/internal/util"
+)
+
+// StateMissing is the state of a workspace member that has no worktree for a ticket the
+// other members have one for.
+const StateMissing = "missing"
+
+// Workspace manages tickets that span several repositories. Each ticket gets a directory
+// of its own, holding a worktree of every member repository on a branch of the same
+// name. The worktrees are recorded in the metadata of their repositories, so they can
+// also be managed one at a time with each repository's Manager.
+type Workspace struct {
+	config  *config.Workspace
+	members []*Manager
+	names   []string
+	log     io.Writer
+}
+
+// Member is a repository of a workspace.
+type Member struct {
+	// Name is the repository's name, which is also the name of its worktrees' directories
+	// in a ticket directory.
+	Name string
+
+	// Manager manages the repository's worktrees.
+	Manager *Manager
+}
+
+// OpenWorkspace returns the workspace configured under name in the git configuration for
+// dir, usually the global configuration. An unknown or misconfigured workspace is an
+// ErrInvalidSettings.
+func OpenWorkspace(dir, name string, opts Options) (*Workspace, error) {
+	ws, err := config.LoadWorkspace(dir, name)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	w := &Workspace{config: ws, log: opts.Log}
+	if w.log == nil {
+		w.log = io.Discard
+	}
+	seen := make(map[string]string)
+	for _, repoPath := range ws.Repos {
+		m, err := Open(repoPath, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", repoPath, err)
+		}
+		memberName := util.GetRepoName(m.RepoPath())
+		if other, ok := seen[memberName]; ok {
+			return nil, newError(ErrInvalidSettings, "", "workspace %s repositories %s and %s are both named %s", name, other, repoPath, memberName)
+		}
+		seen[memberName] = repoPath
+		w.members = append(w.members, m)
+		w.names = append(w.names, memberName)
+	}
+	return w, nil
+}
+
+// Name returns the name of the workspace.
+func (w *Workspace) Name() string {
+	return w.config.Name
+}
+
+// Members returns the repositories of the workspace, in the order configured.
+func (w *Workspace) Members() []Member {
+	members := make([]Member, len(w.members))
+	for i, m := range w.members {
+		members[i] = Member{Name: w.names[i], Manager: m}
+	}
+	return members
+}
+
+// TicketPath returns the directory shared by a ticket's worktrees.
+func (w *Workspace) TicketPath(ticketID string) string {
+	return w.config.TicketPath(ticketID)
+}
+
+// Create creates a worktree for a ticket in every member repository, in
+// <ticket directory>/<repository name>, all on the same branch. The ticket ID is
+// normalized and validated, and the branch named, with the first repository's settings
+// unless opts.Branch is set. opts.Lookup, if set, is only called once. If a worktree
+// can't be created, those already created are removed again.
+func (w *Workspace) Create(ticketID string, opts CreateOptions) ([]Worktree, error) {
+	ticketID, err := w.members[0].settings.NormalizeTicket(ticketID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	for i, m := range w.members {
+		if existing, ok := m.meta.FindWorktree(ticketID); ok {
+			return nil, newError(ErrExists, existing, "worktree for %s already exists in %s at %s", existing, w.names[i], m.meta.Worktrees[existing].Path)
+		}
+	}
+
+	if lookup := opts.Lookup; lookup != nil {
+		var info *TicketInfo
+		var lookupErr error
+		looked := false
+		opts.Lookup = func(ticketID string) (*TicketInfo, error) {
+			if !looked {
+				info, lookupErr = lookup(ticketID)
+				looked = true
+			}
+			return info, lookupErr
+		}
+	}
+	if opts.Branch == "" {
+		slug := ""
+		if opts.Lookup != nil {
+			info, err := opts.Lookup(ticketID)
+			if err != nil {
+				return nil, err
+			}
+			if info != nil {
+				slug = tracker.Slug(info.Title)
+			}
+		}
+		opts.Branch = w.members[0].settings.BranchName(ticketID, slug)
+	}
+
+	ticketPath := w.TicketPath(ticketID)
+	opts.Workspace = w.Name()
+	var created []Worktree
+	for i, m := range w.members {
+		w.logf("\n[%s]\n", w.names[i])
+		opts.Path = filepath.Join(ticketPath, w.names[i])
+		entry, err := m.Create(ticketID, opts)
+		if err != nil {
+			w.rollback(ticketID, created)
+			return nil, fmt.Errorf("failed to create worktree in %s: %w", w.names[i], err)
+		}
+		created = append(created, entry)
+	}
+	return created, nil
+}
+
+// rollback removes the worktrees created for a ticket before a member failed.
+func (w *Workspace) rollback(ticketID string, created []Worktree) {
+	for i := range created {
+		w.logf("Removing the worktree created in %s...\n", w.names[i])
+		if _, err := w.members[i].Delete(ticketID, DeleteOptions{Force: true}); err != nil {
+			w.logf("Warning: %v\n", err)
+		}
+	}
+	w.removeTicketDir(ticketID)
+}
+
+// TicketStatus is the state of a ticket's worktrees across the workspace.
+type TicketStatus struct {
+	Ticket string `json:"ticket"`
+
+	// Path is the directory shared by the ticket's worktrees.
+	Path string `json:"path"`
+
+	// State is the most pressing state of the worktrees: StateUnknown, StateStale,
+	// StateMissing, StateDirty, StateClean and StateArchived, in that order.
+	State string `json:"state"`
+
+	// Changes is the number of changed files across the worktrees.
+	Changes int `json:"changes"`
+
+	// LastActive is the latest activity in any of the worktrees.
+	LastActive time.Time `json:"last_active"`
+
+	// Repos has the status of the ticket's worktree in each member repository, in order.
+	Repos []RepoStatus `json:"repos"`
+}
+
+// RepoStatus is the status of a ticket's worktree in a member repository. A member
+// without a worktree for the ticket is StateMissing.
+type RepoStatus struct {
+	Repo string `json:"repo"`
+	Status
+}
+
+// statePriority orders states by how pressing they are, most pressing first.
+var statePriority = []string{StateUnknown, StateStale, StateMissing, StateDirty, StateClean, StateArchived}
+
+// List returns the status of the workspace's tickets selected by opts. A ticket is
+// selected if any of its worktrees is, and SortCreated orders tickets by their most
+// recently created worktree.
+func (w *Workspace) List(opts ListOptions) ([]TicketStatus, error) {
+	if err := checkSort(opts.Sort); err != nil {
+		return nil, err
+	}
+
+	created := make(map[string]time.Time)
+	for _, m := range w.members {
+		for _, id := range m.meta.Tickets() {
+			entry := m.meta.Worktrees[id]
+			if entry.Workspace != w.Name() || !hasLabels(entry, opts.Labels) {
+				continue
+			}
+			if t, ok := created[id]; !ok || entry.Created.After(t) {
+				created[id] = entry.Created
+			}
+		}
+	}
+	tickets := make([]string, 0, len(created))
+	for id := range created {
+		tickets = append(tickets, id)
+	}
+	sort.Strings(tickets)
+
+	results := []TicketStatus{}
+	for _, id := range tickets {
+		result, err := w.status(id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	// Tickets are already in order
+	switch opts.Sort {
+	case SortActive:
+		sort.SliceStable(results, func(i, j int) bool { return results[i].LastActive.After(results[j].LastActive) })
+	case SortCreated:
+		sort.SliceStable(results, func(i, j int) bool { return created[results[i].Ticket].After(created[results[j].Ticket]) })
+	}
+	return results, nil
+}
+
+// Status returns the status of a ticket's worktrees across the workspace.
+func (w *Workspace) Status(ticketID string) (TicketStatus, error) {
+	id, err := w.find(ticketID)
+	if err != nil {
+		return TicketStatus{}, err
+	}
+	return w.status(id)
+}
+
+// status returns the status of the worktrees of a ticket stored under id.
+func (w *Workspace) status(id string) (TicketStatus, error) {
+	result := TicketStatus{Ticket: id, Path: w.TicketPath(id)}
+	rank := len(statePriority)
+	for i, m := range w.members {
+		repo := RepoStatus{Repo: w.names[i]}
+		if s, err := m.Status(id); err == nil {
+			repo.Status = s
+		} else if errors.Is(err, ErrNotFound) {
+			repo.Ticket, repo.State = id, StateMissing
+		} else {
+			return TicketStatus{}, err
+		}
+
+		for r, state := range statePriority {
+			if repo.State == state && r < rank {
+				rank = r
+			}
+		}
+		result.Changes += repo.Changes
+		if repo.LastActive.After(result.LastActive) {
+			result.LastActive = repo.LastActive
+		}
+		result.Repos = append(result.Repos, repo)
+	}
+	result.State = statePriority[rank]
+	return result, nil
+}
+
+// find returns the ID a ticket is stored under in the workspace. It is an ErrNotFound if
+// no member repository has a worktree for the ticket.
+func (w *Workspace) find(ticketID string) (string, error) {
+	for _, m := range w.members {
+		if id, _, err := m.find(ticketID); err == nil {
+			return id, nil
+		}
+	}
+	return "", newError(ErrNotFound, ticketID, "worktree for %s not found in workspace %s", ticketID, w.Name())
+}
+
+// each calls fn with every member repository that has a worktree for a ticket, going on
+// after failures. The errors are joined, each naming its repository, so errors.Is
+// matches any of them.
+func (w *Workspace) each(ticketID string, fn func(m *Manager, id string) error) error {
+	id, err := w.find(ticketID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, m := range w.members {
+		if _, _, err := m.find(id); err != nil {
+			w.logf("\n[%s]\nNo worktree for %s, skipping.\n", w.names[i], id)
+			continue
+		}
+		w.logf("\n[%s]\n", w.names[i])
+		if err := fn(m, id); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Update fetches the mainline of every member repository and rebases the ticket's
+// worktree onto it, as Manager.Update does. A worktree that can't be updated doesn't stop
+// the others; the error names the repositories that failed.
+func (w *Workspace) Update(ticketID string) ([]Worktree, error) {
+	var updated []Worktree
+	err := w.each(ticketID, func(m *Manager, id string) error {
+		entry, err := m.Update(id)
+		if err == nil {
+			updated = append(updated, entry)
+		}
+		return err
+	})
+	return updated, err
+}
+
+// Push pushes the ticket's branch in every member repository, as Manager.Push does.
+func (w *Workspace) Push(ticketID string) ([]Worktree, error) {
+	var pushed []Worktree
+	err := w.each(ticketID, func(m *Manager, id string) error {
+		entry, err := m.Push(id)
+		if err == nil {
+			pushed = append(pushed, entry)
+		}
+		return err
+	})
+	return pushed, err
+}
+
+// Delete removes the ticket's worktree and branch in every member repository, and the
+// ticket directory once it is empty. Unless opts.Force is set, nothing is removed if any
+// of the worktrees has uncommitted changes; the error is then an ErrDirty naming them.
+func (w *Workspace) Delete(ticketID string, opts DeleteOptions) ([]Worktree, error) {
+	id, err := w.find(ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Force {
+		var dirty []string
+		for i, m := range w.members {
+			if _, entry, err := m.find(id); err == nil {
+				if clean, err := m.repo.WithPath(entry.Path).IsClean(); err == nil && !clean {
+					dirty = append(dirty, w.names[i])
+				}
+			}
+		}
+		if len(dirty) > 0 {
+			return nil, newError(ErrDirty, id, "worktrees for %s have uncommitted changes in %s", id, strings.Join(dirty, ", "))
+		}
+	}
+
+	var deleted []Worktree
+	err = w.each(id, func(m *Manager, id string) error {
+		entry, err := m.Delete(id, opts)
+		if err == nil {
+			deleted = append(deleted, entry)
+		}
+		return err
+	})
+	w.removeTicketDir(id)
+	return deleted, err
+}
+
+// removeTicketDir removes a ticket's directory if it is empty.
+func (w *Workspace) removeTicketDir(ticketID string) {
+	if err := os.Remove(w.TicketPath(ticketID)); err != nil && !os.IsNotExist(err) {
+		w.logf("Warning: keeping %s: %v\n", w.TicketPath(ticketID), err)
+	}
+}
+
+// logf writes a progress message or warning to the log.
+func (w *Workspace) logf(format string, args ...any) {
+	fmt.Fprintf(w.log, format, args...)
+}